@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"slices"
+	"time"
+)
+
+// Authentication method references, as registered by RFC 8176
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRSMS         = "sms"
+	AMRHardwareKey = "hwk"
+	AMRMultiFactor = "mfa"
+)
+
+// AMRImpersonation marks a session started by impersonation. It is not
+// registered by RFC 8176 and proves nothing, so it only achieves the
+// weakest class.
+const AMRImpersonation = "imp"
+
+// Authentication context classes, named after the NIST SP 800-63B
+// authenticator assurance levels, weakest first
+const (
+	ACRSingleFactor = "aal1"
+	ACRMultiFactor  = "aal2"
+)
+
+// acrLevels orders the authentication context classes
+var acrLevels = []string{ACRSingleFactor, ACRMultiFactor}
+
+// ACR returns the authentication context class the methods achieve
+func ACR(amr []string) string {
+	if slices.Contains(amr, AMRMultiFactor) {
+		return ACRMultiFactor
+	}
+	return ACRSingleFactor
+}
+
+// ACRSatisfies reports whether acr is at least as strong as required.
+// An unknown acr satisfies nothing, and an unknown requirement nothing but
+// itself.
+func ACRSatisfies(acr, required string) bool {
+	if acr == required {
+		return true
+	}
+	have, want := slices.Index(acrLevels, acr), slices.Index(acrLevels, required)
+	return have >= 0 && want >= 0 && have >= want
+}
+
+// Requirement is the authentication a route demands of a session: at
+// least ACR, and no longer ago than MaxAge when it is positive
+type Requirement struct {
+	ACR    string
+	MaxAge time.Duration
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+)
+
+var (
+	// ErrNoCredentials means the request carries no credentials the authenticator understands
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials means credentials were presented but could not be verified
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrTooManyAttempts means the client is temporarily blocked after repeated failures
+	ErrTooManyAttempts = errors.New("too many failed attempts")
+)
+
+// Authenticator resolves the principal behind the credentials of a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
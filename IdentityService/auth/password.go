@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Hash algorithms recognised in stored credentials
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+	HashPBKDF2   = "pbkdf2"
+	HashScrypt   = "scrypt"
+	HashSSHA     = "ssha"
+)
+
+const (
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// ErrUnsupportedHash means a stored password hash is in an unknown format
+var ErrUnsupportedHash = errors.New("unsupported password hash")
+
+// Argon2Params are the cost parameters new password hashes are created with
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+}
+
+// PasswordHasher hashes new passwords with Argon2id and verifies hashes in
+// any supported format. Every hash is self-describing, so hashes made with
+// other algorithms or older parameters keep working and can be detected
+// with NeedsRehash.
+type PasswordHasher struct {
+	params Argon2Params
+}
+
+// NewPasswordHasher creates a new PasswordHasher instance
+func NewPasswordHasher(params Argon2Params) *PasswordHasher {
+	return &PasswordHasher{params: params}
+}
+
+// Hash hashes a password with Argon2id and encodes it as a PHC string:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Time, h.params.Memory, h.params.Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Time, h.params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether password matches an encoded hash of any supported format
+func (h *PasswordHasher) Verify(password, encoded string) (bool, error) {
+	switch HashAlgorithm(encoded) {
+	case HashArgon2id:
+		return verifyArgon2id(password, encoded)
+	case HashBcrypt:
+		return verifyBcrypt(password, encoded)
+	case HashPBKDF2:
+		return verifyPBKDF2(password, encoded)
+	case HashScrypt:
+		return verifyScrypt(password, encoded)
+	case HashSSHA:
+		return verifySSHA(password, encoded)
+	}
+	return false, ErrUnsupportedHash
+}
+
+// NeedsRehash reports whether an encoded hash was made with another
+// algorithm or with parameters other than the current ones
+func (h *PasswordHasher) NeedsRehash(encoded string) bool {
+	params, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory != h.params.Memory || params.time != h.params.Time ||
+		params.threads != h.params.Threads || len(params.key) != argon2KeyLen
+}
+
+// HashAlgorithm names the algorithm of an encoded hash, or returns ""
+// when the format is not recognised
+func HashAlgorithm(encoded string) string {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return HashArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return HashBcrypt
+	case strings.HasPrefix(encoded, "$pbkdf2"):
+		return HashPBKDF2
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return HashScrypt
+	case strings.HasPrefix(encoded, "{SSHA"):
+		return HashSSHA
+	}
+	return ""
+}
+
+// ValidateHash checks that an encoded hash, such as one imported from
+// another user store, is well formed and within safe cost limits
+func ValidateHash(encoded string) error {
+	var err error
+	switch HashAlgorithm(encoded) {
+	case HashArgon2id:
+		_, err = parseArgon2id(encoded)
+	case HashBcrypt:
+		_, err = parseBcrypt(encoded)
+	case HashPBKDF2:
+		_, err = parsePBKDF2(encoded)
+	case HashScrypt:
+		_, err = parseScrypt(encoded)
+	case HashSSHA:
+		_, err = parseSSHA(encoded)
+	default:
+		err = ErrUnsupportedHash
+	}
+	return err
+}
+
+type argon2Hash struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2id(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != HashArgon2id {
+		return nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnsupportedHash
+	}
+	var hash argon2Hash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.time, &hash.threads); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if hash.memory > maxArgon2Memory || hash.time == 0 || hash.time > maxArgon2Time || hash.threads == 0 {
+		return nil, ErrUnsupportedHash
+	}
+
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return nil, ErrUnsupportedHash
+	}
+	return &hash, nil
+}
+
+func verifyArgon2id(password, encoded string) (bool, error) {
+	hash, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	got := argon2.IDKey([]byte(password), hash.salt, hash.time, hash.memory, hash.threads, uint32(len(hash.key)))
+	return subtle.ConstantTimeCompare(got, hash.key) == 1, nil
+}
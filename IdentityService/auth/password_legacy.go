@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Cost ceilings for stored hashes. Imported hashes above them are refused
+// so a crafted credential cannot make a single sign-in arbitrarily slow.
+const (
+	maxArgon2Memory  = 1024 * 1024 // KiB
+	maxArgon2Time    = 16
+	maxPBKDF2Rounds  = 5_000_000
+	maxScryptLogN    = 20
+	maxScryptRP      = 64
+	maxHashKeyLength = 128
+)
+
+// Legacy formats are accepted so users migrated from other stores can sign
+// in with their existing passwords; they are replaced with Argon2id on the
+// next successful sign-in.
+//
+//	bcrypt:  $2a$, $2b$ or $2y$ modular crypt strings
+//	PBKDF2:  $pbkdf2-<sha1|sha256|sha512>$i=<rounds>$<salt>$<hash> (PHC),
+//	         or the passlib form $pbkdf2-<digest>$<rounds>$<salt>$<hash>
+//	scrypt:  $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<hash> (PHC)
+//	SSHA:    {SSHA}, {SSHA256} or {SSHA512} followed by base64(digest || salt),
+//	         where digest = H(password || salt), as written by LDAP servers
+
+func parseBcrypt(encoded string) ([]byte, error) {
+	if _, err := bcrypt.Cost([]byte(encoded)); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	return []byte(encoded), nil
+}
+
+func verifyBcrypt(password, encoded string) (bool, error) {
+	hashed, err := parseBcrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashed, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return true, nil
+}
+
+type pbkdf2Hash struct {
+	digest func() hash.Hash
+	rounds int
+	salt   []byte
+	key    []byte
+}
+
+func parsePBKDF2(encoded string) (*pbkdf2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 {
+		return nil, ErrUnsupportedHash
+	}
+
+	var parsed pbkdf2Hash
+	switch parts[1] {
+	case "pbkdf2", "pbkdf2-sha1":
+		parsed.digest = sha1.New
+	case "pbkdf2-sha256":
+		parsed.digest = sha256.New
+	case "pbkdf2-sha512":
+		parsed.digest = sha512.New
+	default:
+		return nil, ErrUnsupportedHash
+	}
+
+	rounds, _, _ := strings.Cut(strings.TrimPrefix(parts[2], "i="), ",")
+	n, err := strconv.Atoi(rounds)
+	if err != nil || n <= 0 || n > maxPBKDF2Rounds {
+		return nil, ErrUnsupportedHash
+	}
+	parsed.rounds = n
+
+	if parsed.salt, err = decodeLegacyBase64(parts[3]); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if parsed.key, err = decodeLegacyBase64(parts[4]); err != nil || len(parsed.key) == 0 || len(parsed.key) > maxHashKeyLength {
+		return nil, ErrUnsupportedHash
+	}
+	return &parsed, nil
+}
+
+func verifyPBKDF2(password, encoded string) (bool, error) {
+	parsed, err := parsePBKDF2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	got, err := pbkdf2.Key(parsed.digest, password, parsed.salt, parsed.rounds, len(parsed.key))
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return subtle.ConstantTimeCompare(got, parsed.key) == 1, nil
+}
+
+type scryptHash struct {
+	logN, r, p int
+	salt       []byte
+	key        []byte
+}
+
+func parseScrypt(encoded string) (*scryptHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 5 || parts[1] != "scrypt" {
+		return nil, ErrUnsupportedHash
+	}
+
+	var parsed scryptHash
+	for _, param := range strings.Split(parts[2], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, ErrUnsupportedHash
+		}
+		switch name {
+		case "ln":
+			parsed.logN = n
+		case "r":
+			parsed.r = n
+		case "p":
+			parsed.p = n
+		}
+	}
+	if parsed.logN < 1 || parsed.logN > maxScryptLogN ||
+		parsed.r < 1 || parsed.r > maxScryptRP || parsed.p < 1 || parsed.p > maxScryptRP {
+		return nil, ErrUnsupportedHash
+	}
+
+	var err error
+	if parsed.salt, err = decodeLegacyBase64(parts[3]); err != nil {
+		return nil, ErrUnsupportedHash
+	}
+	if parsed.key, err = decodeLegacyBase64(parts[4]); err != nil || len(parsed.key) == 0 || len(parsed.key) > maxHashKeyLength {
+		return nil, ErrUnsupportedHash
+	}
+	return &parsed, nil
+}
+
+func verifyScrypt(password, encoded string) (bool, error) {
+	parsed, err := parseScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	got, err := scrypt.Key([]byte(password), parsed.salt, 1<<parsed.logN, parsed.r, parsed.p, len(parsed.key))
+	if err != nil {
+		return false, ErrUnsupportedHash
+	}
+	return subtle.ConstantTimeCompare(got, parsed.key) == 1, nil
+}
+
+type sshaHash struct {
+	digest func() hash.Hash
+	sum    []byte
+	salt   []byte
+}
+
+func parseSSHA(encoded string) (*sshaHash, error) {
+	scheme, payload, found := strings.Cut(strings.TrimPrefix(encoded, "{"), "}")
+	if !found {
+		return nil, ErrUnsupportedHash
+	}
+
+	var parsed sshaHash
+	var size int
+	switch strings.ToUpper(scheme) {
+	case "SSHA":
+		parsed.digest, size = sha1.New, sha1.Size
+	case "SSHA256":
+		parsed.digest, size = sha256.New, sha256.Size
+	case "SSHA512":
+		parsed.digest, size = sha512.New, sha512.Size
+	default:
+		return nil, ErrUnsupportedHash
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(raw) <= size {
+		return nil, ErrUnsupportedHash
+	}
+	parsed.sum, parsed.salt = raw[:size], raw[size:]
+	return &parsed, nil
+}
+
+func verifySSHA(password, encoded string) (bool, error) {
+	parsed, err := parseSSHA(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	h := parsed.digest()
+	h.Write([]byte(password))
+	h.Write(parsed.salt)
+	return subtle.ConstantTimeCompare(h.Sum(nil), parsed.sum) == 1, nil
+}
+
+// decodeLegacyBase64 accepts standard base64 with or without padding, and
+// passlib's variant which writes "." in place of "+"
+func decodeLegacyBase64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(strings.ReplaceAll(s, ".", "+"), "="))
+}
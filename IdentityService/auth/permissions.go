@@ -0,0 +1,47 @@
+package auth
+
+// Permission names an action that can be granted to a role
+type Permission string
+
+// Permissions granted to the built-in roles. Keep in sync with the seed
+// data in the database migrations.
+const (
+	PermUsersRead    Permission = "users:read"
+	PermUsersCreate  Permission = "users:create"
+	PermUsersUnlock  Permission = "users:unlock"
+	PermUsersImport  Permission = "users:import"
+	PermUsersExport  Permission = "users:export"
+	PermRolesRead    Permission = "roles:read"
+	PermRolesAssign  Permission = "roles:assign"
+	PermProfileRead  Permission = "profile:read"
+	PermProfileWrite Permission = "profile:write"
+
+	PermInvitationsManage Permission = "invitations:manage"
+	PermAPIKeysManage     Permission = "api_keys:manage"
+	PermAuditRead         Permission = "audit:read"
+	PermAuditVerify       Permission = "audit:verify"
+	PermWebhooksManage    Permission = "webhooks:manage"
+	PermPasswordPolicy    Permission = "password_policy:manage"
+	PermSCIMManage        Permission = "scim:manage"
+	PermUsersPrivacy      Permission = "users:privacy"
+	PermUserAttributes    Permission = "user_attributes:manage"
+	PermUsersManage       Permission = "users:manage"
+	PermUsersDisable      Permission = "users:disable"
+	PermUsersImpersonate  Permission = "users:impersonate"
+	PermOAuthClients      Permission = "oauth_clients:manage"
+)
+
+// Built-in role names
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+	RoleUser    = "user"
+)
+
+// Resource describes the object a permission check applies to.
+// A nil Resource means the check is not tied to a specific object.
+type Resource struct {
+	Type    string
+	ID      string
+	OwnerID int
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+type principalKey struct{}
+
+// Principal identifies the authenticated caller of a request.
+// OrganizationID is the active tenant; zero means none is selected.
+// Scopes, when non-nil, further restrict the principal's permissions,
+// as is the case for API keys. A SCIM provisioning token acts for its
+// organization rather than a user, so UserID is zero. ActorUserID is set
+// while another user impersonates UserID, like the RFC 8693 "act" claim.
+// AMR and AuthTime say how and when a session last authenticated.
+type Principal struct {
+	UserID         int
+	Email          string
+	Roles          []string
+	OrganizationID int
+	Scopes         []string
+	APIKeyID       int
+	SessionID      int64
+	SCIMTokenID    int
+	ActorUserID    int
+	AMR            []string
+	AuthTime       time.Time
+}
+
+// Impersonated reports whether another user is acting as the principal
+func (p *Principal) Impersonated() bool {
+	return p.ActorUserID != 0
+}
+
+// ACR returns the authentication context class of the principal's session
+func (p *Principal) ACR() string {
+	return ACR(p.AMR)
+}
+
+// HasRole reports whether the principal holds the given role
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScope reports whether the principal's scopes permit the permission
+func (p *Principal) AllowsScope(permission Permission) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if Permission(scope) == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal returns a copy of ctx carrying the given principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
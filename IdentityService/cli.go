@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	stderrors "errors"
+	"flag"
+	"fmt"
+	"identity-service/database"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/service"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const commandUsage = `Usage: identity-service [command]
+
+Without a command the HTTP server is started.
+
+Commands:
+  import-users -org ID [-format csv|jsonl] [-dry-run] FILE
+        Create or update users from a CSV or JSON Lines file ("-" for stdin)
+  export-users -org ID [-format csv|jsonl] [-fields id,name,...] [-o FILE]
+        Write every member of an organization to FILE or stdout
+`
+
+// commands holds what the command-line subcommands need
+type commands struct {
+	imports service.UserImportService
+	orgs    repository.OrganizationRepository
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// run executes a subcommand and returns the process exit code
+func (c *commands) run(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var err error
+	switch args[0] {
+	case "import-users":
+		err = c.importUsers(ctx, args[1:])
+	case "export-users":
+		err = c.exportUsers(ctx, args[1:])
+	default:
+		fmt.Fprintf(c.stderr, "unknown command %q\n\n%s", args[0], commandUsage)
+		return 2
+	}
+
+	var usage usageError
+	switch {
+	case stderrors.As(err, &usage):
+		fmt.Fprintf(c.stderr, "%s\n\n%s", usage, commandUsage)
+		return 2
+	case stderrors.Is(err, flag.ErrHelp):
+		return 0
+	case stderrors.Is(err, errRowsFailed):
+		return 1
+	case err != nil:
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// isHelpCommand reports whether arg asks for the command usage, which is
+// printed without connecting to the database
+func isHelpCommand(arg string) bool {
+	switch arg {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+// usageError reports a command invoked with bad arguments
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
+
+// errRowsFailed means an import finished but rejected some rows
+var errRowsFailed = stderrors.New("some rows were not imported")
+
+func (c *commands) importUsers(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	orgID := flags.Int("org", 0, "organization to import into")
+	format := flags.String("format", "", "file format, csv or jsonl (default from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError("import-users needs exactly one file")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = formatFromPath(path)
+	}
+	ctx, err := c.organization(ctx, *orgID)
+	if err != nil {
+		return err
+	}
+
+	src := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		src = file
+	}
+
+	result, err := c.imports.Import(ctx, *orgID, src, models.UserImportOptions{Format: *format, DryRun: *dryRun},
+		func(progress models.UserImportProgress) {
+			fmt.Fprintf(c.stderr, "processed %d rows: %d created, %d updated, %d failed\n",
+				progress.Processed, progress.Created, progress.Updated, progress.Failed)
+		})
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		field := rowErr.Field
+		if field != "" {
+			field += " "
+		}
+		fmt.Fprintf(c.stderr, "line %d: %s%s\n", rowErr.Line, field, rowErr.Message)
+	}
+	if result.ErrorsTruncated {
+		fmt.Fprintln(c.stderr, "further row errors omitted")
+	}
+
+	verb := "imported"
+	if result.DryRun {
+		verb = "dry run, nothing written"
+	}
+	fmt.Fprintf(c.stdout, "%s: %d rows, %d created, %d updated, %d failed\n",
+		verb, result.Processed, result.Created, result.Updated, result.Failed)
+
+	if result.Failed > 0 {
+		return errRowsFailed
+	}
+	return nil
+}
+
+func (c *commands) exportUsers(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export-users", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	orgID := flags.Int("org", 0, "organization to export")
+	format := flags.String("format", "", "file format, csv or jsonl (default from -o, else csv)")
+	fields := flags.String("fields", "", "comma-separated fields: "+strings.Join(models.UserExportFields, ","))
+	output := flags.String("o", "-", "file to write, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageError("export-users takes no arguments")
+	}
+
+	opts := models.UserExportOptions{Format: *format}
+	if opts.Format == "" {
+		opts.Format = formatFromPath(*output)
+	}
+	if *fields != "" {
+		for _, field := range strings.Split(*fields, ",") {
+			opts.Fields = append(opts.Fields, strings.TrimSpace(field))
+		}
+	}
+	ctx, err := c.organization(ctx, *orgID)
+	if err != nil {
+		return err
+	}
+
+	dst := c.stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		dst = file
+	}
+
+	count, err := c.imports.Export(ctx, *orgID, dst, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "exported %d users\n", count)
+	return nil
+}
+
+// organization checks the organization exists and scopes ctx to it
+func (c *commands) organization(ctx context.Context, orgID int) (context.Context, error) {
+	if orgID <= 0 {
+		return nil, usageError("-org is required")
+	}
+	if _, err := c.orgs.GetByID(ctx, orgID); err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("organization %d not found", orgID)
+		}
+		return nil, err
+	}
+	return database.WithTenant(ctx, orgID), nil
+}
+
+// formatFromPath guesses a file format from its extension, defaulting to CSV
+func formatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return models.UserFileJSONL
+	}
+	return models.UserFileCSV
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,22 @@ type Config struct {
 	Timeouts     TimeoutConfig
 	CORS         CORSConfig
 	Validation   ValidationConfig
+	Mail         MailConfig
+	Organization OrganizationConfig
+	APIKeys      APIKeyConfig
+	Webhooks     WebhookConfig
+	RateLimit    RateLimitConfig
+	Lockout      LockoutConfig
+	UserImport   UserImportConfig
+	Security     SecurityHeadersConfig
+	Password     PasswordConfig
+	Sessions     SessionConfig
+	SCIM         SCIMConfig
+	Profile      ProfileConfig
+	OAuth        OAuthConfig
+	OTP          OTPConfig
+	SMS          SMSConfig
+	Risk         RiskConfig
 }
 
 // DatabaseConfig holds database-specific configuration
@@ -54,13 +71,220 @@ type CORSConfig struct {
 	MaxAge           int
 }
 
+// SecurityHeadersConfig holds response hardening headers
+type SecurityHeadersConfig struct {
+	HSTSMaxAge              int
+	HSTSIncludeSubdomains   bool
+	HSTSPreload             bool
+	ContentSecurityPolicy   string
+	ContentTypeOptions      string
+	ReferrerPolicy          string
+	PermissionsPolicy       string
+	CrossOriginOpenerPolicy string
+	NoStorePaths            []string
+}
+
 // ValidationConfig holds validation rules
 type ValidationConfig struct {
 	MaxNameLength  int
 	MaxEmailLength int
 	EmailRegex     string
+	SlugRegex      string
+
+	// Password policy. PasswordMinStrength is a zxcvbn-style score from
+	// 0 (trivially guessable) to 4 (very strong).
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+	PasswordMinStrength   int
+	// BreachedPasswordsDir holds SHA-1 range files in the Have I Been Pwned
+	// format; empty disables the check, as does a PasswordBreachThreshold
+	// below 1. Passwords seen at least PasswordBreachThreshold times are
+	// rejected.
+	BreachedPasswordsDir    string
+	PasswordBreachThreshold int
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+}
+
+// SMSConfig holds outgoing text message configuration. The "file" driver
+// appends messages to FilePath; any other driver logs them. Both are
+// stand-ins for development until a provider is configured.
+type SMSConfig struct {
+	Driver   string
+	From     string
+	FilePath string
+}
+
+// OrganizationConfig holds multi-tenant organization configuration
+type OrganizationConfig struct {
+	InvitationTTL time.Duration
+	InvitationURL string
+}
+
+// APIKeyConfig holds personal API key configuration
+type APIKeyConfig struct {
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+}
+
+// WebhookConfig holds outbox dispatch and webhook delivery configuration
+type WebhookConfig struct {
+	Enabled        bool
+	PollInterval   time.Duration
+	BatchSize      int
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	RequestTimeout time.Duration
+	AllowHTTP      bool
+	// AllowPrivateNetworks lets deliveries reach loopback, private and
+	// link-local addresses, for local development only
+	AllowPrivateNetworks bool
+}
+
+// PasswordConfig holds password history and expiry defaults.
+// Organizations may tighten them for their members. The Hash* fields are
+// the Argon2id parameters for new hashes; stored hashes made with other
+// parameters are upgraded on the user's next sign-in.
+type PasswordConfig struct {
+	HistoryCount    int
+	MaxAgeDays      int
+	HashMemory      int // KiB
+	HashIterations  int
+	HashParallelism int
 }
 
+// SessionConfig holds sign-in session configuration. Impersonation
+// sessions end after ImpersonationTTL and cannot be extended. Sign-in
+// links are confirmed through MagicLinkURL within MagicLinkTTL. Sensitive
+// account changes need an authentication no older than StepUpMaxAge.
+// Devices trusted after a second factor skip it for TrustedDeviceTTL, or
+// never when it is zero; their tokens are signed with TrustedDeviceKey.
+type SessionConfig struct {
+	TTL              time.Duration
+	ImpersonationTTL time.Duration
+	MagicLinkTTL     time.Duration
+	MagicLinkURL     string
+	StepUpMaxAge     time.Duration
+	TrustedDeviceTTL time.Duration
+	TrustedDeviceKey string
+}
+
+// LockoutConfig holds failed sign-in thresholds. After DelayAfter failures
+// an account is locked for BaseDelay, doubling per failure up to MaxDelay;
+// after Threshold failures it is locked for Duration and the user is told.
+type LockoutConfig struct {
+	FailureWindow time.Duration
+	DelayAfter    int
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	Threshold     int
+	Duration      time.Duration
+	IPThreshold   int
+	IPWindow      time.Duration
+	IPDuration    time.Duration
+}
+
+// UserImportConfig holds bulk user import limits. Rows are written
+// BatchSize at a time, each batch in its own transaction; at most
+// MaxErrors row errors are reported back.
+type UserImportConfig struct {
+	BatchSize int
+	MaxErrors int
+	MaxBytes  int64
+	Timeout   time.Duration
+}
+
+// SCIMConfig holds SCIM provisioning configuration. BaseURL is the public
+// address of /scim/v2 used in resource locations; when empty it is taken
+// from the request.
+type SCIMConfig struct {
+	BaseURL string
+}
+
+// OAuthConfig holds OAuth 2.0 token exchange configuration. Exchanged
+// tokens live for TokenTTL, and never beyond the token they came from.
+type OAuthConfig struct {
+	TokenTTL time.Duration
+}
+
+// OTPConfig holds one-time passcode configuration. Codes have Length
+// digits. A challenge lasts ChallengeTTL and allows MaxAttempts guesses
+// and MaxSends codes, sent at least ResendCooldown apart.
+type OTPConfig struct {
+	Length         int
+	ChallengeTTL   time.Duration
+	MaxAttempts    int
+	MaxSends       int
+	ResendCooldown time.Duration
+}
+
+// RiskConfig holds adaptive sign-in thresholds. Each sign-in is scored
+// from what is new about it compared with the user's sign-ins over
+// HistoryRetention; at MFAThreshold a second factor is required and at
+// BlockThreshold the sign-in is refused, where 0 means always for MFA and
+// never for blocking. Networks and travel faster than MaxTravelSpeed km/h
+// are judged with the local GeoIP database at GeoIPPath, if any. Failed
+// attempts count within VelocityWindow.
+type RiskConfig struct {
+	MFAThreshold     int
+	BlockThreshold   int
+	GeoIPPath        string
+	MaxTravelSpeed   int
+	VelocityWindow   time.Duration
+	HistoryRetention time.Duration
+	NotifyNewDevice  bool
+}
+
+// ProfileConfig holds self-service profile configuration. Email changes
+// are confirmed through EmailChangeURL within EmailChangeTTL, and email
+// addresses verified through VerificationURL within VerificationTTL.
+type ProfileConfig struct {
+	EmailChangeTTL  time.Duration
+	EmailChangeURL  string
+	VerificationTTL time.Duration
+	VerificationURL string
+}
+
+// RateLimitConfig holds request rate limiting configuration. Routes are
+// keyed by their ServeMux pattern; unlisted routes use Default.
+type RateLimitConfig struct {
+	Enabled bool
+	Store   string
+	Default RateLimitRule
+	Routes  map[string]RateLimitRule
+	// IPRoutes are enforced per address alongside Routes, so a caller
+	// cannot escape a per-email rule by varying the address
+	IPRoutes map[string]RateLimitRule
+}
+
+// RateLimitRule allows Requests per Window for each value of Key
+type RateLimitRule struct {
+	Requests int
+	Window   time.Duration
+	Key      string
+}
+
+// Rate limit keys
+const (
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyUser   = "user"
+	RateLimitKeyClient = "client"
+	RateLimitKeyEmail  = "email"
+)
+
 // LoadConfig loads all application configuration from environment variables
 func LoadConfig() *Config {
 	return &Config{
@@ -69,6 +293,22 @@ func LoadConfig() *Config {
 		Timeouts: loadTimeoutConfig(),
 		CORS:     loadCORSConfig(),
 		Validation: loadValidationConfig(),
+		Mail:       loadMailConfig(),
+		Organization: loadOrganizationConfig(),
+		APIKeys:      loadAPIKeyConfig(),
+		Webhooks:     loadWebhookConfig(),
+		RateLimit:    loadRateLimitConfig(),
+		Lockout:      loadLockoutConfig(),
+		UserImport:   loadUserImportConfig(),
+		Security:     loadSecurityHeadersConfig(),
+		Password:     loadPasswordConfig(),
+		Sessions:     loadSessionConfig(),
+		SCIM:         loadSCIMConfig(),
+		Profile:      loadProfileConfig(),
+		OAuth:        loadOAuthConfig(),
+		OTP:          loadOTPConfig(),
+		SMS:          loadSMSConfig(),
+		Risk:         loadRiskConfig(),
 	}
 }
 
@@ -114,22 +354,276 @@ func loadTimeoutConfig() TimeoutConfig {
 func loadCORSConfig() CORSConfig {
 	return CORSConfig{
 		AllowedOrigins:   getEnvSlice("CORS_ALLOWED_ORIGINS", []string{"*"}),
-		AllowedMethods:   getEnvSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		AllowedHeaders:   getEnvSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization"}),
-		ExposedHeaders:   getEnvSlice("CORS_EXPOSED_HEADERS", []string{}),
+		AllowedMethods:   getEnvSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		AllowedHeaders:   getEnvSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-Organization-ID", "X-Trusted-Device"}),
+		ExposedHeaders:   getEnvSlice("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "X-Impersonated-By"}),
 		AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
 		MaxAge:           getEnvInt("CORS_MAX_AGE", 86400), // 24 hours
 	}
 }
 
+// Security header values of "off" omit the header entirely
+func loadSecurityHeadersConfig() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		HSTSMaxAge:              getEnvInt("SECURITY_HSTS_MAX_AGE", 31536000), // 1 year
+		HSTSIncludeSubdomains:   getEnvBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+		HSTSPreload:             getEnvBool("SECURITY_HSTS_PRELOAD", false),
+		ContentSecurityPolicy:   getEnvHeader("SECURITY_CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+		ContentTypeOptions:      getEnvHeader("SECURITY_CONTENT_TYPE_OPTIONS", "nosniff"),
+		ReferrerPolicy:          getEnvHeader("SECURITY_REFERRER_POLICY", "no-referrer"),
+		PermissionsPolicy:       getEnvHeader("SECURITY_PERMISSIONS_POLICY", "accelerometer=(), camera=(), geolocation=(), microphone=(), payment=(), usb=()"),
+		CrossOriginOpenerPolicy: getEnvHeader("SECURITY_CROSS_ORIGIN_OPENER_POLICY", "same-origin"),
+		NoStorePaths:            getEnvSlice("SECURITY_NO_STORE_PATHS", []string{"/api/", "/oauth/", "/scim/"}),
+	}
+}
+
 func loadValidationConfig() ValidationConfig {
 	return ValidationConfig{
 		MaxNameLength:  getEnvInt("VALIDATION_MAX_NAME_LENGTH", 100),
 		MaxEmailLength: getEnvInt("VALIDATION_MAX_EMAIL_LENGTH", 100),
 		EmailRegex:     getEnv("VALIDATION_EMAIL_REGEX", `^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`),
+		SlugRegex:      getEnv("VALIDATION_SLUG_REGEX", `^[a-z0-9]+(?:-[a-z0-9]+)*$`),
+
+		PasswordMinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 12),
+		PasswordMaxLength:       getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireUpper:    getEnvBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:    getEnvBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:    getEnvBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:   getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordMinStrength:     getEnvInt("PASSWORD_MIN_STRENGTH", 3),
+		BreachedPasswordsDir:    getEnv("PASSWORD_BREACHED_DIR", ""),
+		PasswordBreachThreshold: getEnvInt("PASSWORD_BREACH_THRESHOLD", 1),
 	}
 }
 
+func loadMailConfig() MailConfig {
+	return MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", "log"),
+		From:         getEnv("MAIL_FROM", "no-reply@identity.local"),
+		SMTPHost:     getEnv("MAIL_SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("MAIL_SMTP_PORT", "587"),
+		SMTPUser:     getEnv("MAIL_SMTP_USER", ""),
+		SMTPPassword: getEnv("MAIL_SMTP_PASSWORD", ""),
+	}
+}
+
+func loadSMSConfig() SMSConfig {
+	return SMSConfig{
+		Driver:   getEnv("SMS_DRIVER", "log"),
+		From:     getEnv("SMS_FROM", "Identity"),
+		FilePath: getEnv("SMS_FILE_PATH", "sms.log"),
+	}
+}
+
+func loadOrganizationConfig() OrganizationConfig {
+	return OrganizationConfig{
+		InvitationTTL: getEnvDuration("ORG_INVITATION_TTL", 7*24*time.Hour),
+		InvitationURL: getEnv("ORG_INVITATION_URL", "http://localhost:5173/invitations"),
+	}
+}
+
+func loadAPIKeyConfig() APIKeyConfig {
+	return APIKeyConfig{
+		DefaultTTL: getEnvDuration("API_KEY_DEFAULT_TTL", 90*24*time.Hour),
+		MaxTTL:     getEnvDuration("API_KEY_MAX_TTL", 365*24*time.Hour),
+	}
+}
+
+func loadWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		Enabled:        getEnvBool("WEBHOOK_DISPATCHER_ENABLED", true),
+		PollInterval:   getEnvDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second),
+		BatchSize:      getEnvInt("WEBHOOK_BATCH_SIZE", 100),
+		MaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		InitialBackoff: getEnvDuration("WEBHOOK_INITIAL_BACKOFF", 30*time.Second),
+		MaxBackoff:     getEnvDuration("WEBHOOK_MAX_BACKOFF", 6*time.Hour),
+		RequestTimeout: getEnvDuration("WEBHOOK_REQUEST_TIMEOUT", 10*time.Second),
+		AllowHTTP:      getEnvBool("WEBHOOK_ALLOW_HTTP", false),
+
+		AllowPrivateNetworks: getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+	}
+}
+
+func loadUserImportConfig() UserImportConfig {
+	return UserImportConfig{
+		BatchSize: getEnvInt("USER_IMPORT_BATCH_SIZE", 500),
+		MaxErrors: getEnvInt("USER_IMPORT_MAX_ERRORS", 1000),
+		MaxBytes:  int64(getEnvInt("USER_IMPORT_MAX_BYTES", 64<<20)),
+		Timeout:   getEnvDuration("USER_IMPORT_TIMEOUT", 30*time.Minute),
+	}
+}
+
+func loadRateLimitConfig() RateLimitConfig {
+	routes := map[string]RateLimitRule{
+		// Invitation tokens are guessable only by brute force
+		"/api/invitations/decline":    {Requests: 10, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/invitations/accept":     {Requests: 10, Window: time.Minute, Key: RateLimitKeyUser},
+		"/api/auth/verify-email":      {Requests: 10, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/magic-link/redeem": {Requests: 10, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/otp/send":          {Requests: 10, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/otp/verify":        {Requests: 10, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/step-up":           {Requests: 10, Window: time.Minute, Key: RateLimitKeyUser},
+		"/api/auth/step-up/verify":    {Requests: 10, Window: time.Minute, Key: RateLimitKeyUser},
+		// Each invitation or verification link sends an email
+		"/api/organizations/invitations/create": {Requests: 30, Window: time.Hour, Key: RateLimitKeyUser},
+		"/api/admin/users/{id}/verification":    {Requests: 30, Window: time.Hour, Key: RateLimitKeyUser},
+		"/api/auth/magic-link":                  {Requests: 5, Window: 15 * time.Minute, Key: RateLimitKeyEmail},
+		"/api/auth/otp":                         {Requests: 5, Window: 15 * time.Minute, Key: RateLimitKeyEmail},
+		"/api/users/create":                     {Requests: 60, Window: time.Minute, Key: RateLimitKeyUser},
+		"/api/auth/sign-in":                     {Requests: 10, Window: time.Minute, Key: RateLimitKeyEmail},
+		"/api/auth/password/expired":            {Requests: 10, Window: time.Minute, Key: RateLimitKeyEmail},
+	}
+	ipRoutes := map[string]RateLimitRule{
+		"/api/auth/sign-in":          {Requests: 30, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/password/expired": {Requests: 30, Window: time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/magic-link":       {Requests: 20, Window: 15 * time.Minute, Key: RateLimitKeyIP},
+		"/api/auth/otp":              {Requests: 20, Window: 15 * time.Minute, Key: RateLimitKeyIP},
+	}
+
+	// RATE_LIMIT_ROUTES overrides individual routes, e.g.
+	// "/api/users/create=10/1m:user;/api/invitations/decline=5/1m:ip", and
+	// RATE_LIMIT_IP_ROUTES the per-address rules, e.g. "/api/auth/otp=10/15m"
+	overrideRateLimitRoutes(routes, os.Getenv("RATE_LIMIT_ROUTES"))
+	overrideRateLimitRoutes(ipRoutes, os.Getenv("RATE_LIMIT_IP_ROUTES"))
+	for pattern, rule := range ipRoutes {
+		rule.Key = RateLimitKeyIP
+		ipRoutes[pattern] = rule
+	}
+
+	return RateLimitConfig{
+		Enabled:  getEnvBool("RATE_LIMIT_ENABLED", true),
+		Store:    getEnv("RATE_LIMIT_STORE", "memory"),
+		Default:  getEnvRateLimitRule("RATE_LIMIT_DEFAULT", RateLimitRule{Requests: 300, Window: time.Minute, Key: RateLimitKeyClient}),
+		Routes:   routes,
+		IPRoutes: ipRoutes,
+	}
+}
+
+// overrideRateLimitRoutes applies "pattern=rule" entries separated by
+// semicolons, skipping malformed ones
+func overrideRateLimitRoutes(routes map[string]RateLimitRule, entries string) {
+	for _, entry := range strings.Split(entries, ";") {
+		pattern, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		if rule, err := ParseRateLimitRule(spec); err == nil {
+			routes[strings.TrimSpace(pattern)] = rule
+		}
+	}
+}
+
+func loadPasswordConfig() PasswordConfig {
+	return PasswordConfig{
+		HistoryCount:    getEnvInt("PASSWORD_HISTORY_COUNT", 5),
+		MaxAgeDays:      getEnvInt("PASSWORD_MAX_AGE_DAYS", 0), // 0 disables expiry
+		HashMemory:      getEnvInt("PASSWORD_HASH_MEMORY", 64*1024),
+		HashIterations:  getEnvInt("PASSWORD_HASH_ITERATIONS", 3),
+		HashParallelism: getEnvInt("PASSWORD_HASH_PARALLELISM", 4),
+	}
+}
+
+func loadSessionConfig() SessionConfig {
+	return SessionConfig{
+		TTL:              getEnvDuration("SESSION_TTL", 24*time.Hour),
+		ImpersonationTTL: getEnvDuration("SESSION_IMPERSONATION_TTL", 15*time.Minute),
+		MagicLinkTTL:     getEnvDuration("SESSION_MAGIC_LINK_TTL", 10*time.Minute),
+		MagicLinkURL:     getEnv("SESSION_MAGIC_LINK_URL", "http://localhost:5173/sign-in/link"),
+		StepUpMaxAge:     getEnvDuration("SESSION_STEP_UP_MAX_AGE", 10*time.Minute),
+		TrustedDeviceTTL: getEnvDuration("SESSION_TRUSTED_DEVICE_TTL", 30*24*time.Hour),
+		TrustedDeviceKey: getEnv("SESSION_TRUSTED_DEVICE_KEY", ""),
+	}
+}
+
+func loadProfileConfig() ProfileConfig {
+	return ProfileConfig{
+		EmailChangeTTL:  getEnvDuration("PROFILE_EMAIL_CHANGE_TTL", 24*time.Hour),
+		EmailChangeURL:  getEnv("PROFILE_EMAIL_CHANGE_URL", "http://localhost:5173/email/verify"),
+		VerificationTTL: getEnvDuration("PROFILE_VERIFICATION_TTL", 72*time.Hour),
+		VerificationURL: getEnv("PROFILE_VERIFICATION_URL", "http://localhost:5173/email/confirm"),
+	}
+}
+
+func loadOTPConfig() OTPConfig {
+	return OTPConfig{
+		Length:         getEnvInt("OTP_LENGTH", 6),
+		ChallengeTTL:   getEnvDuration("OTP_CHALLENGE_TTL", 10*time.Minute),
+		MaxAttempts:    getEnvInt("OTP_MAX_ATTEMPTS", 5),
+		MaxSends:       getEnvInt("OTP_MAX_SENDS", 5),
+		ResendCooldown: getEnvDuration("OTP_RESEND_COOLDOWN", 30*time.Second),
+	}
+}
+
+func loadRiskConfig() RiskConfig {
+	return RiskConfig{
+		MFAThreshold:     getEnvInt("RISK_MFA_THRESHOLD", 30),
+		BlockThreshold:   getEnvInt("RISK_BLOCK_THRESHOLD", 90),
+		GeoIPPath:        getEnv("RISK_GEOIP_PATH", ""),
+		MaxTravelSpeed:   getEnvInt("RISK_MAX_TRAVEL_SPEED", 1000),
+		VelocityWindow:   getEnvDuration("RISK_VELOCITY_WINDOW", time.Hour),
+		HistoryRetention: getEnvDuration("RISK_HISTORY_RETENTION", 90*24*time.Hour),
+		NotifyNewDevice:  getEnvBool("RISK_NOTIFY_NEW_DEVICE", true),
+	}
+}
+
+func loadOAuthConfig() OAuthConfig {
+	return OAuthConfig{
+		TokenTTL: getEnvDuration("OAUTH_TOKEN_TTL", 5*time.Minute),
+	}
+}
+
+func loadSCIMConfig() SCIMConfig {
+	return SCIMConfig{
+		BaseURL: strings.TrimSuffix(getEnv("SCIM_BASE_URL", ""), "/"),
+	}
+}
+
+func loadLockoutConfig() LockoutConfig {
+	return LockoutConfig{
+		FailureWindow: getEnvDuration("LOCKOUT_FAILURE_WINDOW", 24*time.Hour),
+		DelayAfter:    getEnvInt("LOCKOUT_DELAY_AFTER", 3),
+		BaseDelay:     getEnvDuration("LOCKOUT_BASE_DELAY", time.Second),
+		MaxDelay:      getEnvDuration("LOCKOUT_MAX_DELAY", 5*time.Minute),
+		Threshold:     getEnvInt("LOCKOUT_THRESHOLD", 10),
+		Duration:      getEnvDuration("LOCKOUT_DURATION", 30*time.Minute),
+		IPThreshold:   getEnvInt("LOCKOUT_IP_THRESHOLD", 100),
+		IPWindow:      getEnvDuration("LOCKOUT_IP_WINDOW", 15*time.Minute),
+		IPDuration:    getEnvDuration("LOCKOUT_IP_DURATION", 15*time.Minute),
+	}
+}
+
+// ParseRateLimitRule parses a rule of the form "REQUESTS/WINDOW[:KEY]",
+// e.g. "10/1m:ip". The key defaults to ip.
+func ParseRateLimitRule(spec string) (RateLimitRule, error) {
+	spec, key, hasKey := strings.Cut(strings.TrimSpace(spec), ":")
+	if !hasKey {
+		key = RateLimitKeyIP
+	}
+
+	var rule RateLimitRule
+	requests, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		return rule, fmt.Errorf("rate limit %q must be REQUESTS/WINDOW", spec)
+	}
+	if _, err := fmt.Sscanf(requests, "%d", &rule.Requests); err != nil || rule.Requests <= 0 {
+		return rule, fmt.Errorf("invalid rate limit request count %q", requests)
+	}
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return rule, fmt.Errorf("invalid rate limit window %q", window)
+	}
+	rule.Window = duration
+
+	switch key {
+	case RateLimitKeyIP, RateLimitKeyUser, RateLimitKeyClient, RateLimitKeyEmail:
+		rule.Key = key
+	default:
+		return rule, fmt.Errorf("unknown rate limit key %q", key)
+	}
+
+	return rule, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -163,6 +657,22 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+func getEnvRateLimitRule(key string, defaultValue RateLimitRule) RateLimitRule {
+	if value := os.Getenv(key); value != "" {
+		if rule, err := ParseRateLimitRule(value); err == nil {
+			return rule
+		}
+	}
+	return defaultValue
+}
+
+func getEnvHeader(key, defaultValue string) string {
+	if value := getEnv(key, defaultValue); value != "off" {
+		return value
+	}
+	return ""
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		return []string{value}
@@ -80,7 +80,8 @@ func (d *Database) InitSchema(ctx context.Context) error {
 		return fmt.Errorf("failed to commit schema transaction: %w", err)
 	}
 
-	return nil
+	// Apply versioned migrations on top of the base schema
+	return d.Migrate(ctx)
 }
 
 // BeginTx starts a new transaction
@@ -0,0 +1,917 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Migration represents a versioned, forward-only schema change
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// migrations holds every schema change in the order it must be applied.
+// Never edit a migration that has shipped; append a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create roles and permissions",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS roles (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(50) NOT NULL UNIQUE,
+				description VARCHAR(255) NOT NULL DEFAULT '',
+				built_in BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS role_permissions (
+				role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+				permission VARCHAR(100) NOT NULL,
+				PRIMARY KEY (role_id, permission)
+			)`,
+			`CREATE TABLE IF NOT EXISTS user_roles (
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (user_id, role_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id)`,
+			`INSERT INTO roles (name, description, built_in) VALUES
+				('admin', 'Full access to every resource', TRUE),
+				('support', 'Read-only access for customer support', TRUE),
+				('user', 'Default role for registered users', TRUE)
+			ON CONFLICT (name) DO NOTHING`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, p.permission
+			FROM roles r
+			JOIN (VALUES
+				('admin', 'users:read'),
+				('admin', 'users:create'),
+				('admin', 'roles:read'),
+				('admin', 'roles:assign'),
+				('admin', 'profile:read'),
+				('admin', 'profile:write'),
+				('support', 'users:read'),
+				('support', 'roles:read'),
+				('support', 'profile:read'),
+				('user', 'profile:read'),
+				('user', 'profile:write')
+			) AS p(role, permission) ON p.role = r.name
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "create organizations, memberships and invitations",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS organizations (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(100) NOT NULL,
+				slug VARCHAR(63) NOT NULL UNIQUE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS organization_members (
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (organization_id, user_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id)`,
+			// Roles become org-scoped; a NULL organization means platform-wide
+			`ALTER TABLE user_roles ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE`,
+			`ALTER TABLE user_roles DROP CONSTRAINT IF EXISTS user_roles_pkey`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_roles_scope ON user_roles(user_id, role_id, COALESCE(organization_id, 0))`,
+			`CREATE TABLE IF NOT EXISTS organization_invitations (
+				id SERIAL PRIMARY KEY,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				email VARCHAR(100) NOT NULL,
+				role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				status VARCHAR(20) NOT NULL DEFAULT 'pending',
+				invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				expires_at TIMESTAMP NOT NULL,
+				responded_at TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_invitations_pending
+				ON organization_invitations(organization_id, email) WHERE status = 'pending'`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, p.permission
+			FROM roles r
+			JOIN (VALUES
+				('admin', 'invitations:manage')
+			) AS p(role, permission) ON p.role = r.name
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "enforce tenant isolation with row-level security",
+		Statements: []string{
+			// NULLIF guards against the empty string a reset custom setting reports
+			`CREATE OR REPLACE FUNCTION app_tenant_id() RETURNS INTEGER
+				LANGUAGE sql STABLE
+				AS $$ SELECT NULLIF(current_setting('app.tenant_id', true), '')::integer $$`,
+			`CREATE OR REPLACE FUNCTION app_rls_bypass() RETURNS BOOLEAN
+				LANGUAGE sql STABLE
+				AS $$ SELECT COALESCE(current_setting('app.bypass_rls', true), '') = 'on' $$`,
+			`ALTER TABLE organization_members ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE organization_members FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON organization_members`,
+			`CREATE POLICY tenant_isolation ON organization_members
+				USING (app_rls_bypass() OR organization_id = app_tenant_id())
+				WITH CHECK (app_rls_bypass() OR organization_id = app_tenant_id())`,
+			`ALTER TABLE organization_invitations ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE organization_invitations FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON organization_invitations`,
+			`CREATE POLICY tenant_isolation ON organization_invitations
+				USING (app_rls_bypass() OR organization_id = app_tenant_id())
+				WITH CHECK (app_rls_bypass() OR organization_id = app_tenant_id())`,
+			// Users are global identities; a tenant sees only its own members
+			`ALTER TABLE users ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE users FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON users`,
+			`CREATE POLICY tenant_isolation ON users
+				USING (app_rls_bypass() OR EXISTS (
+					SELECT 1 FROM organization_members om
+					WHERE om.user_id = users.id AND om.organization_id = app_tenant_id()
+				))
+				WITH CHECK (app_rls_bypass() OR EXISTS (
+					SELECT 1 FROM organization_members om
+					WHERE om.user_id = users.id AND om.organization_id = app_tenant_id()
+				))`,
+			// Maintenance jobs log in with a role granted identity_maintenance.
+			// Creating a BYPASSRLS role needs superuser; skip it otherwise.
+			`DO $$
+			BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'identity_maintenance') THEN
+					BEGIN
+						CREATE ROLE identity_maintenance NOLOGIN BYPASSRLS;
+					EXCEPTION WHEN insufficient_privilege THEN
+						RAISE NOTICE 'identity_maintenance role not created: insufficient privilege';
+					END;
+				END IF;
+
+				IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'identity_maintenance') THEN
+					GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO identity_maintenance;
+					GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO identity_maintenance;
+					ALTER DEFAULT PRIVILEGES IN SCHEMA public
+						GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO identity_maintenance;
+					ALTER DEFAULT PRIVILEGES IN SCHEMA public
+						GRANT USAGE, SELECT ON SEQUENCES TO identity_maintenance;
+				END IF;
+			END
+			$$`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "create personal api keys",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS api_keys (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				prefix VARCHAR(16) NOT NULL UNIQUE,
+				key_hash CHAR(64) NOT NULL,
+				scopes TEXT[] NOT NULL DEFAULT '{}',
+				expires_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP,
+				last_used_ip VARCHAR(45),
+				revoked_at TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'api_keys:manage'
+			FROM roles r
+			WHERE r.name IN ('admin', 'support', 'user')
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "create append-only audit events",
+		Statements: []string{
+			// No foreign keys: events must outlive the users and orgs they mention
+			`CREATE TABLE IF NOT EXISTS audit_events (
+				id BIGSERIAL PRIMARY KEY,
+				occurred_at TIMESTAMP NOT NULL,
+				action VARCHAR(100) NOT NULL,
+				outcome VARCHAR(20) NOT NULL,
+				actor_user_id INTEGER,
+				actor_api_key_id INTEGER,
+				organization_id INTEGER,
+				target_type VARCHAR(50) NOT NULL DEFAULT '',
+				target_id VARCHAR(100) NOT NULL DEFAULT '',
+				ip VARCHAR(45) NOT NULL DEFAULT '',
+				user_agent VARCHAR(512) NOT NULL DEFAULT '',
+				request_id VARCHAR(64) NOT NULL DEFAULT '',
+				details JSONB NOT NULL DEFAULT '{}',
+				prev_hash CHAR(64) NOT NULL,
+				hash CHAR(64) NOT NULL UNIQUE
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_events_organization_id ON audit_events(organization_id, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_events_actor_user_id ON audit_events(actor_user_id, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target_type, target_id, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events(action, id)`,
+			`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger
+				LANGUAGE plpgsql
+				AS $$ BEGIN RAISE EXCEPTION 'audit_events is append-only'; END $$`,
+			`DROP TRIGGER IF EXISTS audit_events_no_modify ON audit_events`,
+			`CREATE TRIGGER audit_events_no_modify
+				BEFORE UPDATE OR DELETE ON audit_events
+				FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+			`DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events`,
+			`CREATE TRIGGER audit_events_no_truncate
+				BEFORE TRUNCATE ON audit_events
+				FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only()`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'audit:read' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "create transactional outbox and webhook delivery",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS outbox_events (
+				id BIGSERIAL PRIMARY KEY,
+				event_type VARCHAR(100) NOT NULL,
+				organization_id INTEGER,
+				payload JSONB NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				dispatched_at TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(id) WHERE dispatched_at IS NULL`,
+			`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+				id SERIAL PRIMARY KEY,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				url VARCHAR(2048) NOT NULL,
+				secret VARCHAR(100) NOT NULL,
+				event_types TEXT[] NOT NULL DEFAULT '{}',
+				active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_organization_id ON webhook_endpoints(organization_id)`,
+			`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id BIGSERIAL PRIMARY KEY,
+				event_id BIGINT NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+				endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+				status VARCHAR(20) NOT NULL DEFAULT 'pending',
+				attempts INTEGER NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMP NOT NULL,
+				last_status_code INTEGER,
+				last_error VARCHAR(500),
+				delivered_at TIMESTAMP,
+				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE (event_id, endpoint_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending'`,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id ON webhook_deliveries(endpoint_id, status)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'webhooks:manage' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     7,
+		Description: "create shared rate limit buckets",
+		Statements: []string{
+			// Unlogged: losing buckets on a crash only resets the limits
+			`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+				key VARCHAR(255) PRIMARY KEY,
+				tokens DOUBLE PRECISION NOT NULL,
+				updated_at TIMESTAMP NOT NULL,
+				full_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at)`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "track failed sign-ins and account lockout",
+		Statements: []string{
+			`ALTER TABLE users
+				ADD COLUMN IF NOT EXISTS failed_login_count INTEGER NOT NULL DEFAULT 0,
+				ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP`,
+			`CREATE TABLE IF NOT EXISTS ip_auth_failures (
+				ip VARCHAR(45) PRIMARY KEY,
+				failures INTEGER NOT NULL,
+				window_started_at TIMESTAMP NOT NULL,
+				locked_until TIMESTAMP
+			)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, p.permission
+			FROM roles r
+			JOIN (VALUES
+				('admin', 'users:unlock'),
+				('support', 'users:unlock')
+			) AS p(role, permission) ON p.role = r.name
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     9,
+		Description: "add passwords, sessions and password history",
+		Statements: []string{
+			`ALTER TABLE users
+				ADD COLUMN IF NOT EXISTS password_hash VARCHAR(255),
+				ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS password_change_required BOOLEAN NOT NULL DEFAULT FALSE`,
+			`CREATE TABLE IF NOT EXISTS password_history (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				password_hash VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history(user_id, id DESC)`,
+			`ALTER TABLE organizations
+				ADD COLUMN IF NOT EXISTS password_history_count INTEGER,
+				ADD COLUMN IF NOT EXISTS password_max_age_days INTEGER`,
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash VARCHAR(64) NOT NULL UNIQUE,
+				created_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP,
+				ip VARCHAR(45),
+				user_agent VARCHAR(512),
+				revoked_at TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'password_policy:manage' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "bulk user import and export permissions",
+		Statements: []string{
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, p.permission
+			FROM roles r
+			JOIN (VALUES
+				('admin', 'users:import'),
+				('admin', 'users:export')
+			) AS p(role, permission) ON p.role = r.name
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     11,
+		Description: "add scim provisioning tokens, member status and groups",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS scim_tokens (
+				id SERIAL PRIMARY KEY,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				name VARCHAR(100) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				created_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP,
+				revoked_at TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_scim_tokens_organization_id ON scim_tokens(organization_id)`,
+			// Deactivated members keep their row, and with it their
+			// roles and groups, but lose access to the organization
+			`ALTER TABLE organization_members
+				ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE,
+				ADD COLUMN IF NOT EXISTS external_id VARCHAR(255),
+				ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_organization_members_external_id
+				ON organization_members(organization_id, external_id) WHERE external_id IS NOT NULL`,
+			`CREATE TABLE IF NOT EXISTS groups (
+				id SERIAL PRIMARY KEY,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				display_name VARCHAR(255) NOT NULL,
+				external_id VARCHAR(255),
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_groups_organization_id ON groups(organization_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_groups_external_id
+				ON groups(organization_id, external_id) WHERE external_id IS NOT NULL`,
+			`CREATE TABLE IF NOT EXISTS group_members (
+				group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				PRIMARY KEY (group_id, user_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_group_members_user_id ON group_members(user_id)`,
+			`ALTER TABLE groups ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE groups FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON groups`,
+			`CREATE POLICY tenant_isolation ON groups
+				USING (app_rls_bypass() OR organization_id = app_tenant_id())
+				WITH CHECK (app_rls_bypass() OR organization_id = app_tenant_id())`,
+			`ALTER TABLE group_members ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE group_members FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON group_members`,
+			`CREATE POLICY tenant_isolation ON group_members
+				USING (app_rls_bypass() OR organization_id = app_tenant_id())
+				WITH CHECK (app_rls_bypass() OR organization_id = app_tenant_id())`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'scim:manage' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     12,
+		Description: "record data subject exports",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS data_exports (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+				requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				reason VARCHAR(500) NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id, created_at)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'users:privacy' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     13,
+		Description: "add profile attributes and email changes",
+		Statements: []string{
+			`ALTER TABLE users
+				ADD COLUMN IF NOT EXISTS display_name VARCHAR(100),
+				ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048),
+				ADD COLUMN IF NOT EXISTS locale VARCHAR(35),
+				ADD COLUMN IF NOT EXISTS time_zone VARCHAR(64),
+				ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP`,
+			// A user has at most one pending change; a new request replaces it
+			`CREATE TABLE IF NOT EXISTS email_changes (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+				new_email VARCHAR(100) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				expires_at TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+		},
+	},
+	{
+		Version:     14,
+		Description: "add custom user attributes",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS user_attribute_definitions (
+				id SERIAL PRIMARY KEY,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				name VARCHAR(63) NOT NULL,
+				type VARCHAR(20) NOT NULL,
+				description VARCHAR(255) NOT NULL DEFAULT '',
+				required BOOLEAN NOT NULL DEFAULT FALSE,
+				unique_values BOOLEAN NOT NULL DEFAULT FALSE,
+				pattern VARCHAR(500) NOT NULL DEFAULT '',
+				enum JSONB NOT NULL DEFAULT '[]',
+				claim BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMP NOT NULL,
+				updated_at TIMESTAMP NOT NULL,
+				UNIQUE (organization_id, name)
+			)`,
+			`ALTER TABLE user_attribute_definitions ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE user_attribute_definitions FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON user_attribute_definitions`,
+			`CREATE POLICY tenant_isolation ON user_attribute_definitions
+				USING (app_rls_bypass() OR organization_id = app_tenant_id())
+				WITH CHECK (app_rls_bypass() OR organization_id = app_tenant_id())`,
+			// Values follow their organization's schema, so they live on the
+			// membership rather than the global user row
+			`ALTER TABLE organization_members ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}'`,
+			`CREATE INDEX IF NOT EXISTS idx_organization_members_attributes
+				ON organization_members USING GIN (attributes jsonb_path_ops)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'user_attributes:manage' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     15,
+		Description: "add account disabling and email verification",
+		Statements: []string{
+			`ALTER TABLE users
+				ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP,
+				ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP`,
+			// A link proves control of the address it was sent to; a user has
+			// at most one outstanding link and resending replaces it
+			`CREATE TABLE IF NOT EXISTS email_verifications (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+				email VARCHAR(100) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				expires_at TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'users:manage' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     16,
+		Description: "add impersonation sessions",
+		Statements: []string{
+			// An impersonation session signs in as user_id on behalf of
+			// actor_user_id and only within organization_id
+			`ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+				ADD COLUMN IF NOT EXISTS actor_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'users:impersonate' FROM roles r WHERE r.name IN ('admin', 'support')
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     17,
+		Description: "add oauth clients and token exchange",
+		Statements: []string{
+			// Like SCIM tokens, clients are resolved before any tenant is
+			// known, so queries filter on organization_id themselves
+			`CREATE TABLE IF NOT EXISTS oauth_clients (
+				id SERIAL PRIMARY KEY,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				client_id VARCHAR(64) NOT NULL UNIQUE,
+				name VARCHAR(100) NOT NULL,
+				secret_hash CHAR(64) NOT NULL,
+				allowed_audiences TEXT[] NOT NULL DEFAULT '{}',
+				allowed_scopes TEXT[] NOT NULL DEFAULT '{}',
+				allow_impersonation BOOLEAN NOT NULL DEFAULT FALSE,
+				created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+				created_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP,
+				revoked_at TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_oauth_clients_organization_id ON oauth_clients(organization_id)`,
+			// An exchanged token dies with the session it was ultimately
+			// derived from, and with its client
+			`CREATE TABLE IF NOT EXISTS oauth_access_tokens (
+				id BIGSERIAL PRIMARY KEY,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				client_id INTEGER NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+				organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+				audience VARCHAR(255) NOT NULL,
+				scopes TEXT[] NOT NULL DEFAULT '{}',
+				act JSONB,
+				issued_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_oauth_access_tokens_session_id ON oauth_access_tokens(session_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_oauth_access_tokens_expires_at ON oauth_access_tokens(expires_at)`,
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'oauth_clients:manage' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     18,
+		Description: "add magic link sign-in",
+		Statements: []string{
+			// A link only signs in on the device that asked for it, proven by
+			// device_hash; a user has at most one and a new request replaces it
+			`CREATE TABLE IF NOT EXISTS magic_links (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+				email VARCHAR(100) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				device_hash CHAR(64) NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL
+			)`,
+		},
+	},
+	{
+		Version:     19,
+		Description: "add one-time passcode factors",
+		Statements: []string{
+			// A factor counts once verified_at is set; an email factor
+			// delivers to the account's current address
+			`CREATE TABLE IF NOT EXISTS mfa_factors (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				type VARCHAR(20) NOT NULL,
+				destination VARCHAR(100) NOT NULL DEFAULT '',
+				verified_at TIMESTAMP,
+				created_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP,
+				UNIQUE (user_id, type, destination)
+			)`,
+			// A user has at most one challenge per purpose and a new one
+			// replaces it; sends and sent_at carry over until it expires so
+			// starting again does not skip the resend limits. Codes are
+			// hashed together with the challenge token.
+			`CREATE TABLE IF NOT EXISTS otp_challenges (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				purpose VARCHAR(20) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				first_factor VARCHAR(20) NOT NULL DEFAULT '',
+				factor_id INTEGER REFERENCES mfa_factors(id) ON DELETE CASCADE,
+				channel VARCHAR(20) NOT NULL DEFAULT '',
+				code_hash CHAR(64),
+				attempts INTEGER NOT NULL DEFAULT 0,
+				sends INTEGER NOT NULL DEFAULT 0,
+				sent_at TIMESTAMP,
+				expires_at TIMESTAMP NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				UNIQUE (user_id, purpose)
+			)`,
+		},
+	},
+	{
+		Version:     20,
+		Description: "record how and when sessions were authenticated",
+		Statements: []string{
+			`ALTER TABLE sessions
+				ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}',
+				ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP`,
+			`UPDATE sessions SET auth_time = created_at WHERE auth_time IS NULL`,
+			`ALTER TABLE sessions ALTER COLUMN auth_time SET NOT NULL`,
+		},
+	},
+	{
+		Version:     21,
+		Description: "keep sign-in history for risk scoring",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS sign_in_events (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				session_id BIGINT REFERENCES sessions(id) ON DELETE SET NULL,
+				ip VARCHAR(45) NOT NULL DEFAULT '',
+				user_agent VARCHAR(512) NOT NULL DEFAULT '',
+				asn INTEGER,
+				country VARCHAR(2) NOT NULL DEFAULT '',
+				latitude DOUBLE PRECISION,
+				longitude DOUBLE PRECISION,
+				created_at TIMESTAMP NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_sign_in_events_user_id ON sign_in_events(user_id, created_at DESC)`,
+		},
+	},
+	{
+		Version:     22,
+		Description: "remember devices trusted after a second factor",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS trusted_devices (
+				id BIGSERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				token_hash VARCHAR(64) NOT NULL UNIQUE,
+				created_at TIMESTAMP NOT NULL,
+				expires_at TIMESTAMP NOT NULL,
+				last_used_at TIMESTAMP,
+				ip VARCHAR(45),
+				user_agent VARCHAR(512),
+				revoked_at TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_trusted_devices_user_id ON trusted_devices(user_id)`,
+		},
+	},
+	{
+		Version:     23,
+		Description: "bypass row-level security through a role and isolate later tenant tables",
+		Statements: []string{
+			// BypassTx switches to this role for inherently cross-tenant work.
+			// Creating a BYPASSRLS role needs superuser, so without one an
+			// administrator must create and grant it before upgrading.
+			`DO $$
+			BEGIN
+				IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'identity_rls_bypass') THEN
+					BEGIN
+						CREATE ROLE identity_rls_bypass NOLOGIN BYPASSRLS;
+					EXCEPTION WHEN insufficient_privilege THEN
+						RAISE EXCEPTION 'create the role with: CREATE ROLE identity_rls_bypass NOLOGIN BYPASSRLS; GRANT identity_rls_bypass TO %', current_user;
+					END;
+				END IF;
+
+				IF NOT pg_has_role(current_user, 'identity_rls_bypass', 'MEMBER') THEN
+					BEGIN
+						EXECUTE format('GRANT identity_rls_bypass TO %I', current_user);
+					EXCEPTION WHEN insufficient_privilege THEN
+						RAISE EXCEPTION 'grant the role with: GRANT identity_rls_bypass TO %', current_user;
+					END;
+				END IF;
+
+				GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO identity_rls_bypass;
+				GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO identity_rls_bypass;
+				ALTER DEFAULT PRIVILEGES IN SCHEMA public
+					GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO identity_rls_bypass;
+				ALTER DEFAULT PRIVILEGES IN SCHEMA public
+					GRANT USAGE, SELECT ON SEQUENCES TO identity_rls_bypass;
+			END
+			$$`,
+			// Organization-scoped tables see only the tenant's rows
+			`ALTER TABLE organization_members ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE organization_members FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON organization_members`,
+			`CREATE POLICY tenant_isolation ON organization_members
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE organization_invitations ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE organization_invitations FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON organization_invitations`,
+			`CREATE POLICY tenant_isolation ON organization_invitations
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE groups ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE groups FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON groups`,
+			`CREATE POLICY tenant_isolation ON groups
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE group_members ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE group_members FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON group_members`,
+			`CREATE POLICY tenant_isolation ON group_members
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE user_attribute_definitions ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE user_attribute_definitions FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON user_attribute_definitions`,
+			`CREATE POLICY tenant_isolation ON user_attribute_definitions
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE webhook_endpoints ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE webhook_endpoints FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON webhook_endpoints`,
+			`CREATE POLICY tenant_isolation ON webhook_endpoints
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE scim_tokens ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE scim_tokens FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON scim_tokens`,
+			`CREATE POLICY tenant_isolation ON scim_tokens
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE oauth_clients ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE oauth_clients FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON oauth_clients`,
+			`CREATE POLICY tenant_isolation ON oauth_clients
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			`ALTER TABLE oauth_access_tokens ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE oauth_access_tokens FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON oauth_access_tokens`,
+			`CREATE POLICY tenant_isolation ON oauth_access_tokens
+				USING (organization_id = app_tenant_id())
+				WITH CHECK (organization_id = app_tenant_id())`,
+			// Personal and platform rows have no organization and stay visible
+			// to their owners' queries; organization rows only to the tenant
+			`ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE api_keys FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON api_keys`,
+			`CREATE POLICY tenant_isolation ON api_keys
+				USING (organization_id IS NULL OR organization_id = app_tenant_id())
+				WITH CHECK (organization_id IS NULL OR organization_id = app_tenant_id())`,
+			`ALTER TABLE audit_events ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE audit_events FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON audit_events`,
+			`CREATE POLICY tenant_isolation ON audit_events
+				USING (organization_id IS NULL OR organization_id = app_tenant_id())
+				WITH CHECK (organization_id IS NULL OR organization_id = app_tenant_id())`,
+			`ALTER TABLE outbox_events ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE outbox_events FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON outbox_events`,
+			`CREATE POLICY tenant_isolation ON outbox_events
+				USING (organization_id IS NULL OR organization_id = app_tenant_id())
+				WITH CHECK (organization_id IS NULL OR organization_id = app_tenant_id())`,
+			`ALTER TABLE data_exports ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE data_exports FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON data_exports`,
+			`CREATE POLICY tenant_isolation ON data_exports
+				USING (organization_id IS NULL OR organization_id = app_tenant_id())
+				WITH CHECK (organization_id IS NULL OR organization_id = app_tenant_id())`,
+			`ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY`,
+			`ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY`,
+			`DROP POLICY IF EXISTS tenant_isolation ON webhook_deliveries`,
+			`CREATE POLICY tenant_isolation ON webhook_deliveries
+				USING (EXISTS (
+					SELECT 1 FROM webhook_endpoints e
+					WHERE e.id = webhook_deliveries.endpoint_id AND e.organization_id = app_tenant_id()
+				))
+				WITH CHECK (EXISTS (
+					SELECT 1 FROM webhook_endpoints e
+					WHERE e.id = webhook_deliveries.endpoint_id AND e.organization_id = app_tenant_id()
+				))`,
+			`DROP POLICY IF EXISTS tenant_isolation ON users`,
+			`CREATE POLICY tenant_isolation ON users
+				USING (EXISTS (
+					SELECT 1 FROM organization_members om
+					WHERE om.user_id = users.id AND om.organization_id = app_tenant_id()
+				))
+				WITH CHECK (EXISTS (
+					SELECT 1 FROM organization_members om
+					WHERE om.user_id = users.id AND om.organization_id = app_tenant_id()
+				))`,
+			`DROP FUNCTION IF EXISTS app_rls_bypass()`,
+		},
+	},
+	{
+		Version:     24,
+		Description: "add platform-only audit log verification",
+		Statements: []string{
+			// Only honoured through platform-wide grants; see platformPermissions
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'audit:verify' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     25,
+		Description: "hold an expired password's replacement until the second factor is proven",
+		Statements: []string{
+			`ALTER TABLE otp_challenges ADD COLUMN IF NOT EXISTS pending_password_hash TEXT`,
+		},
+	},
+	{
+		Version:     26,
+		Description: "make disabling an account platform-only",
+		Statements: []string{
+			// Only honoured through platform-wide grants; see platformPermissions
+			`INSERT INTO role_permissions (role_id, permission)
+			SELECT r.id, 'users:disable' FROM roles r WHERE r.name = 'admin'
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+}
+
+// Migrate applies all pending migrations, each in its own transaction
+func (d *Database) Migrate(ctx context.Context) error {
+	createQuery := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`
+	if _, err := d.DB.ExecContext(ctx, createQuery); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	for _, m := range migrations {
+		if err := d.applyMigration(ctx, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Database) applyMigration(ctx context.Context, m Migration) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Will rollback if not committed
+
+	// Serialize concurrent replicas so each migration runs exactly once
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))"); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	var applied bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)",
+		m.Version,
+	).Scan(&applied)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check migration state: %w", err)
+	}
+	if applied {
+		return nil
+	}
+
+	// Policies created before version 23 let data changes in migrations see
+	// every tenant's rows through this setting; later migrations that change
+	// tenant rows run those statements under SET LOCAL ROLE identity_rls_bypass
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('app.bypass_rls', 'on', true)"); err != nil {
+		return fmt.Errorf("failed to bypass row-level security: %w", err)
+	}
+
+	for _, stmt := range m.Statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, description) VALUES ($1, $2)",
+		m.Version, m.Description,
+	); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx scoped to the given organization
+func WithTenant(ctx context.Context, orgID int) context.Context {
+	return context.WithValue(ctx, tenantKey{}, orgID)
+}
+
+// TenantFromContext returns the organization the context is scoped to, if any
+func TenantFromContext(ctx context.Context) (int, bool) {
+	orgID, ok := ctx.Value(tenantKey{}).(int)
+	return orgID, ok && orgID > 0
+}
+
+// TenantTx runs fn in a transaction whose row-level security policies are
+// scoped to the tenant stored in ctx. Without a tenant the policies match no
+// rows, so a query that forgets its tenant filter fails closed.
+func (d *Database) TenantTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return d.withTx(ctx, func(tx *sql.Tx) error {
+		if orgID, ok := TenantFromContext(ctx); ok {
+			if _, err := tx.ExecContext(ctx,
+				"SELECT set_config('app.tenant_id', $1, true)",
+				strconv.Itoa(orgID),
+			); err != nil {
+				return fmt.Errorf("failed to set tenant: %w", err)
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// BypassRole is the BYPASSRLS role BypassTx switches to. Switching roles
+// is a statement of its own, so unlike a setting it cannot be flipped from
+// inside another query.
+const BypassRole = "identity_rls_bypass"
+
+// BypassTx runs fn in a transaction that is exempt from tenant row-level
+// security. Use it only for operations that are inherently cross-tenant,
+// such as global email uniqueness checks or listing a user's memberships.
+func (d *Database) BypassTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return d.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL ROLE "+BypassRole); err != nil {
+			return fmt.Errorf("failed to bypass row-level security: %w", err)
+		}
+		return fn(tx)
+	})
+}
+
+// RowSecurityEnforced reports whether row-level security applies to the
+// connected role. Superusers and BYPASSRLS roles ignore every policy.
+func (d *Database) RowSecurityEnforced(ctx context.Context) (bool, error) {
+	var super, bypass bool
+	err := d.DB.QueryRowContext(ctx,
+		"SELECT rolsuper, rolbypassrls FROM pg_roles WHERE rolname = current_user",
+	).Scan(&super, &bypass)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect database role: %w", err)
+	}
+	return !super && !bypass, nil
+}
+
+func (d *Database) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Will rollback if not committed
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 )
 
-// AppError represents an application error with code and message
+// AppError represents an application error with code and message.
+// Reason, when set, is a stable machine-readable identifier clients can
+// branch on without parsing Message.
 type AppError struct {
 	Code    int
 	Message string
+	Reason  string
 	Err     error
 }
 
@@ -22,6 +25,12 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// WithReason sets the machine-readable reason and returns the error
+func (e *AppError) WithReason(reason string) *AppError {
+	e.Reason = reason
+	return e
+}
+
 // Common error constructors
 func NewBadRequestError(message string, err error) *AppError {
 	return &AppError{
@@ -53,3 +62,24 @@ func NewConflictError(message string, err error) *AppError {
 		Err:     err,
 	}
 }
+
+func NewUnauthorizedError(message string) *AppError {
+	return &AppError{
+		Code:    401,
+		Message: message,
+	}
+}
+
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Code:    403,
+		Message: message,
+	}
+}
+
+func NewTooManyRequestsError(message string) *AppError {
+	return &AppError{
+		Code:    429,
+		Message: message,
+	}
+}
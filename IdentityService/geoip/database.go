@@ -0,0 +1,123 @@
+package geoip
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Location is what the database knows about an address. ASN is zero and
+// Country empty when unknown; the coordinates are only meaningful when
+// HasCoordinates is set.
+type Location struct {
+	ASN            int
+	Country        string
+	Latitude       float64
+	Longitude      float64
+	HasCoordinates bool
+}
+
+// Database resolves IP addresses using a local CSV file with one network
+// per line as "network,asn,country,latitude,longitude", for example
+// "203.0.113.0/24,64500,AU,-33.8688,151.2093". Fields other than the
+// network may be empty, lines starting with "#" are ignored, and networks
+// must not overlap. A nil Database knows no addresses.
+type Database struct {
+	networks []network
+}
+
+type network struct {
+	prefix   netip.Prefix
+	location Location
+}
+
+// Open loads the database at path into memory
+func Open(path string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geoip database: %w", err)
+	}
+	defer file.Close()
+
+	db := &Database{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		entry, err := parseNetwork(text)
+		if err != nil {
+			return nil, fmt.Errorf("geoip database line %d: %w", line, err)
+		}
+		db.networks = append(db.networks, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read geoip database: %w", err)
+	}
+
+	sort.Slice(db.networks, func(i, j int) bool {
+		return db.networks[i].prefix.Addr().Less(db.networks[j].prefix.Addr())
+	})
+	return db, nil
+}
+
+// Lookup returns the location of the network containing ip
+func (d *Database) Lookup(ip string) (Location, bool) {
+	if d == nil {
+		return Location{}, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}, false
+	}
+	addr = addr.Unmap()
+
+	// The candidate is the last network starting at or before addr
+	i := sort.Search(len(d.networks), func(i int) bool {
+		return addr.Less(d.networks[i].prefix.Addr())
+	})
+	if i == 0 || !d.networks[i-1].prefix.Contains(addr) {
+		return Location{}, false
+	}
+	return d.networks[i-1].location, true
+}
+
+func parseNetwork(text string) (network, error) {
+	fields := strings.Split(text, ",")
+	if len(fields) != 5 {
+		return network{}, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	prefix, err := netip.ParsePrefix(fields[0])
+	if err != nil {
+		return network{}, fmt.Errorf("invalid network %q", fields[0])
+	}
+	entry := network{prefix: prefix.Masked(), location: Location{Country: strings.ToUpper(fields[2])}}
+
+	if fields[1] != "" {
+		if entry.location.ASN, err = strconv.Atoi(strings.TrimPrefix(strings.ToUpper(fields[1]), "AS")); err != nil {
+			return network{}, fmt.Errorf("invalid asn %q", fields[1])
+		}
+	}
+	if fields[3] != "" || fields[4] != "" {
+		lat, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return network{}, fmt.Errorf("invalid latitude %q", fields[3])
+		}
+		lon, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return network{}, fmt.Errorf("invalid longitude %q", fields[4])
+		}
+		entry.location.Latitude, entry.location.Longitude, entry.location.HasCoordinates = lat, lon, true
+	}
+	return entry, nil
+}
@@ -3,3 +3,8 @@ module identity-service
 go 1.25.5
 
 require github.com/lib/pq v1.10.9
+
+require (
+	golang.org/x/crypto v0.54.0
+	golang.org/x/sys v0.47.0 // indirect
+)
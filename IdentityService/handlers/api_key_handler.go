@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// APIKeyHandler handles HTTP requests for the caller's personal API keys
+type APIKeyHandler struct {
+	service service.APIKeyService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance
+func NewAPIKeyHandler(svc service.APIKeyService, cfg *config.Config, log *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// APIKeys handles GET requests to list and POST requests to create API keys
+func (h *APIKeyHandler) APIKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getAPIKeys(w, r)
+	case http.MethodPost:
+		h.createAPIKey(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// RevokeAPIKey handles DELETE requests to revoke one of the caller's API keys
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid api key id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RevokeAPIKey(ctx, principal.UserID, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIKeyHandler) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	keys, err := h.service.GetAPIKeys(ctx, principal.UserID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	key, err := h.service.CreateAPIKey(ctx, principal, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, key)
+}
+
+// Ensure APIKeyHandler implements APIKeyHandlerInterface
+var _ APIKeyHandlerInterface = (*APIKeyHandler)(nil)
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// AttributeHandler handles HTTP requests for custom user attributes
+type AttributeHandler struct {
+	service service.AttributeService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewAttributeHandler creates a new AttributeHandler instance
+func NewAttributeHandler(svc service.AttributeService, cfg *config.Config, log *slog.Logger) *AttributeHandler {
+	return &AttributeHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Definitions handles GET requests to list and POST requests to add
+// entries of the organization's attribute schema
+func (h *AttributeHandler) Definitions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getDefinitions(w, r)
+	case http.MethodPost:
+		h.createDefinition(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// Definition handles PUT requests to replace and DELETE requests to remove
+// an attribute definition
+func (h *AttributeHandler) Definition(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid user attribute id", err))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+		defer cancel()
+
+		if err := h.service.DeleteDefinition(ctx, orgID, id); err != nil {
+			handleError(w, r, h.log, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req models.AttributeDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	def, err := h.service.UpdateDefinition(ctx, orgID, id, req)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, def)
+}
+
+// UserAttributes handles GET requests to view and PUT requests to replace
+// a member's custom attributes
+func (h *AttributeHandler) UserAttributes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid user id", err))
+		return
+	}
+
+	var req models.SetAttributesRequest
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	var attrs map[string]interface{}
+	if r.Method == http.MethodPut {
+		attrs, err = h.service.SetAttributes(ctx, orgID, userID, req.Attributes)
+	} else {
+		attrs, err = h.service.GetAttributes(ctx, orgID, userID)
+	}
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, models.SetAttributesRequest{Attributes: attrs})
+}
+
+func (h *AttributeHandler) getDefinitions(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	defs, err := h.service.GetDefinitions(ctx, orgID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, defs)
+}
+
+func (h *AttributeHandler) createDefinition(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.AttributeDefinitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	def, err := h.service.CreateDefinition(ctx, orgID, req)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, def)
+}
+
+// Ensure AttributeHandler implements AttributeHandlerInterface
+var _ AttributeHandlerInterface = (*AttributeHandler)(nil)
@@ -0,0 +1,372 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// AuthHandler handles HTTP requests for password sign-in and sessions
+type AuthHandler struct {
+	service service.AuthService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewAuthHandler creates a new AuthHandler instance
+func NewAuthHandler(svc service.AuthService, cfg *config.Config, log *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// SignIn handles POST requests to exchange an email and password for a session token
+func (h *AuthHandler) SignIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.SignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	session, err := h.service.SignIn(ctx, req.Email, req.Password)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, session)
+}
+
+// ChangeExpiredPassword handles POST requests to replace a password that
+// sign-in refused as expired or flagged for change
+func (h *AuthHandler) ChangeExpiredPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.ChangeExpiredPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	session, err := h.service.ChangeExpiredPassword(ctx, req.Email, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, session)
+}
+
+// RequestMagicLink handles POST requests to email a sign-in link. The
+// response is 202 whether or not the address has an account.
+func (h *AuthHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	link, err := h.service.RequestMagicLink(ctx, req.Email)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusAccepted, link)
+}
+
+// RedeemMagicLink handles POST requests to sign in with an emailed link
+// from the device that asked for it
+func (h *AuthHandler) RedeemMagicLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.RedeemMagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	session, err := h.service.RedeemMagicLink(ctx, req.Token, req.DeviceToken)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, session)
+}
+
+// RequestSignInCode handles POST requests to send a one-time passcode for
+// passwordless sign-in. The response is 202 whether or not the address has
+// an account.
+func (h *AuthHandler) RequestSignInCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.OTPSignInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	challenge, err := h.service.RequestSignInCode(ctx, req.Email, req.Channel)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusAccepted, challenge)
+}
+
+// SendCode handles POST requests to send a code, or a new one, for a
+// passwordless or MFA challenge
+func (h *AuthHandler) SendCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.SendOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.SendCode(ctx, req.OTPToken, req.FactorID); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifyCode handles POST requests to complete a passwordless or MFA
+// challenge with its code
+func (h *AuthHandler) VerifyCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.VerifyOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	session, err := h.service.VerifyCode(ctx, req.OTPToken, req.Code, req.RememberDevice)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, session)
+}
+
+// StepUp handles POST requests to re-authenticate the caller's session
+// for operations that need a recent or stronger sign-in
+func (h *AuthHandler) StepUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.StepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	result, err := h.service.StepUp(ctx, principal, req.Password)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, result)
+}
+
+// VerifyStepUp handles POST requests to complete a step-up with its code
+func (h *AuthHandler) VerifyStepUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.VerifyOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	result, err := h.service.VerifyStepUp(ctx, principal, req.OTPToken, req.Code)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, result)
+}
+
+// SignOut handles POST requests to end the caller's session
+func (h *AuthHandler) SignOut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.SignOut(ctx, principal); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword handles POST requests to change the caller's password
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.ChangePassword(ctx, principal, req.CurrentPassword, req.NewPassword); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Impersonate handles POST requests to start a session acting as the user
+// in the path
+func (h *AuthHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+	if _, err := activeOrganization(r); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid user id", err))
+		return
+	}
+
+	var req models.UserActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	session, err := h.service.Impersonate(ctx, principal, userID, req.Reason)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, session)
+}
+
+// Ensure AuthHandler implements AuthHandlerInterface
+var _ AuthHandlerInterface = (*AuthHandler)(nil)
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// DataExportHandler handles HTTP requests for data subject access and
+// rectification
+type DataExportHandler struct {
+	service service.DataExportService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewDataExportHandler creates a new DataExportHandler instance
+func NewDataExportHandler(svc service.DataExportService, cfg *config.Config, log *slog.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// ExportOwnData handles GET requests to download the caller's data archive
+func (h *DataExportHandler) ExportOwnData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	export, err := h.service.ExportOwnData(ctx, principal)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	h.writeArchive(w, export)
+}
+
+// ExportUserData handles POST requests to produce a user's data archive on
+// their behalf. The body may carry the reason, such as a ticket reference.
+func (h *DataExportHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid user id", err))
+		return
+	}
+
+	var req models.DataExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !stderrors.Is(err, io.EOF) {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	export, err := h.service.ExportUserData(ctx, principal, userID, req.Reason)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	h.writeArchive(w, export)
+}
+
+// GetExportRecords handles GET requests to list when a user's data was
+// exported. Organization admins only see exports for their organization.
+func (h *DataExportHandler) GetExportRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid user id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	records, err := h.service.GetExportRecords(ctx, principal.OrganizationID, userID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, records)
+}
+
+// RectifyUser handles PUT requests to correct a member's name and email on
+// their behalf
+func (h *DataExportHandler) RectifyUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid user id", err))
+		return
+	}
+
+	var req models.RectifyUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	user, err := h.service.RectifyUser(ctx, orgID, userID, req.Name, req.Email, req.Reason)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, user)
+}
+
+// writeArchive sends an export as a JSON file download
+func (h *DataExportHandler) writeArchive(w http.ResponseWriter, export *models.DataExport) {
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.json"`, export.Profile.ID))
+	writeJSONResponse(w, h.log, http.StatusOK, export)
+}
+
+// Ensure DataExportHandler implements DataExportHandlerInterface
+var _ DataExportHandlerInterface = (*DataExportHandler)(nil)
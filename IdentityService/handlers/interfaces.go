@@ -13,4 +13,130 @@ type Handler interface {
 type UserHandlerInterface interface {
 	GetAllUsers(w http.ResponseWriter, r *http.Request)
 	CreateUser(w http.ResponseWriter, r *http.Request)
+	UnlockUser(w http.ResponseWriter, r *http.Request)
+	DisableUser(w http.ResponseWriter, r *http.Request)
+	EnableUser(w http.ResponseWriter, r *http.Request)
+	SignOutUser(w http.ResponseWriter, r *http.Request)
+	RequirePasswordChange(w http.ResponseWriter, r *http.Request)
+	ResendVerification(w http.ResponseWriter, r *http.Request)
+}
+
+// RoleHandlerInterface defines the interface for role HTTP handlers
+type RoleHandlerInterface interface {
+	GetAllRoles(w http.ResponseWriter, r *http.Request)
+	AssignRole(w http.ResponseWriter, r *http.Request)
+	RemoveRole(w http.ResponseWriter, r *http.Request)
+}
+
+// OrganizationHandlerInterface defines the interface for organization HTTP handlers
+type OrganizationHandlerInterface interface {
+	GetMyOrganizations(w http.ResponseWriter, r *http.Request)
+	CreateOrganization(w http.ResponseWriter, r *http.Request)
+	GetInvitations(w http.ResponseWriter, r *http.Request)
+	CreateInvitation(w http.ResponseWriter, r *http.Request)
+	RevokeInvitation(w http.ResponseWriter, r *http.Request)
+	AcceptInvitation(w http.ResponseWriter, r *http.Request)
+	DeclineInvitation(w http.ResponseWriter, r *http.Request)
+	PasswordPolicy(w http.ResponseWriter, r *http.Request)
+}
+
+// APIKeyHandlerInterface defines the interface for API key HTTP handlers
+type APIKeyHandlerInterface interface {
+	APIKeys(w http.ResponseWriter, r *http.Request)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+}
+
+// AuditHandlerInterface defines the interface for audit log HTTP handlers
+type AuditHandlerInterface interface {
+	GetAuditEvents(w http.ResponseWriter, r *http.Request)
+	VerifyAuditLog(w http.ResponseWriter, r *http.Request)
+}
+
+// WebhookHandlerInterface defines the interface for webhook HTTP handlers
+type WebhookHandlerInterface interface {
+	Endpoints(w http.ResponseWriter, r *http.Request)
+	DeleteEndpoint(w http.ResponseWriter, r *http.Request)
+	GetDeliveries(w http.ResponseWriter, r *http.Request)
+	RetryDelivery(w http.ResponseWriter, r *http.Request)
+}
+
+// AuthHandlerInterface defines the interface for sign-in and session HTTP handlers
+type AuthHandlerInterface interface {
+	SignIn(w http.ResponseWriter, r *http.Request)
+	ChangeExpiredPassword(w http.ResponseWriter, r *http.Request)
+	RequestMagicLink(w http.ResponseWriter, r *http.Request)
+	RedeemMagicLink(w http.ResponseWriter, r *http.Request)
+	RequestSignInCode(w http.ResponseWriter, r *http.Request)
+	SendCode(w http.ResponseWriter, r *http.Request)
+	VerifyCode(w http.ResponseWriter, r *http.Request)
+	StepUp(w http.ResponseWriter, r *http.Request)
+	VerifyStepUp(w http.ResponseWriter, r *http.Request)
+	SignOut(w http.ResponseWriter, r *http.Request)
+	ChangePassword(w http.ResponseWriter, r *http.Request)
+	Impersonate(w http.ResponseWriter, r *http.Request)
+}
+
+// UserImportHandlerInterface defines the interface for bulk user import and export HTTP handlers
+type UserImportHandlerInterface interface {
+	ImportUsers(w http.ResponseWriter, r *http.Request)
+	ExportUsers(w http.ResponseWriter, r *http.Request)
+}
+
+// SCIMHandlerInterface defines the interface for SCIM provisioning and SCIM token HTTP handlers
+type SCIMHandlerInterface interface {
+	Tokens(w http.ResponseWriter, r *http.Request)
+	RevokeToken(w http.ResponseWriter, r *http.Request)
+	Users(w http.ResponseWriter, r *http.Request)
+	User(w http.ResponseWriter, r *http.Request)
+	Groups(w http.ResponseWriter, r *http.Request)
+	Group(w http.ResponseWriter, r *http.Request)
+	ServiceProviderConfig(w http.ResponseWriter, r *http.Request)
+	Schemas(w http.ResponseWriter, r *http.Request)
+	ResourceTypes(w http.ResponseWriter, r *http.Request)
+}
+
+// OAuthHandlerInterface defines the interface for OAuth client, token exchange and introspection HTTP handlers
+type OAuthHandlerInterface interface {
+	Clients(w http.ResponseWriter, r *http.Request)
+	RevokeClient(w http.ResponseWriter, r *http.Request)
+	Token(w http.ResponseWriter, r *http.Request)
+	Introspect(w http.ResponseWriter, r *http.Request)
+}
+
+// DataExportHandlerInterface defines the interface for data subject access and rectification HTTP handlers
+type DataExportHandlerInterface interface {
+	ExportOwnData(w http.ResponseWriter, r *http.Request)
+	ExportUserData(w http.ResponseWriter, r *http.Request)
+	GetExportRecords(w http.ResponseWriter, r *http.Request)
+	RectifyUser(w http.ResponseWriter, r *http.Request)
+}
+
+// ProfileHandlerInterface defines the interface for self-service profile HTTP handlers
+type ProfileHandlerInterface interface {
+	Profile(w http.ResponseWriter, r *http.Request)
+	EmailChange(w http.ResponseWriter, r *http.Request)
+	ConfirmEmailChange(w http.ResponseWriter, r *http.Request)
+	VerifyEmail(w http.ResponseWriter, r *http.Request)
+	DeleteAccount(w http.ResponseWriter, r *http.Request)
+}
+
+// MFAHandlerInterface defines the interface for the caller's MFA factor HTTP handlers
+type MFAHandlerInterface interface {
+	Factors(w http.ResponseWriter, r *http.Request)
+	ConfirmFactor(w http.ResponseWriter, r *http.Request)
+	DeleteFactor(w http.ResponseWriter, r *http.Request)
+}
+
+// SessionHandlerInterface defines the interface for the caller's session HTTP handlers
+type SessionHandlerInterface interface {
+	Sessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeTrustedDevice(w http.ResponseWriter, r *http.Request)
+}
+
+// AttributeHandlerInterface defines the interface for custom user attribute HTTP handlers
+type AttributeHandlerInterface interface {
+	Definitions(w http.ResponseWriter, r *http.Request)
+	Definition(w http.ResponseWriter, r *http.Request)
+	UserAttributes(w http.ResponseWriter, r *http.Request)
 }
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// MFAHandler handles HTTP requests for the caller's one-time passcode factors
+type MFAHandler struct {
+	service service.MFAService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewMFAHandler creates a new MFAHandler instance
+func NewMFAHandler(svc service.MFAService, cfg *config.Config, log *slog.Logger) *MFAHandler {
+	return &MFAHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Factors handles GET requests to list and POST requests to enroll the
+// caller's factors
+func (h *MFAHandler) Factors(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getFactors(w, r)
+	case http.MethodPost:
+		h.enrollFactor(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// ConfirmFactor handles POST requests carrying the code sent to a newly
+// enrolled factor
+func (h *MFAHandler) ConfirmFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.VerifyOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	factor, err := h.service.ConfirmFactor(ctx, principal, req.OTPToken, req.Code)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, factor)
+}
+
+// DeleteFactor handles DELETE requests to remove one of the caller's factors
+func (h *MFAHandler) DeleteFactor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid factor id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.DeleteFactor(ctx, principal, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MFAHandler) getFactors(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	factors, err := h.service.GetFactors(ctx, principal)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, factors)
+}
+
+func (h *MFAHandler) enrollFactor(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.EnrollFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	enrollment, err := h.service.EnrollFactor(ctx, principal, req)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, enrollment)
+}
+
+// Ensure MFAHandler implements MFAHandlerInterface
+var _ MFAHandlerInterface = (*MFAHandler)(nil)
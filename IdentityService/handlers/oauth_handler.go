@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/requestinfo"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// maxOAuthFormBytes bounds the form body of token and introspection requests
+const maxOAuthFormBytes = 64 << 10
+
+// OAuthHandler handles HTTP requests for OAuth clients, token exchange and
+// token introspection
+type OAuthHandler struct {
+	service service.OAuthService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewOAuthHandler creates a new OAuthHandler instance
+func NewOAuthHandler(svc service.OAuthService, cfg *config.Config, log *slog.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Clients handles GET requests to list and POST requests to register
+// OAuth clients of the active organization
+func (h *OAuthHandler) Clients(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getClients(w, r)
+	case http.MethodPost:
+		h.createClient(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// RevokeClient handles DELETE requests to revoke an OAuth client
+func (h *OAuthHandler) RevokeClient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid oauth client id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RevokeClient(ctx, orgID, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Token handles POST requests to the RFC 8693 token exchange grant. The
+// client authenticates with HTTP Basic or client_id and client_secret
+// form parameters.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+	if r.PostForm.Get("grant_type") != models.GrantTypeTokenExchange {
+		handleOAuthError(w, r, h.log, apperrors.NewBadRequestError("grant_type is not supported", nil).
+			WithReason(service.OAuthUnsupportedGrantType))
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+	req := models.TokenExchangeRequest{
+		SubjectToken:       r.PostForm.Get("subject_token"),
+		SubjectTokenType:   r.PostForm.Get("subject_token_type"),
+		ActorToken:         r.PostForm.Get("actor_token"),
+		ActorTokenType:     r.PostForm.Get("actor_token_type"),
+		Audience:           r.PostForm.Get("audience"),
+		Scope:              r.PostForm.Get("scope"),
+		RequestedTokenType: r.PostForm.Get("requested_token_type"),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	token, err := h.service.ExchangeToken(ctx, clientID, clientSecret, req)
+	if err != nil {
+		handleOAuthError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, token)
+}
+
+// Introspect handles POST requests to introspect an exchanged token as in
+// RFC 7662. The client authenticates as for Token.
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if !h.parseForm(w, r) {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		handleOAuthError(w, r, h.log, apperrors.NewBadRequestError("token is required", nil).
+			WithReason(service.OAuthInvalidRequest))
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	result, err := h.service.Introspect(ctx, clientID, clientSecret, token)
+	if err != nil {
+		handleOAuthError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, result)
+}
+
+func (h *OAuthHandler) getClients(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	clients, err := h.service.GetClients(ctx, orgID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, clients)
+}
+
+func (h *OAuthHandler) createClient(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+	if _, err := activeOrganization(r); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.CreateOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	client, err := h.service.CreateClient(ctx, principal, req)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, client)
+}
+
+// parseForm reads a form-encoded POST body, writing the OAuth error itself
+// when the request is not one
+func (h *OAuthHandler) parseForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		handleOAuthError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil).
+			WithReason(service.OAuthInvalidRequest))
+		return false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxOAuthFormBytes)
+	if err := r.ParseForm(); err != nil {
+		handleOAuthError(w, r, h.log, apperrors.NewBadRequestError("invalid form body", err).
+			WithReason(service.OAuthInvalidRequest))
+		return false
+	}
+	return true
+}
+
+// clientCredentials returns the client's credentials from HTTP Basic, whose
+// parts are form-encoded as RFC 6749 requires, or else from the form
+func clientCredentials(r *http.Request) (string, string) {
+	if user, password, ok := r.BasicAuth(); ok {
+		clientID, err := url.QueryUnescape(user)
+		if err != nil {
+			return "", ""
+		}
+		clientSecret, err := url.QueryUnescape(password)
+		if err != nil {
+			return "", ""
+		}
+		return clientID, clientSecret
+	}
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+}
+
+// handleOAuthError writes an error in the RFC 6749 format. The OAuth error
+// code is carried as the reason of the application error.
+func handleOAuthError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	log.ErrorContext(r.Context(), "oauth request error",
+		slog.String("error", err.Error()),
+		slog.String("path", r.URL.Path),
+		slog.String("method", r.Method),
+		slog.String("request_id", requestinfo.From(r.Context()).ID),
+	)
+
+	code := http.StatusInternalServerError
+	body := map[string]string{"error": "server_error"}
+
+	var appErr *apperrors.AppError
+	if stderrors.As(err, &appErr) && appErr.Code < http.StatusInternalServerError {
+		code = appErr.Code
+		body["error"] = appErr.Reason
+		if body["error"] == "" {
+			body["error"] = service.OAuthInvalidRequest
+		}
+		body["error_description"] = appErr.Message
+	}
+
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	writeJSONResponse(w, log, code, body)
+}
+
+// Ensure OAuthHandler implements OAuthHandlerInterface
+var _ OAuthHandlerInterface = (*OAuthHandler)(nil)
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+)
+
+// OrganizationHandler handles HTTP requests for organizations and invitations
+type OrganizationHandler struct {
+	service service.OrganizationService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewOrganizationHandler creates a new OrganizationHandler instance
+func NewOrganizationHandler(svc service.OrganizationService, cfg *config.Config, log *slog.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// GetMyOrganizations handles GET requests to list the caller's organizations
+func (h *OrganizationHandler) GetMyOrganizations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	orgs, err := h.service.GetOrganizationsForUser(ctx, principal.UserID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, orgs)
+}
+
+// CreateOrganization handles POST requests to create an organization owned by the caller
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	org, err := h.service.CreateOrganization(ctx, principal.UserID, req.Name, req.Slug)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, org)
+}
+
+// GetInvitations handles GET requests to list invitations of the active organization
+func (h *OrganizationHandler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	invitations, err := h.service.GetInvitations(ctx, orgID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, invitations)
+}
+
+// CreateInvitation handles POST requests to invite someone to the active organization
+func (h *OrganizationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	inv, err := h.service.InviteMember(ctx, orgID, principal.UserID, req.Email, req.Role)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, inv)
+}
+
+// RevokeInvitation handles POST requests to revoke a pending invitation
+func (h *OrganizationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.RevokeInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RevokeInvitation(ctx, orgID, req.ID); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitation handles POST requests from the invited user to join the organization
+func (h *OrganizationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.InvitationTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	inv, err := h.service.AcceptInvitation(ctx, principal, req.Token)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, inv)
+}
+
+// DeclineInvitation handles POST requests to decline an invitation; the token is the credential
+func (h *OrganizationHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.InvitationTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.DeclineInvitation(ctx, req.Token); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PasswordPolicy handles GET requests to read and PUT requests to replace
+// the active organization's password policy overrides
+func (h *OrganizationHandler) PasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	var policy *models.PasswordPolicy
+	switch r.Method {
+	case http.MethodGet:
+		policy, err = h.service.GetPasswordPolicy(ctx, orgID)
+	case http.MethodPut:
+		var req models.PasswordPolicy
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+			return
+		}
+		policy, err = h.service.UpdatePasswordPolicy(ctx, orgID, req)
+	default:
+		err = apperrors.NewBadRequestError("method not allowed", nil)
+	}
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, policy)
+}
+
+// Ensure OrganizationHandler implements OrganizationHandlerInterface
+var _ OrganizationHandlerInterface = (*OrganizationHandler)(nil)
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+)
+
+// currentPrincipal returns the authenticated caller of the request
+func currentPrincipal(r *http.Request) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, apperrors.NewUnauthorizedError("authentication required")
+	}
+	return principal, nil
+}
+
+// activeOrganization returns the caller's active organization ID
+func activeOrganization(r *http.Request) (int, error) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		return 0, err
+	}
+	if principal.OrganizationID == 0 {
+		return 0, apperrors.NewBadRequestError("an active organization is required", nil)
+	}
+	return principal.OrganizationID, nil
+}
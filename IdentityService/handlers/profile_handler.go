@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+)
+
+// ProfileHandler handles HTTP requests for the caller's own profile
+type ProfileHandler struct {
+	service service.ProfileService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewProfileHandler creates a new ProfileHandler instance
+func NewProfileHandler(svc service.ProfileService, cfg *config.Config, log *slog.Logger) *ProfileHandler {
+	return &ProfileHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Profile handles GET requests to view and PATCH requests to update the
+// caller's profile
+func (h *ProfileHandler) Profile(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getProfile(w, r)
+	case http.MethodPatch:
+		h.updateProfile(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// EmailChange handles POST requests to start and DELETE requests to cancel
+// a change of the caller's email address
+func (h *ProfileHandler) EmailChange(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.requestEmailChange(w, r)
+	case http.MethodDelete:
+		h.cancelEmailChange(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// ConfirmEmailChange handles POST requests carrying the token sent to the
+// new email address
+func (h *ProfileHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.ConfirmEmailChange(ctx, req.Token); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail handles POST requests carrying the token of an email
+// verification link
+func (h *ProfileHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.VerifyEmail(ctx, req.Token); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProfileHandler) getProfile(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	profile, err := h.service.GetProfile(ctx, principal.UserID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, profile)
+}
+
+func (h *ProfileHandler) updateProfile(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	profile, err := h.service.UpdateProfile(ctx, principal.UserID, req)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, profile)
+}
+
+func (h *ProfileHandler) requestEmailChange(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	change, err := h.service.RequestEmailChange(ctx, principal, req.NewEmail, req.CurrentPassword)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusAccepted, change)
+}
+
+func (h *ProfileHandler) cancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.CancelEmailChange(ctx, principal.UserID); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount handles DELETE requests to delete the caller's account
+func (h *ProfileHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.DeleteAccount(ctx, principal); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Ensure ProfileHandler implements ProfileHandlerInterface
+var _ ProfileHandlerInterface = (*ProfileHandler)(nil)
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	apperrors "identity-service/errors"
+	"identity-service/requestinfo"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+)
+
+// handleError maps an error to an HTTP response shared by all handlers
+func handleError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	var appErr *apperrors.AppError
+
+	// Log error with context
+	if err != nil {
+		log.ErrorContext(r.Context(), "request error",
+			slog.String("error", err.Error()),
+			slog.String("path", r.URL.Path),
+			slog.String("method", r.Method),
+			slog.String("request_id", requestinfo.From(r.Context()).ID),
+		)
+	}
+
+	// Check if it's an AppError
+	if stderrors.As(err, &appErr) {
+		body := map[string]string{
+			"error": appErr.Message,
+		}
+		if appErr.Reason != "" {
+			body["reason"] = appErr.Reason
+		}
+		writeJSONResponse(w, log, appErr.Code, body)
+		return
+	}
+
+	// Check if it's a validation error
+	var validationErrors service.ValidationErrors
+	if stderrors.As(err, &validationErrors) {
+		errors := make([]map[string]string, len(validationErrors))
+		for i, verr := range validationErrors {
+			errors[i] = map[string]string{
+				verr.Field: verr.Message,
+			}
+		}
+		writeJSONResponse(w, log, http.StatusBadRequest, map[string]interface{}{
+			"errors": errors,
+		})
+		return
+	}
+
+	// Fallback for unknown errors
+	writeJSONResponse(w, log, http.StatusInternalServerError, map[string]string{
+		"error": "internal server error",
+	})
+}
+
+// writeJSONResponse writes a JSON response with the given status code
+func writeJSONResponse(w http.ResponseWriter, log *slog.Logger, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.ErrorContext(context.Background(), "failed to encode response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
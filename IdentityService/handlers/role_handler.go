@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+)
+
+// RoleHandler handles HTTP requests for role management
+type RoleHandler struct {
+	service service.AuthorizationService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewRoleHandler creates a new RoleHandler instance
+func NewRoleHandler(svc service.AuthorizationService, cfg *config.Config, log *slog.Logger) *RoleHandler {
+	return &RoleHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// GetAllRoles handles GET requests to list roles and their permissions
+func (h *RoleHandler) GetAllRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	roles, err := h.service.GetAllRoles(ctx)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, roles)
+}
+
+// AssignRole handles POST requests to grant a role to a user
+func (h *RoleHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, h.service.AssignRole)
+}
+
+// RemoveRole handles POST requests to revoke a role from a user
+func (h *RoleHandler) RemoveRole(w http.ResponseWriter, r *http.Request) {
+	h.changeRole(w, r, h.service.RemoveRole)
+}
+
+func (h *RoleHandler) changeRole(w http.ResponseWriter, r *http.Request, change func(ctx context.Context, orgID, userID int, role string) error) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	// Roles are granted within the caller's active organization
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+	if req.UserID <= 0 || req.Role == "" {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("user_id and role are required", nil))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := change(ctx, orgID, req.UserID, req.Role); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	roles, err := h.service.GetUserRoles(ctx, orgID, req.UserID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, map[string]interface{}{
+		"user_id": req.UserID,
+		"roles":   roles,
+	})
+}
+
+// Ensure RoleHandler implements RoleHandlerInterface
+var _ RoleHandlerInterface = (*RoleHandler)(nil)
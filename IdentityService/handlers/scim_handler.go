@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/requestinfo"
+	"identity-service/scim"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// scimPath is where the SCIM endpoints are mounted
+const scimPath = "/scim/v2"
+
+// SCIMHandler handles SCIM 2.0 provisioning requests, which an identity
+// provider sends with one of the organization's SCIM tokens, and the
+// management of those tokens
+type SCIMHandler struct {
+	service service.SCIMService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewSCIMHandler creates a new SCIMHandler instance
+func NewSCIMHandler(svc service.SCIMService, cfg *config.Config, log *slog.Logger) *SCIMHandler {
+	return &SCIMHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Tokens handles GET requests to list and POST requests to create the
+// active organization's SCIM tokens
+func (h *SCIMHandler) Tokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getTokens(w, r)
+	case http.MethodPost:
+		h.createToken(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// RevokeToken handles DELETE requests to revoke a SCIM token
+func (h *SCIMHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid scim token id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RevokeToken(ctx, orgID, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Users handles GET requests to query and POST requests to create users.
+// Query supports filter, startIndex, count, attributes and excludedAttributes.
+func (h *SCIMHandler) Users(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		query, err := parseSCIMQuery(r)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		users, total, err := h.service.ListUsers(ctx, orgID, query)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		resources := make([]interface{}, len(users))
+		for i, user := range users {
+			resources[i] = h.locateUser(r, user)
+		}
+		h.writeList(w, r, query, total, resources)
+
+	case http.MethodPost:
+		var user scim.User
+		if !h.decode(w, r, &user) {
+			return
+		}
+		created, err := h.service.CreateUser(ctx, orgID, &user)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		w.Header().Set("Location", h.locateUser(r, created).Meta.Location)
+		h.writeResource(w, r, http.StatusCreated, created)
+
+	default:
+		handleSCIMError(w, r, h.log, scim.NewError(http.StatusMethodNotAllowed, "", "method not allowed"))
+	}
+}
+
+// User handles GET, PUT, PATCH and DELETE requests for a single user
+func (h *SCIMHandler) User(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	id, convErr := strconv.Atoi(r.PathValue("id"))
+	if convErr != nil || id <= 0 {
+		handleSCIMError(w, r, h.log, apperrors.NewNotFoundError("user not found"))
+		return
+	}
+
+	var user *scim.User
+	switch r.Method {
+	case http.MethodGet:
+		user, err = h.service.GetUser(ctx, orgID, id)
+	case http.MethodPut:
+		var replacement scim.User
+		if !h.decode(w, r, &replacement) {
+			return
+		}
+		user, err = h.service.ReplaceUser(ctx, orgID, id, &replacement)
+	case http.MethodPatch:
+		var patch scim.PatchRequest
+		if !h.decode(w, r, &patch) {
+			return
+		}
+		user, err = h.service.PatchUser(ctx, orgID, id, patch.Operations)
+	case http.MethodDelete:
+		if err := h.service.DeleteUser(ctx, orgID, id); err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		err = scim.NewError(http.StatusMethodNotAllowed, "", "method not allowed")
+	}
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+
+	h.writeResource(w, r, http.StatusOK, h.locateUser(r, user))
+}
+
+// Groups handles GET requests to query and POST requests to create groups
+func (h *SCIMHandler) Groups(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		query, err := parseSCIMQuery(r)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		groups, total, err := h.service.ListGroups(ctx, orgID, query)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		resources := make([]interface{}, len(groups))
+		for i, group := range groups {
+			resources[i] = h.locateGroup(r, group)
+		}
+		h.writeList(w, r, query, total, resources)
+
+	case http.MethodPost:
+		var group scim.Group
+		if !h.decode(w, r, &group) {
+			return
+		}
+		created, err := h.service.CreateGroup(ctx, orgID, &group)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		w.Header().Set("Location", h.locateGroup(r, created).Meta.Location)
+		h.writeResource(w, r, http.StatusCreated, created)
+
+	default:
+		handleSCIMError(w, r, h.log, scim.NewError(http.StatusMethodNotAllowed, "", "method not allowed"))
+	}
+}
+
+// Group handles GET, PUT, PATCH and DELETE requests for a single group
+func (h *SCIMHandler) Group(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	id, convErr := strconv.Atoi(r.PathValue("id"))
+	if convErr != nil || id <= 0 {
+		handleSCIMError(w, r, h.log, apperrors.NewNotFoundError("group not found"))
+		return
+	}
+
+	var group *scim.Group
+	switch r.Method {
+	case http.MethodGet:
+		group, err = h.service.GetGroup(ctx, orgID, id)
+	case http.MethodPut:
+		var replacement scim.Group
+		if !h.decode(w, r, &replacement) {
+			return
+		}
+		group, err = h.service.ReplaceGroup(ctx, orgID, id, &replacement)
+	case http.MethodPatch:
+		var patch scim.PatchRequest
+		if !h.decode(w, r, &patch) {
+			return
+		}
+		group, err = h.service.PatchGroup(ctx, orgID, id, patch.Operations)
+	case http.MethodDelete:
+		if err := h.service.DeleteGroup(ctx, orgID, id); err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		err = scim.NewError(http.StatusMethodNotAllowed, "", "method not allowed")
+	}
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+
+	h.writeResource(w, r, http.StatusOK, h.locateGroup(r, group))
+}
+
+// ServiceProviderConfig handles GET requests for the service provider configuration
+func (h *SCIMHandler) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleSCIMError(w, r, h.log, scim.NewError(http.StatusMethodNotAllowed, "", "method not allowed"))
+		return
+	}
+	writeSCIMResponse(w, h.log, http.StatusOK, scim.ServiceProviderConfig(h.baseURL(r)))
+}
+
+// Schemas handles GET requests for all schemas or, with an id, one of them
+func (h *SCIMHandler) Schemas(w http.ResponseWriter, r *http.Request) {
+	h.discovery(w, r, scim.Schemas(h.baseURL(r)))
+}
+
+// ResourceTypes handles GET requests for all resource types or, with an id, one of them
+func (h *SCIMHandler) ResourceTypes(w http.ResponseWriter, r *http.Request) {
+	h.discovery(w, r, scim.ResourceTypes(h.baseURL(r)))
+}
+
+func (h *SCIMHandler) discovery(w http.ResponseWriter, r *http.Request, documents []map[string]interface{}) {
+	if r.Method != http.MethodGet {
+		handleSCIMError(w, r, h.log, scim.NewError(http.StatusMethodNotAllowed, "", "method not allowed"))
+		return
+	}
+
+	if id := r.PathValue("id"); id != "" {
+		for _, document := range documents {
+			if document["id"] == id {
+				writeSCIMResponse(w, h.log, http.StatusOK, document)
+				return
+			}
+		}
+		handleSCIMError(w, r, h.log, scim.NewError(http.StatusNotFound, "", "not found"))
+		return
+	}
+
+	writeSCIMResponse(w, h.log, http.StatusOK, scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: len(documents),
+		StartIndex:   1,
+		ItemsPerPage: len(documents),
+		Resources:    documents,
+	})
+}
+
+func (h *SCIMHandler) getTokens(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	tokens, err := h.service.GetTokens(ctx, orgID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, tokens)
+}
+
+func (h *SCIMHandler) createToken(w http.ResponseWriter, r *http.Request) {
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.CreateSCIMTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	token, err := h.service.CreateToken(ctx, orgID, principal.UserID, req.Name)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, token)
+}
+
+// decode reads a JSON request body, writing the SCIM error itself when the
+// body is invalid
+func (h *SCIMHandler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		handleSCIMError(w, r, h.log, scim.NewError(http.StatusBadRequest, scim.ErrInvalidSyntax, "invalid request body: "+err.Error()))
+		return false
+	}
+	return true
+}
+
+// writeResource writes a resource after applying the attributes and
+// excludedAttributes parameters
+func (h *SCIMHandler) writeResource(w http.ResponseWriter, r *http.Request, statusCode int, resource interface{}) {
+	m, err := h.project(r, resource)
+	if err != nil {
+		handleSCIMError(w, r, h.log, err)
+		return
+	}
+	writeSCIMResponse(w, h.log, statusCode, m)
+}
+
+func (h *SCIMHandler) writeList(w http.ResponseWriter, r *http.Request, query scim.Query, total int, resources []interface{}) {
+	list := scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   query.StartIndex,
+		ItemsPerPage: len(resources),
+		Resources:    make([]map[string]interface{}, len(resources)),
+	}
+	for i, resource := range resources {
+		m, err := h.project(r, resource)
+		if err != nil {
+			handleSCIMError(w, r, h.log, err)
+			return
+		}
+		list.Resources[i] = m
+	}
+	writeSCIMResponse(w, h.log, http.StatusOK, list)
+}
+
+func (h *SCIMHandler) project(r *http.Request, resource interface{}) (map[string]interface{}, error) {
+	m, err := scim.ToMap(resource)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	return scim.Project(m, scim.SplitAttributes(query.Get("attributes")), scim.SplitAttributes(query.Get("excludedAttributes"))), nil
+}
+
+func (h *SCIMHandler) locateUser(r *http.Request, user *scim.User) *scim.User {
+	user.Meta.Location = h.baseURL(r) + "/Users/" + user.ID
+	return user
+}
+
+func (h *SCIMHandler) locateGroup(r *http.Request, group *scim.Group) *scim.Group {
+	base := h.baseURL(r)
+	group.Meta.Location = base + "/Groups/" + group.ID
+	for i := range group.Members {
+		group.Members[i].Ref = base + "/Users/" + group.Members[i].Value
+	}
+	return group
+}
+
+// baseURL returns the public address of the SCIM endpoints
+func (h *SCIMHandler) baseURL(r *http.Request) string {
+	if h.config.SCIM.BaseURL != "" {
+		return h.config.SCIM.BaseURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + scimPath
+}
+
+// parseSCIMQuery reads the list parameters of a query request
+func parseSCIMQuery(r *http.Request) (scim.Query, error) {
+	query := r.URL.Query()
+	return scim.ParseQuery(query.Get("filter"), query.Get("startIndex"), query.Get("count"))
+}
+
+// handleSCIMError maps an error to a SCIM error response
+func handleSCIMError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error) {
+	log.ErrorContext(r.Context(), "scim request error",
+		slog.String("error", err.Error()),
+		slog.String("path", r.URL.Path),
+		slog.String("method", r.Method),
+		slog.String("request_id", requestinfo.From(r.Context()).ID),
+	)
+
+	var scimErr *scim.Error
+	var appErr *apperrors.AppError
+	var validationErrors service.ValidationErrors
+	switch {
+	case stderrors.As(err, &scimErr):
+	case stderrors.As(err, &appErr):
+		scimType := appErr.Reason
+		switch {
+		case scimType != "":
+		case appErr.Code == http.StatusConflict:
+			scimType = scim.ErrUniqueness
+		case appErr.Code == http.StatusBadRequest:
+			scimType = scim.ErrInvalidValue
+		}
+		detail := appErr.Message
+		if appErr.Code >= http.StatusInternalServerError {
+			detail = "internal server error"
+		} else if stderrors.As(appErr.Err, &validationErrors) {
+			detail = validationErrors.Error()
+		}
+		scimErr = scim.NewError(appErr.Code, scimType, detail)
+	case stderrors.As(err, &validationErrors):
+		scimErr = scim.NewError(http.StatusBadRequest, scim.ErrInvalidValue, validationErrors.Error())
+	default:
+		scimErr = scim.NewError(http.StatusInternalServerError, "", "internal server error")
+	}
+
+	writeSCIMResponse(w, log, scimErr.StatusCode(), scimErr)
+}
+
+// writeSCIMResponse writes a response with the SCIM media type
+func writeSCIMResponse(w http.ResponseWriter, log *slog.Logger, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.ErrorContext(context.Background(), "failed to encode response",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Ensure SCIMHandler implements SCIMHandlerInterface
+var _ SCIMHandlerInterface = (*SCIMHandler)(nil)
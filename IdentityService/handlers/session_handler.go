@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// SessionHandler handles HTTP requests for the caller's sessions and
+// trusted devices
+type SessionHandler struct {
+	service service.SessionService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewSessionHandler creates a new SessionHandler instance
+func NewSessionHandler(svc service.SessionService, cfg *config.Config, log *slog.Logger) *SessionHandler {
+	return &SessionHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Sessions handles GET requests to list the caller's sessions and trusted devices
+func (h *SessionHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	sessions, err := h.service.GetSessions(ctx, principal)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, sessions)
+}
+
+// RevokeSession handles DELETE requests to sign out one of the caller's sessions
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid session id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RevokeSession(ctx, principal, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeTrustedDevice handles DELETE requests to stop trusting one of the
+// caller's devices
+func (h *SessionHandler) RevokeTrustedDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	principal, err := currentPrincipal(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid trusted device id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RevokeTrustedDevice(ctx, principal, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Ensure SessionHandler implements SessionHandlerInterface
+var _ SessionHandlerInterface = (*SessionHandler)(nil)
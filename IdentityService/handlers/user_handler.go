@@ -3,13 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	stderrors "errors"
 	"identity-service/config"
 	"identity-service/models"
 	"identity-service/service"
 	apperrors "identity-service/errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // UserHandler handles HTTP requests for user operations
@@ -36,12 +37,32 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users are always listed within the caller's organization
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	// Custom attributes are filtered with attr.<name>=<value>
+	var filter models.UserFilter
+	for key, values := range r.URL.Query() {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok {
+			continue
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = make(map[string]string)
+		}
+		filter.Attributes[name] = values[0]
+	}
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
 	defer cancel()
 
 	// Call service layer
-	users, err := h.service.GetAllUsers(ctx)
+	users, err := h.service.GetAllUsers(ctx, orgID, filter)
 	if err != nil {
 		h.handleError(w, r, err)
 		return
@@ -59,6 +80,12 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
 	// Parse request body
 	var req models.CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -75,7 +102,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	// Call service layer
-	user, err := h.service.CreateUser(ctx, req.Name, req.Email)
+	user, err := h.service.CreateUser(ctx, orgID, req.Name, req.Email, req.Password, req.PasswordHash)
 	if err != nil {
 		h.handleError(w, r, err)
 		return
@@ -85,58 +112,131 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(w, http.StatusCreated, user)
 }
 
-// handleError handles errors and sends appropriate HTTP responses
-func (h *UserHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
-	var appErr *apperrors.AppError
+// UnlockUser handles POST requests to lift a sign-in lockout
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	// Validate method
+	if r.Method != http.MethodPost {
+		h.handleError(w, r, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
 
-	// Log error with context
+	orgID, err := activeOrganization(r)
 	if err != nil {
-		h.log.ErrorContext(r.Context(), "request error",
-			slog.String("error", err.Error()),
-			slog.String("path", r.URL.Path),
-			slog.String("method", r.Method),
-		)
+		h.handleError(w, r, err)
+		return
 	}
 
-	// Check if it's an AppError
-	if stderrors.As(err, &appErr) {
-		h.writeJSONResponse(w, appErr.Code, map[string]string{
-			"error": appErr.Message,
-		})
+	// Parse request body
+	var req models.UnlockUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.handleError(w, r, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+	if req.UserID <= 0 {
+		h.handleError(w, r, apperrors.NewBadRequestError("user_id is required", nil))
 		return
 	}
 
-	// Check if it's a validation error
-	var validationErrors service.ValidationErrors
-	if stderrors.As(err, &validationErrors) {
-		errors := make([]map[string]string, len(validationErrors))
-		for i, verr := range validationErrors {
-			errors[i] = map[string]string{
-				verr.Field: verr.Message,
-			}
-		}
-		h.writeJSONResponse(w, http.StatusBadRequest, map[string]interface{}{
-			"errors": errors,
-		})
+	// Create context with timeout
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.UnlockUser(ctx, orgID, req.UserID); err != nil {
+		h.handleError(w, r, err)
 		return
 	}
 
-	// Fallback for unknown errors
-	h.writeJSONResponse(w, http.StatusInternalServerError, map[string]string{
-		"error": "internal server error",
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableUser handles POST requests to block a user's account
+func (h *UserHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	if h.userAction(w, r, h.service.DisableUser) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// EnableUser handles POST requests to unblock a disabled account
+func (h *UserHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	if h.userAction(w, r, h.service.EnableUser) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SignOutUser handles POST requests to end all of a user's sessions
+func (h *UserHandler) SignOutUser(w http.ResponseWriter, r *http.Request) {
+	var resp models.SignOutUserResponse
+	ok := h.userAction(w, r, func(ctx context.Context, orgID, id int, reason string) error {
+		var err error
+		resp.SessionsRevoked, err = h.service.SignOutUser(ctx, orgID, id, reason)
+		return err
 	})
+	if ok {
+		h.writeJSONResponse(w, http.StatusOK, resp)
+	}
 }
 
-// writeJSONResponse writes a JSON response with the given status code
-func (h *UserHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// RequirePasswordChange handles POST requests to make a user change their
+// password at the next sign-in
+func (h *UserHandler) RequirePasswordChange(w http.ResponseWriter, r *http.Request) {
+	if h.userAction(w, r, h.service.RequirePasswordChange) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.log.ErrorContext(context.Background(), "failed to encode response",
-			slog.String("error", err.Error()),
-		)
+// ResendVerification handles POST requests to send a user a new email
+// verification link
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if h.userAction(w, r, h.service.ResendVerification) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// userAction runs an administrative action on the user in the path with
+// the reason from the request body. It reports whether the action
+// succeeded; on failure the error response has been written.
+func (h *UserHandler) userAction(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, orgID, id int, reason string) error) bool {
+	if r.Method != http.MethodPost {
+		h.handleError(w, r, apperrors.NewBadRequestError("method not allowed", nil))
+		return false
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		h.handleError(w, r, err)
+		return false
 	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		h.handleError(w, r, apperrors.NewBadRequestError("invalid user id", err))
+		return false
+	}
+
+	var req models.UserActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.handleError(w, r, apperrors.NewBadRequestError("invalid request body", err))
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := action(ctx, orgID, id, req.Reason); err != nil {
+		h.handleError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// handleError handles errors and sends appropriate HTTP responses
+func (h *UserHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	handleError(w, r, h.log, err)
+}
+
+// writeJSONResponse writes a JSON response with the given status code
+func (h *UserHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	writeJSONResponse(w, h.log, statusCode, data)
 }
 
 // Ensure UserHandler implements UserHandlerInterface
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserImportHandler handles HTTP requests for bulk user import and export
+type UserImportHandler struct {
+	service service.UserImportService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewUserImportHandler creates a new UserImportHandler instance
+func NewUserImportHandler(svc service.UserImportService, cfg *config.Config, log *slog.Logger) *UserImportHandler {
+	return &UserImportHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// ImportUsers handles POST requests whose body is a CSV or JSON Lines file
+// of users. The format comes from the format query parameter or else the
+// Content-Type; dry_run=true validates without writing. The response is
+// JSON Lines: a {"progress": ...} line after every batch, then a single
+// {"result": ...} line, or {"error": ...} if the import stops part way.
+func (h *UserImportHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	// Large files outlive the server's usual read and write timeouts
+	timeout := h.config.UserImport.Timeout
+	extendDeadlines(w, timeout)
+	r.Body = http.MaxBytesReader(w, r.Body, h.config.UserImport.MaxBytes)
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	stream := &importStream{w: w, log: h.log}
+	result, err := h.service.Import(ctx, orgID, r.Body, opts, func(progress models.UserImportProgress) {
+		stream.send(r.Context(), map[string]interface{}{"progress": progress})
+	})
+
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		err = apperrors.NewBadRequestError(fmt.Sprintf("import file is larger than %d bytes", tooLarge.Limit), err)
+	}
+	if err != nil {
+		if !stream.started {
+			handleError(w, r, h.log, err)
+			return
+		}
+		// The status line is already sent, so the error goes in the stream
+		h.log.ErrorContext(r.Context(), "user import failed",
+			slog.String("error", err.Error()),
+			slog.Int("organization_id", orgID),
+		)
+		message := "internal server error"
+		var appErr *apperrors.AppError
+		if stderrors.As(err, &appErr) && appErr.Code < http.StatusInternalServerError {
+			message = appErr.Message
+		}
+		stream.send(r.Context(), map[string]interface{}{"error": message})
+		return
+	}
+
+	stream.send(r.Context(), map[string]interface{}{"result": result})
+}
+
+// ExportUsers handles GET requests to download every member of the active
+// organization. Supported query parameters: format (csv or jsonl, default
+// csv) and fields, a comma-separated list.
+func (h *UserImportHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	query := r.URL.Query()
+	opts := models.UserExportOptions{Format: query.Get("format")}
+	if opts.Format == "" {
+		opts.Format = models.UserFileCSV
+	}
+	if fields := query.Get("fields"); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			opts.Fields = append(opts.Fields, strings.TrimSpace(field))
+		}
+	}
+
+	timeout := h.config.UserImport.Timeout
+	extendDeadlines(w, timeout)
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	contentType := "text/csv"
+	if opts.Format == models.UserFileJSONL {
+		contentType = "application/x-ndjson"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users.%s"`, opts.Format))
+
+	// Options are checked before the first byte is written, so a rejected
+	// export still gets an ordinary error response
+	count, err := h.service.Export(ctx, orgID, w, opts)
+	if err != nil {
+		if count == 0 {
+			w.Header().Del("Content-Disposition")
+			handleError(w, r, h.log, err)
+			return
+		}
+		h.log.ErrorContext(r.Context(), "user export failed",
+			slog.String("error", err.Error()),
+			slog.Int("organization_id", orgID),
+			slog.Int("exported", count),
+		)
+	}
+}
+
+// parseImportOptions reads the import format and dry-run flag
+func parseImportOptions(r *http.Request) (models.UserImportOptions, error) {
+	query := r.URL.Query()
+	opts := models.UserImportOptions{Format: query.Get("format")}
+
+	if opts.Format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			opts.Format = models.UserFileCSV
+		case "application/x-ndjson", "application/jsonl", "application/json-lines":
+			opts.Format = models.UserFileJSONL
+		default:
+			return opts, apperrors.NewBadRequestError("set format to csv or jsonl, or send a text/csv or application/x-ndjson body", nil)
+		}
+	}
+
+	if value := query.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, apperrors.NewBadRequestError("invalid dry_run", err)
+		}
+		opts.DryRun = dryRun
+	}
+	return opts, nil
+}
+
+// extendDeadlines lifts the server's read and write timeouts for a
+// request expected to run for up to timeout
+func extendDeadlines(w http.ResponseWriter, timeout time.Duration) {
+	controller := http.NewResponseController(w)
+	deadline := time.Now().Add(timeout)
+	_ = controller.SetReadDeadline(deadline)
+	_ = controller.SetWriteDeadline(deadline)
+}
+
+// importStream writes JSON Lines messages, flushing each one
+type importStream struct {
+	w       http.ResponseWriter
+	log     *slog.Logger
+	started bool
+}
+
+func (s *importStream) send(ctx context.Context, message interface{}) {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	if err := json.NewEncoder(s.w).Encode(message); err != nil {
+		s.log.ErrorContext(ctx, "failed to write import progress",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	_ = http.NewResponseController(s.w).Flush()
+}
+
+// Ensure UserImportHandler implements UserImportHandlerInterface
+var _ UserImportHandlerInterface = (*UserImportHandler)(nil)
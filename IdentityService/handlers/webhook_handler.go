@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/service"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// WebhookHandler handles HTTP requests for the active organization's webhooks
+type WebhookHandler struct {
+	service service.WebhookService
+	config  *config.Config
+	log     *slog.Logger
+}
+
+// NewWebhookHandler creates a new WebhookHandler instance
+func NewWebhookHandler(svc service.WebhookService, cfg *config.Config, log *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: svc,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+// Endpoints handles GET requests to list and POST requests to register webhook endpoints
+func (h *WebhookHandler) Endpoints(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.getEndpoints(w, r)
+	case http.MethodPost:
+		h.createEndpoint(w, r)
+	default:
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+	}
+}
+
+// DeleteEndpoint handles DELETE requests to remove a webhook endpoint
+func (h *WebhookHandler) DeleteEndpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid webhook endpoint id", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.DeleteEndpoint(ctx, orgID, id); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries handles GET requests to list recent deliveries; ?status=dead is the dead-letter view
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	deliveries, err := h.service.GetDeliveries(ctx, orgID, r.URL.Query().Get("status"))
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, deliveries)
+}
+
+// RetryDelivery handles POST requests to requeue a dead delivery
+func (h *WebhookHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("method not allowed", nil))
+		return
+	}
+
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.RetryWebhookDeliveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+	if req.ID <= 0 {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("id is required", nil))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	if err := h.service.RetryDelivery(ctx, orgID, req.ID); err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) getEndpoints(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	endpoints, err := h.service.GetEndpoints(ctx, orgID)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusOK, endpoints)
+}
+
+func (h *WebhookHandler) createEndpoint(w http.ResponseWriter, r *http.Request) {
+	orgID, err := activeOrganization(r)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	var req models.CreateWebhookEndpointRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, h.log, apperrors.NewBadRequestError("invalid request body", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.config.Timeouts.Handler)
+	defer cancel()
+
+	endpoint, err := h.service.CreateEndpoint(ctx, orgID, req.URL, req.EventTypes)
+	if err != nil {
+		handleError(w, r, h.log, err)
+		return
+	}
+
+	writeJSONResponse(w, h.log, http.StatusCreated, endpoint)
+}
+
+// Ensure WebhookHandler implements WebhookHandlerInterface
+var _ WebhookHandlerInterface = (*WebhookHandler)(nil)
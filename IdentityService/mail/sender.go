@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"strings"
+
+	"identity-service/config"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers email messages
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender creates the Sender selected by the mail configuration
+func NewSender(cfg *config.MailConfig, log *slog.Logger) Sender {
+	if cfg.Driver == "smtp" {
+		return &smtpSender{config: cfg}
+	}
+	return &logSender{from: cfg.From, log: log}
+}
+
+// logSender writes messages to the log instead of delivering them.
+// It is intended for local development.
+type logSender struct {
+	from string
+	log  *slog.Logger
+}
+
+func (s *logSender) Send(ctx context.Context, msg Message) error {
+	s.log.InfoContext(ctx, "email not delivered (log driver)",
+		slog.String("from", s.from),
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
+
+// smtpSender delivers messages through an SMTP relay
+type smtpSender struct {
+	config *config.MailConfig
+}
+
+func (s *smtpSender) Send(ctx context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid header value")
+	}
+
+	var auth smtp.Auth
+	if s.config.SMTPUser != "" {
+		auth = smtp.PlainAuth("", s.config.SMTPUser, s.config.SMTPPassword, s.config.SMTPHost)
+	}
+
+	body := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.config.From, msg.To, msg.Subject, msg.Body,
+	)
+
+	addr := s.config.SMTPHost + ":" + s.config.SMTPPort
+	if err := smtp.SendMail(addr, auth, s.config.From, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"identity-service/auth"
 	"identity-service/config"
 	"identity-service/database"
+	"identity-service/geoip"
 	"identity-service/handlers"
+	"identity-service/mail"
 	"identity-service/middleware"
+	"identity-service/ratelimit"
 	"identity-service/repository"
 	"identity-service/service"
+	"identity-service/sms"
 	"identity-service/validation"
 	"log/slog"
 	"net/http"
@@ -17,12 +23,22 @@ import (
 )
 
 func main() {
-	// Initialize structured logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// Initialize structured logger. Subcommands may write their results to
+	// stdout, so they log to stderr.
+	logOutput := os.Stdout
+	if len(os.Args) > 1 {
+		logOutput = os.Stderr
+	}
+	logger := slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
 	slog.SetDefault(logger)
 
+	if len(os.Args) > 1 && isHelpCommand(os.Args[1]) {
+		fmt.Print(commandUsage)
+		return
+	}
+
 	// Load configuration
 	cfg := config.LoadConfig()
 
@@ -55,11 +71,112 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Row-level security is silently skipped for privileged roles
+	enforced, err := db.RowSecurityEnforced(schemaCtx)
+	if err != nil {
+		logger.Error("failed to check row-level security",
+			slog.String("error", err.Error()),
+		)
+		os.Exit(1)
+	}
+	if !enforced {
+		logger.Warn("database role bypasses row-level security; tenant isolation relies on query filters only",
+			slog.String("database_user", cfg.Database.User),
+		)
+	}
+
 	// Initialize dependencies
-	userRepo := repository.NewUserRepository(db.DB)
+	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db.DB)
+	orgRepo := repository.NewOrganizationRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	lockoutRepo := repository.NewLockoutRepository(db)
+	credentialRepo := repository.NewCredentialRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	scimRepo := repository.NewSCIMRepository(db)
+	groupRepo := repository.NewGroupRepository(db)
+	dataExportRepo := repository.NewDataExportRepository(db)
+	profileRepo := repository.NewProfileRepository(db)
+	attributeRepo := repository.NewAttributeRepository(db)
+	magicLinkRepo := repository.NewMagicLinkRepository(db)
+	// Without a GeoIP database, sign-in risk ignores networks and travel
+	var geoDB *geoip.Database
+	if cfg.Risk.GeoIPPath != "" {
+		if geoDB, err = geoip.Open(cfg.Risk.GeoIPPath); err != nil {
+			logger.Error("failed to load geoip database",
+				slog.String("error", err.Error()),
+			)
+			os.Exit(1)
+		}
+	}
+
+	// A generated key forgets every trusted device when the service restarts
+	deviceKey := cfg.Sessions.TrustedDeviceKey
+	if deviceKey == "" {
+		if deviceKey, err = auth.GenerateToken(32); err != nil {
+			logger.Error("failed to generate trusted device key",
+				slog.String("error", err.Error()),
+			)
+			os.Exit(1)
+		}
+		logger.Warn("SESSION_TRUSTED_DEVICE_KEY is not set; trusted devices will not survive a restart")
+	}
+
+	oauthRepo := repository.NewOAuthRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	riskRepo := repository.NewRiskRepository(db)
+	trustedDeviceRepo := repository.NewTrustedDeviceRepository(db)
 	validator := validation.NewValidator(&cfg.Validation)
-	userService := service.NewUserService(userRepo, validator)
+	mailer := mail.NewSender(&cfg.Mail, logger)
+	smsSender := sms.NewSender(&cfg.SMS, logger)
+	auditService := service.NewAuditService(auditRepo, logger)
+	lockoutService := service.NewLockoutService(lockoutRepo, mailer, auditService, &cfg.Lockout, logger)
+	hasher := auth.NewPasswordHasher(auth.Argon2Params{
+		Memory:  uint32(cfg.Password.HashMemory),
+		Time:    uint32(cfg.Password.HashIterations),
+		Threads: uint8(cfg.Password.HashParallelism),
+	})
+	passwordService := service.NewPasswordService(credentialRepo, orgRepo, sessionRepo, hasher, lockoutService, validator, auditService, &cfg.Password)
+	otpService := service.NewOTPService(mfaRepo, credentialRepo, mailer, smsSender, auditService, &cfg.OTP, logger)
+	riskService := service.NewRiskService(riskRepo, geoDB, mailer, auditService, &cfg.Risk, logger)
+	authService := service.NewAuthService(credentialRepo, sessionRepo, roleRepo, userRepo, magicLinkRepo, mfaRepo, trustedDeviceRepo, passwordService, otpService, riskService, lockoutService, hasher, auth.NewDeviceTokenSigner([]byte(deviceKey)), mailer, auditService, &cfg.Sessions, logger)
+	profileService := service.NewProfileService(profileRepo, userRepo, credentialRepo, passwordService, mailer, validator, auditService, &cfg.Profile, logger)
+	userService := service.NewUserService(userRepo, roleRepo, attributeRepo, sessionRepo, profileService, lockoutService, passwordService, validator, auditService)
+	authzService := service.NewAuthorizationService(roleRepo, userRepo, auditService)
+	orgService := service.NewOrganizationService(orgRepo, invitationRepo, roleRepo, mailer, validator, auditService, &cfg.Organization)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, roleRepo, authzService, lockoutService, validator, auditService, &cfg.APIKeys)
+	webhookService := service.NewWebhookService(webhookRepo, auditService, &cfg.Webhooks)
+	userImportService := service.NewUserImportService(userRepo, hasher, validator, auditService, &cfg.UserImport)
+	scimService := service.NewSCIMService(userService, passwordService, scimRepo, groupRepo, lockoutService, validator, auditService)
+	dataExportService := service.NewDataExportService(dataExportRepo, userService, auditService)
+	attributeService := service.NewAttributeService(attributeRepo, validator, auditService)
+	mfaService := service.NewMFAService(mfaRepo, otpService, validator, auditService)
+	sessionService := service.NewSessionService(sessionRepo, trustedDeviceRepo, auditService)
+	oauthService := service.NewOAuthService(oauthRepo, sessionRepo, orgRepo, roleRepo, authzService, attributeService, lockoutService, validator, auditService, &cfg.OAuth)
 	userHandler := handlers.NewUserHandler(userService, cfg, logger)
+	roleHandler := handlers.NewRoleHandler(authzService, cfg, logger)
+	orgHandler := handlers.NewOrganizationHandler(orgService, cfg, logger)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, cfg, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, cfg, logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, cfg, logger)
+	authHandler := handlers.NewAuthHandler(authService, cfg, logger)
+	userImportHandler := handlers.NewUserImportHandler(userImportService, cfg, logger)
+	scimHandler := handlers.NewSCIMHandler(scimService, cfg, logger)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService, cfg, logger)
+	profileHandler := handlers.NewProfileHandler(profileService, cfg, logger)
+	attributeHandler := handlers.NewAttributeHandler(attributeService, cfg, logger)
+	oauthHandler := handlers.NewOAuthHandler(oauthService, cfg, logger)
+	mfaHandler := handlers.NewMFAHandler(mfaService, cfg, logger)
+	sessionHandler := handlers.NewSessionHandler(sessionService, cfg, logger)
+
+	// Subcommands share the wiring above and exit instead of serving
+	if len(os.Args) > 1 {
+		cmds := &commands{imports: userImportService, orgs: orgRepo, stdout: os.Stdout, stderr: os.Stderr}
+		os.Exit(cmds.run(os.Args[1:]))
+	}
 
 	// Setup middleware
 	corsMiddleware := middleware.CORS(&middleware.CORSConfig{
@@ -71,21 +188,189 @@ func main() {
 		MaxAge:           cfg.CORS.MaxAge,
 	})
 
+	// Sign-in sessions and personal API keys are told apart by their prefix
+	authMiddleware := middleware.Authenticate(
+		middleware.SessionAuthenticator(authService),
+		middleware.APIKeyAuthenticator(apiKeyService),
+	)
+	orgMiddleware := middleware.ActiveOrganization(orgService)
+	scimAuth := middleware.SCIMAuthentication(scimService)
+	requestInfo := middleware.RequestInfo()
+
+	securityHeaders := middleware.SecurityHeaders(&middleware.SecurityHeadersConfig{
+		HSTSMaxAge:              cfg.Security.HSTSMaxAge,
+		HSTSIncludeSubdomains:   cfg.Security.HSTSIncludeSubdomains,
+		HSTSPreload:             cfg.Security.HSTSPreload,
+		ContentSecurityPolicy:   cfg.Security.ContentSecurityPolicy,
+		ContentTypeOptions:      cfg.Security.ContentTypeOptions,
+		ReferrerPolicy:          cfg.Security.ReferrerPolicy,
+		PermissionsPolicy:       cfg.Security.PermissionsPolicy,
+		CrossOriginOpenerPolicy: cfg.Security.CrossOriginOpenerPolicy,
+		NoStorePaths:            cfg.Security.NoStorePaths,
+	})
+
+	// Buckets live in Postgres when several replicas must share limits
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimitStore = ratelimit.NewPostgresStore(db.DB)
+	}
+	rateLimit := middleware.RateLimit(rateLimitStore, &cfg.RateLimit, logger)
+
+	// public wraps a handler that needs no authentication
+	public := func(handler http.HandlerFunc) http.Handler {
+		return corsMiddleware(requestInfo(rateLimit(handler)))
+	}
+
+	// authenticated wraps a handler that any signed-in principal may call
+	authenticated := func(handler http.HandlerFunc) http.Handler {
+		return corsMiddleware(requestInfo(authMiddleware(rateLimit(orgMiddleware(middleware.RequireAuthentication()(handler))))))
+	}
+
+	// protected wraps a handler with CORS, authentication, rate limiting and the permission it requires
+	protected := func(permission auth.Permission, handler http.HandlerFunc) http.Handler {
+		return corsMiddleware(requestInfo(authMiddleware(rateLimit(orgMiddleware(middleware.RequirePermission(authzService, permission)(handler))))))
+	}
+
+	// readWrite is protected with one permission for reading and another for
+	// changing the same resource
+	readWrite := func(read, write auth.Permission, handler http.HandlerFunc) http.Handler {
+		reader, writer := protected(read, handler), protected(write, handler)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				reader.ServeHTTP(w, r)
+			default:
+				writer.ServeHTTP(w, r)
+			}
+		})
+	}
+
+	// stepUp demands fresh multi-factor authentication, or the strongest
+	// the account can manage, for requests that change something
+	requireStepUp := middleware.RequireStepUp(authService, auth.Requirement{
+		ACR:    auth.ACRMultiFactor,
+		MaxAge: cfg.Sessions.StepUpMaxAge,
+	})
+	stepUp := func(handler http.HandlerFunc) http.HandlerFunc {
+		guarded := requireStepUp(handler)
+		return func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				handler(w, r)
+			default:
+				guarded.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	// provisioning wraps a SCIM handler; identity providers call it with an
+	// organization's SCIM token and no browser is involved, so no CORS
+	provisioning := func(handler http.HandlerFunc) http.Handler {
+		return requestInfo(scimAuth(rateLimit(handler)))
+	}
+
+	// backchannel wraps an OAuth endpoint; services call it with their own
+	// client credentials and no browser is involved, so no CORS
+	backchannel := func(handler http.HandlerFunc) http.Handler {
+		return requestInfo(rateLimit(handler))
+	}
+
 	// Setup router with middleware
 	mux := http.NewServeMux()
 
-	// Apply CORS middleware to all routes
-	mux.Handle("/api/users", corsMiddleware(http.HandlerFunc(userHandler.GetAllUsers)))
-	mux.Handle("/api/users/create", corsMiddleware(http.HandlerFunc(userHandler.CreateUser)))
+	// Every route declares the permission it requires
+	mux.Handle("/api/users", protected(auth.PermUsersRead, userHandler.GetAllUsers))
+	mux.Handle("/api/users/create", protected(auth.PermUsersCreate, userHandler.CreateUser))
+	mux.Handle("/api/users/unlock", protected(auth.PermUsersUnlock, userHandler.UnlockUser))
+	mux.Handle("/api/users/import", protected(auth.PermUsersImport, userImportHandler.ImportUsers))
+	mux.Handle("/api/users/export", protected(auth.PermUsersExport, userImportHandler.ExportUsers))
+	mux.Handle("/api/users/{id}/attributes", readWrite(auth.PermUsersRead, auth.PermUserAttributes, attributeHandler.UserAttributes))
+	mux.Handle("/api/roles", protected(auth.PermRolesRead, roleHandler.GetAllRoles))
+	mux.Handle("/api/users/roles/assign", protected(auth.PermRolesAssign, roleHandler.AssignRole))
+	mux.Handle("/api/users/roles/remove", protected(auth.PermRolesAssign, roleHandler.RemoveRole))
+	mux.Handle("/api/organizations", authenticated(orgHandler.GetMyOrganizations))
+	mux.Handle("/api/organizations/create", authenticated(orgHandler.CreateOrganization))
+	mux.Handle("/api/organizations/invitations", protected(auth.PermInvitationsManage, orgHandler.GetInvitations))
+	mux.Handle("/api/organizations/invitations/create", protected(auth.PermInvitationsManage, orgHandler.CreateInvitation))
+	mux.Handle("/api/organizations/invitations/revoke", protected(auth.PermInvitationsManage, orgHandler.RevokeInvitation))
+	mux.Handle("/api/invitations/accept", authenticated(orgHandler.AcceptInvitation))
+	mux.Handle("/api/invitations/decline", public(orgHandler.DeclineInvitation))
+	mux.Handle("/api/organizations/password-policy", protected(auth.PermPasswordPolicy, orgHandler.PasswordPolicy))
+	mux.Handle("/api/organizations/scim-tokens", protected(auth.PermSCIMManage, scimHandler.Tokens))
+	mux.Handle("/api/organizations/scim-tokens/{id}", protected(auth.PermSCIMManage, scimHandler.RevokeToken))
+	mux.Handle("/api/organizations/oauth-clients", protected(auth.PermOAuthClients, oauthHandler.Clients))
+	mux.Handle("/api/organizations/oauth-clients/{id}", protected(auth.PermOAuthClients, oauthHandler.RevokeClient))
+	mux.Handle("/api/organizations/user-attributes", readWrite(auth.PermUsersRead, auth.PermUserAttributes, attributeHandler.Definitions))
+	mux.Handle("/api/organizations/user-attributes/{id}", protected(auth.PermUserAttributes, attributeHandler.Definition))
+	mux.Handle("/api/auth/sign-in", public(authHandler.SignIn))
+	mux.Handle("/api/auth/password/expired", public(authHandler.ChangeExpiredPassword))
+	mux.Handle("/api/auth/magic-link", public(authHandler.RequestMagicLink))
+	mux.Handle("/api/auth/magic-link/redeem", public(authHandler.RedeemMagicLink))
+	mux.Handle("/api/auth/otp", public(authHandler.RequestSignInCode))
+	mux.Handle("/api/auth/otp/send", public(authHandler.SendCode))
+	mux.Handle("/api/auth/otp/verify", public(authHandler.VerifyCode))
+	mux.Handle("/api/auth/step-up", authenticated(authHandler.StepUp))
+	mux.Handle("/api/auth/step-up/verify", authenticated(authHandler.VerifyStepUp))
+	mux.Handle("/api/auth/sign-out", authenticated(authHandler.SignOut))
+	mux.Handle("/api/auth/verify-email", public(profileHandler.VerifyEmail))
+	mux.Handle("/api/me", readWrite(auth.PermProfileRead, auth.PermProfileWrite, profileHandler.Profile))
+	mux.Handle("/api/me/account", protected(auth.PermProfileWrite, stepUp(profileHandler.DeleteAccount)))
+	mux.Handle("/api/me/email", protected(auth.PermProfileWrite, stepUp(profileHandler.EmailChange)))
+	mux.Handle("/api/me/email/verify", public(profileHandler.ConfirmEmailChange))
+	mux.Handle("/api/me/password", protected(auth.PermProfileWrite, stepUp(authHandler.ChangePassword)))
+	mux.Handle("/api/me/mfa/factors", readWrite(auth.PermProfileRead, auth.PermProfileWrite, stepUp(mfaHandler.Factors)))
+	mux.Handle("/api/me/mfa/factors/verify", protected(auth.PermProfileWrite, stepUp(mfaHandler.ConfirmFactor)))
+	mux.Handle("/api/me/mfa/factors/{id}", protected(auth.PermProfileWrite, stepUp(mfaHandler.DeleteFactor)))
+	mux.Handle("/api/me/sessions", protected(auth.PermProfileRead, sessionHandler.Sessions))
+	mux.Handle("/api/me/sessions/{id}", protected(auth.PermProfileWrite, sessionHandler.RevokeSession))
+	mux.Handle("/api/me/trusted-devices/{id}", protected(auth.PermProfileWrite, stepUp(sessionHandler.RevokeTrustedDevice)))
+	mux.Handle("/api/me/api-keys", protected(auth.PermAPIKeysManage, stepUp(apiKeyHandler.APIKeys)))
+	mux.Handle("/api/me/api-keys/{id}", protected(auth.PermAPIKeysManage, apiKeyHandler.RevokeAPIKey))
+	mux.Handle("/api/me/export", protected(auth.PermProfileRead, dataExportHandler.ExportOwnData))
+	mux.Handle("/api/admin/audit", protected(auth.PermAuditRead, auditHandler.GetAuditEvents))
+	mux.Handle("/api/admin/audit/verify", protected(auth.PermAuditVerify, auditHandler.VerifyAuditLog))
+	mux.Handle("/api/admin/users/{id}", protected(auth.PermUsersPrivacy, dataExportHandler.RectifyUser))
+	mux.Handle("/api/admin/users/{id}/disable", protected(auth.PermUsersDisable, userHandler.DisableUser))
+	mux.Handle("/api/admin/users/{id}/enable", protected(auth.PermUsersDisable, userHandler.EnableUser))
+	mux.Handle("/api/admin/users/{id}/sign-out", protected(auth.PermUsersManage, userHandler.SignOutUser))
+	mux.Handle("/api/admin/users/{id}/require-password-change", protected(auth.PermUsersManage, userHandler.RequirePasswordChange))
+	mux.Handle("/api/admin/users/{id}/impersonate", protected(auth.PermUsersImpersonate, authHandler.Impersonate))
+	mux.Handle("/api/admin/users/{id}/verification", protected(auth.PermUsersManage, userHandler.ResendVerification))
+	mux.Handle("/api/admin/users/{id}/export", protected(auth.PermUsersPrivacy, dataExportHandler.ExportUserData))
+	mux.Handle("/api/admin/users/{id}/exports", protected(auth.PermUsersPrivacy, dataExportHandler.GetExportRecords))
+	mux.Handle("/api/webhooks", protected(auth.PermWebhooksManage, webhookHandler.Endpoints))
+	mux.Handle("/api/webhooks/{id}", protected(auth.PermWebhooksManage, webhookHandler.DeleteEndpoint))
+	mux.Handle("/api/webhooks/deliveries", protected(auth.PermWebhooksManage, webhookHandler.GetDeliveries))
+	mux.Handle("/api/webhooks/deliveries/retry", protected(auth.PermWebhooksManage, webhookHandler.RetryDelivery))
+	mux.Handle("/oauth/token", backchannel(oauthHandler.Token))
+	mux.Handle("/oauth/introspect", backchannel(oauthHandler.Introspect))
+	mux.Handle("/scim/v2/Users", provisioning(scimHandler.Users))
+	mux.Handle("/scim/v2/Users/{id}", provisioning(scimHandler.User))
+	mux.Handle("/scim/v2/Groups", provisioning(scimHandler.Groups))
+	mux.Handle("/scim/v2/Groups/{id}", provisioning(scimHandler.Group))
+	mux.Handle("/scim/v2/ServiceProviderConfig", public(scimHandler.ServiceProviderConfig))
+	mux.Handle("/scim/v2/Schemas", public(scimHandler.Schemas))
+	mux.Handle("/scim/v2/Schemas/{id}", public(scimHandler.Schemas))
+	mux.Handle("/scim/v2/ResourceTypes", public(scimHandler.ResourceTypes))
+	mux.Handle("/scim/v2/ResourceTypes/{id}", public(scimHandler.ResourceTypes))
 
 	// Create HTTP server with proper configuration
 	server := &http.Server{
 		Addr:         cfg.Server.Address,
-		Handler:      mux,
+		Handler:      securityHeaders(mux), // applied to every response, including 404s
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
+	// Deliver outbox events to webhook endpoints until shutdown
+	dispatchCtx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+
+	if cfg.Webhooks.Enabled {
+		dispatcher := service.NewWebhookDispatcher(webhookRepo, &cfg.Webhooks, logger)
+		go dispatcher.Run(dispatchCtx)
+	}
+
 	// Graceful shutdown handling
 	go func() {
 		logger.Info("server starting",
@@ -106,6 +391,7 @@ func main() {
 	<-quit
 
 	logger.Info("server shutting down")
+	stopDispatcher()
 
 	// Graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
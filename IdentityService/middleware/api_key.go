@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"identity-service/auth"
+	"identity-service/service"
+)
+
+// apiKeyAuthenticator authenticates requests bearing a personal API key
+type apiKeyAuthenticator struct {
+	keys service.APIKeyService
+}
+
+// APIKeyAuthenticator returns an authenticator for "Authorization: Bearer idk_..."
+// headers. Other bearer tokens are left for the next authenticator.
+func APIKeyAuthenticator(keys service.APIKeyService) auth.Authenticator {
+	return &apiKeyAuthenticator{keys: keys}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	return a.keys.Authenticate(r.Context(), token, clientIP(r))
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// clientIP returns the address of the directly connected client
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
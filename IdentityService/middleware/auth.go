@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"identity-service/auth"
+	"identity-service/service"
+)
+
+// ImpersonatedByHeader names the user impersonating the principal of the
+// request
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+// insufficientUserAuthentication is the RFC 9470 error code for a session
+// that must authenticate again
+const insufficientUserAuthentication = "insufficient_user_authentication"
+
+// Authenticate resolves the request principal using the first authenticator
+// that recognises the presented credentials. Requests without credentials
+// continue anonymously; invalid credentials are rejected with 401, and
+// clients locked out after repeated failures with 429.
+func Authenticate(authenticators ...auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(r)
+				if errors.Is(err, auth.ErrNoCredentials) {
+					continue
+				}
+				if errors.Is(err, auth.ErrTooManyAttempts) {
+					writeJSONError(w, http.StatusTooManyRequests, "too many failed attempts")
+					return
+				}
+				if err != nil {
+					writeJSONError(w, http.StatusUnauthorized, "invalid credentials")
+					return
+				}
+
+				// Impersonation is visible on every response it produces
+				if principal.Impersonated() {
+					w.Header().Set(ImpersonatedByHeader, strconv.Itoa(principal.ActorUserID))
+				}
+
+				next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAuthentication rejects requests that have no authenticated principal
+func RequireAuthentication() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := auth.PrincipalFromContext(r.Context()); !ok {
+				writeJSONError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission rejects requests whose principal lacks the given permission
+func RequirePermission(authz service.AuthorizationService, permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				writeJSONError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			allowed, err := authz.Can(r.Context(), principal, permission, nil)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if !allowed {
+				writeJSONError(w, http.StatusForbidden, "permission denied")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireStepUp rejects requests whose session did not authenticate
+// strongly or recently enough with an RFC 9470 challenge naming the
+// requirement, which the client meets by stepping up the session. Other
+// credentials cannot step up and are refused outright.
+func RequireStepUp(sessions service.AuthService, req auth.Requirement) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				writeJSONError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+			if principal.SessionID == 0 {
+				writeJSONError(w, http.StatusForbidden, "a signed-in session is required")
+				return
+			}
+
+			met, err := sessions.CheckAuthentication(r.Context(), principal, req)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if !met {
+				writeStepUpChallenge(w, req)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeStepUpChallenge answers 401 with the requirement both in the
+// WWW-Authenticate header and in the body
+func writeStepUpChallenge(w http.ResponseWriter, req auth.Requirement) {
+	challenge := fmt.Sprintf(`Bearer error="%s", error_description="a stronger or more recent sign-in is required", acr_values="%s"`,
+		insufficientUserAuthentication, req.ACR)
+	body := map[string]interface{}{
+		"error":      "step-up authentication required",
+		"reason":     insufficientUserAuthentication,
+		"acr_values": req.ACR,
+	}
+	if req.MaxAge > 0 {
+		maxAge := int(req.MaxAge.Seconds())
+		challenge += fmt.Sprintf(", max_age=%d", maxAge)
+		body["max_age"] = maxAge
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeJSONError writes an error body in the same shape the handlers use
+func writeJSONError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
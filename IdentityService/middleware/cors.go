@@ -21,7 +21,7 @@ func DefaultCORSConfig() *CORSConfig {
 	return &CORSConfig{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Organization-ID", "X-Trusted-Device"},
 		ExposedHeaders:   []string{},
 		AllowCredentials: false,
 		MaxAge:           86400, // 24 hours
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"identity-service/auth"
+	"identity-service/database"
+	"identity-service/service"
+)
+
+// OrganizationHeader selects the active organization for a request
+const OrganizationHeader = "X-Organization-ID"
+
+// ActiveOrganization sets the principal's active organization from the
+// X-Organization-ID header after verifying the caller is a member of it.
+// A principal whose credentials already pin an organization keeps it, as
+// long as its user is still an active member. The resolved organization
+// also scopes database row-level security.
+func ActiveOrganization(orgs service.OrganizationService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scoped := *principal
+			if header := r.Header.Get(OrganizationHeader); header != "" {
+				orgID, err := strconv.Atoi(header)
+				if err != nil || orgID <= 0 {
+					writeJSONError(w, http.StatusBadRequest, "invalid organization id")
+					return
+				}
+
+				if principal.OrganizationID != 0 && principal.OrganizationID != orgID {
+					writeJSONError(w, http.StatusForbidden, "credentials are bound to a different organization")
+					return
+				}
+
+				if principal.OrganizationID == 0 {
+					member, err := orgs.IsMember(r.Context(), orgID, principal.UserID)
+					if err != nil {
+						writeJSONError(w, http.StatusInternalServerError, "internal server error")
+						return
+					}
+					if !member {
+						writeJSONError(w, http.StatusForbidden, "not a member of this organization")
+						return
+					}
+					scoped.OrganizationID = orgID
+				}
+			}
+
+			// Deactivating a member also cuts off their organization-bound
+			// keys. Tokens acting for the organization itself have no user.
+			if principal.OrganizationID != 0 && principal.UserID != 0 {
+				member, err := orgs.IsMember(r.Context(), principal.OrganizationID, principal.UserID)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, "internal server error")
+					return
+				}
+				if !member {
+					writeJSONError(w, http.StatusForbidden, "not a member of this organization")
+					return
+				}
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), &scoped)
+			if scoped.OrganizationID != 0 {
+				ctx = database.WithTenant(ctx, scoped.OrganizationID)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
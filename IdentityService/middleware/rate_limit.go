@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	"identity-service/ratelimit"
+	"identity-service/requestinfo"
+)
+
+// maxRateLimitBody caps how much of a request body is read to find an email
+const maxRateLimitBody = 64 << 10
+
+// RateLimit enforces the rule configured for the matched route pattern,
+// falling back to the default rule, and the route's per-IP rule if it has
+// one. It must run after Authenticate so user and client keys can see the
+// principal; unauthenticated requests are keyed by IP instead. When the
+// store fails the request is let through.
+func RateLimit(store ratelimit.Store, cfg *config.RateLimitConfig, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !cfg.Enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rule, ok := cfg.Routes[r.Pattern]
+			if !ok {
+				rule = cfg.Default
+			}
+			now := time.Now().UTC()
+
+			result, err := take(r.Context(), store, r.Pattern+"|"+rateLimitKey(r, rule.Key), rule, now)
+			if err == nil && result.Allowed {
+				if ipRule, ok := cfg.IPRoutes[r.Pattern]; ok {
+					var ipResult ratelimit.Result
+					ipResult, err = take(r.Context(), store, r.Pattern+"|per-ip|"+rateLimitKey(r, config.RateLimitKeyIP), ipRule, now)
+					if err == nil && !ipResult.Allowed {
+						result = ipResult
+					}
+				}
+			}
+			if err != nil {
+				log.ErrorContext(r.Context(), "rate limit store failed",
+					slog.String("error", err.Error()),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(result.Reset))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", seconds(result.RetryAfter))
+				writeJSONError(w, http.StatusTooManyRequests, "too many requests")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// take spends one request from the bucket key under rule
+func take(ctx context.Context, store ratelimit.Store, key string, rule config.RateLimitRule, now time.Time) (ratelimit.Result, error) {
+	return store.Take(ctx, key, ratelimit.Limit{
+		Requests: rule.Requests,
+		Window:   rule.Window,
+	}, now)
+}
+
+// rateLimitKey identifies the caller a rule applies to
+func rateLimitKey(r *http.Request, kind string) string {
+	principal, authenticated := auth.PrincipalFromContext(r.Context())
+
+	switch kind {
+	case config.RateLimitKeyClient:
+		if authenticated && principal.APIKeyID != 0 {
+			return "client:" + strconv.Itoa(principal.APIKeyID)
+		}
+		fallthrough
+	case config.RateLimitKeyUser:
+		// Provisioning tokens act for no user
+		if authenticated && principal.SCIMTokenID != 0 {
+			return "scim:" + strconv.Itoa(principal.SCIMTokenID)
+		}
+		if authenticated {
+			return "user:" + strconv.Itoa(principal.UserID)
+		}
+	case config.RateLimitKeyEmail:
+		if email := requestEmail(r); email != "" {
+			// Hashed so shared stores never hold addresses
+			return "email:" + auth.HashToken(email)
+		}
+	}
+
+	ip := requestinfo.From(r.Context()).IP
+	if ip == "" {
+		ip = clientIP(r)
+	}
+	return "ip:" + ip
+}
+
+// requestEmail reads the email field of a JSON body and restores the body
+func requestEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRateLimitBody))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+// seconds formats a duration as whole seconds, rounding up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
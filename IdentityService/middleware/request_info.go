@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"identity-service/auth"
+	"identity-service/requestinfo"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// TrustedDeviceHeader carries a trusted device token at sign-in
+const TrustedDeviceHeader = "X-Trusted-Device"
+
+// validRequestID keeps caller-supplied IDs safe to log and store
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,64}$`)
+
+// RequestInfo records the request ID, client IP, user agent and any
+// trusted device token in the request context and echoes the request ID
+// back to the caller. A valid X-Request-ID from the caller is reused so
+// traces can be correlated.
+func RequestInfo() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID.MatchString(id) {
+				generated, err := auth.GenerateToken(12)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, "internal server error")
+					return
+				}
+				id = generated
+			}
+
+			w.Header().Set(RequestIDHeader, id)
+			ctx := requestinfo.With(r.Context(), requestinfo.Info{
+				ID:            id,
+				IP:            clientIP(r),
+				UserAgent:     r.UserAgent(),
+				TrustedDevice: r.Header.Get(TrustedDeviceHeader),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"identity-service/auth"
+	"identity-service/database"
+	"identity-service/scim"
+	"identity-service/service"
+)
+
+// SCIMAuthentication authenticates SCIM requests with an organization's
+// provisioning token ("Authorization: Bearer scim_..."). No other
+// credentials are accepted, and failures are reported as SCIM errors. The
+// token's organization becomes the active one and scopes row-level security.
+func SCIMAuthentication(tokens service.SCIMService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				writeSCIMError(w, http.StatusUnauthorized, "authentication required")
+				return
+			}
+
+			principal, err := tokens.Authenticate(r.Context(), token, clientIP(r))
+			switch {
+			case errors.Is(err, auth.ErrTooManyAttempts):
+				writeSCIMError(w, http.StatusTooManyRequests, "too many failed attempts")
+				return
+			case errors.Is(err, auth.ErrNoCredentials), errors.Is(err, auth.ErrInvalidCredentials):
+				writeSCIMError(w, http.StatusUnauthorized, "invalid credentials")
+				return
+			case err != nil:
+				writeSCIMError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			ctx = database.WithTenant(ctx, principal.OrganizationID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeSCIMError writes a SCIM error response
+func writeSCIMError(w http.ResponseWriter, statusCode int, detail string) {
+	if statusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+	}
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(scim.NewError(statusCode, "", detail))
+}
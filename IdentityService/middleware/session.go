@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"identity-service/auth"
+	"identity-service/service"
+)
+
+// sessionAuthenticator authenticates requests bearing a sign-in session token
+type sessionAuthenticator struct {
+	sessions service.AuthService
+}
+
+// SessionAuthenticator returns an authenticator for "Authorization: Bearer ids_..."
+// headers. Other bearer tokens are left for the next authenticator.
+func SessionAuthenticator(sessions service.AuthService) auth.Authenticator {
+	return &sessionAuthenticator{sessions: sessions}
+}
+
+func (a *sessionAuthenticator) Authenticate(r *http.Request) (*auth.Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	return a.sessions.Authenticate(r.Context(), token, clientIP(r))
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+type APIKey struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"-"`
+	OrganizationID *int       `json:"organization_id,omitempty"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	Scopes         []string   `json:"scopes"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP     *string    `json:"last_used_ip,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once at creation; the plaintext key is never stored
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyCredential is an API key together with its owner, as resolved during authentication
+type APIKeyCredential struct {
+	APIKey
+	KeyHash   string
+	UserEmail string
+	RevokedAt *time.Time
+	// UserDisabled is true while the owner's account is disabled
+	UserDisabled bool
+}
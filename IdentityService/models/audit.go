@@ -0,0 +1,120 @@
+package models
+
+import "time"
+
+// Audit event actions. Add new actions here rather than inlining strings.
+const (
+	AuditAuthFailed             = "auth.failed"
+	AuditSignIn                 = "auth.signed_in"
+	AuditSignOut                = "auth.signed_out"
+	AuditImpersonationStarted   = "auth.impersonation_started"
+	AuditMagicLinkSent          = "auth.magic_link_sent"
+	AuditOTPSent                = "auth.otp_sent"
+	AuditMFAFactorAdded         = "mfa.factor_added"
+	AuditMFAFactorRemoved       = "mfa.factor_removed"
+	AuditStepUp                 = "auth.step_up"
+	AuditNewDeviceSignIn        = "auth.new_device_sign_in"
+	AuditDeviceTrusted          = "auth.device_trusted"
+	AuditTrustedDeviceRevoked   = "auth.trusted_device_revoked"
+	AuditSessionRevoked         = "auth.session_revoked"
+	AuditPasswordChanged        = "password.changed"
+	AuditPasswordRehashed       = "password.rehashed"
+	AuditPasswordPolicySet      = "organization.password_policy_updated"
+	AuditUserCreated            = "user.created"
+	AuditUserUpdated            = "user.updated"
+	AuditUserDeactivated        = "user.deactivated"
+	AuditUserReactivated        = "user.reactivated"
+	AuditUserRemoved            = "user.removed"
+	AuditAccountDeleted         = "user.account_deleted"
+	AuditUserDisabled           = "user.disabled"
+	AuditUserEnabled            = "user.enabled"
+	AuditUserSignedOut          = "user.signed_out"
+	AuditPasswordChangeRequired = "user.password_change_required"
+	AuditVerificationSent       = "user.verification_sent"
+	AuditEmailVerified          = "user.email_verified"
+	AuditUserLocked             = "user.locked"
+	AuditUserUnlocked           = "user.unlocked"
+	AuditUsersImported          = "user.imported"
+	AuditUsersExported          = "user.exported"
+	AuditUserDataExported       = "user.data_exported"
+	AuditProfileUpdated         = "user.profile_updated"
+	AuditEmailChangeStarted     = "user.email_change_requested"
+	AuditEmailChanged           = "user.email_changed"
+	AuditAttributesUpdated      = "user.attributes_updated"
+	AuditAttributeCreated       = "user_attribute.created"
+	AuditAttributeUpdated       = "user_attribute.updated"
+	AuditAttributeDeleted       = "user_attribute.deleted"
+	AuditRoleAssigned           = "role.assigned"
+	AuditRoleRemoved            = "role.removed"
+	AuditOrganizationCreated    = "organization.created"
+	AuditInvitationCreated      = "invitation.created"
+	AuditInvitationRevoked      = "invitation.revoked"
+	AuditInvitationAccepted     = "invitation.accepted"
+	AuditInvitationDeclined     = "invitation.declined"
+	AuditAPIKeyCreated          = "api_key.created"
+	AuditAPIKeyRevoked          = "api_key.revoked"
+	AuditWebhookCreated         = "webhook.created"
+	AuditWebhookDeleted         = "webhook.deleted"
+	AuditWebhookRetried         = "webhook.delivery_retried"
+	AuditGroupCreated           = "group.created"
+	AuditGroupUpdated           = "group.updated"
+	AuditGroupDeleted           = "group.deleted"
+	AuditSCIMTokenCreated       = "scim_token.created"
+	AuditSCIMTokenRevoked       = "scim_token.revoked"
+	AuditOAuthClientCreated     = "oauth_client.created"
+	AuditOAuthClientRevoked     = "oauth_client.revoked"
+	AuditTokenExchanged         = "oauth.token_exchanged"
+)
+
+// Audit event outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+type AuditEvent struct {
+	ID             int64                  `json:"id"`
+	OccurredAt     time.Time              `json:"occurred_at"`
+	Action         string                 `json:"action"`
+	Outcome        string                 `json:"outcome"`
+	ActorUserID    *int                   `json:"actor_user_id,omitempty"`
+	ActorAPIKeyID  *int                   `json:"actor_api_key_id,omitempty"`
+	OrganizationID *int                   `json:"organization_id,omitempty"`
+	TargetType     string                 `json:"target_type,omitempty"`
+	TargetID       string                 `json:"target_id,omitempty"`
+	IP             string                 `json:"ip,omitempty"`
+	UserAgent      string                 `json:"user_agent,omitempty"`
+	RequestID      string                 `json:"request_id,omitempty"`
+	Details        map[string]interface{} `json:"details"`
+	PrevHash       string                 `json:"prev_hash"`
+	Hash           string                 `json:"hash"`
+}
+
+// AuditFilter narrows an audit query. Zero values mean "any".
+type AuditFilter struct {
+	OrganizationID int
+	ActorUserID    int
+	Action         string
+	TargetType     string
+	TargetID       string
+	Since          *time.Time
+	Until          *time.Time
+	BeforeID       int64
+	Limit          int
+}
+
+type AuditPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor *int64       `json:"next_cursor,omitempty"`
+}
+
+// AuditVerification reports the result of checking the hash chain. When
+// the deadline ran out first, NextCursor is the last event checked and
+// verification resumes from there.
+type AuditVerification struct {
+	Valid         bool   `json:"valid"`
+	EventsChecked int64  `json:"events_checked"`
+	BrokenAtID    *int64 `json:"broken_at_id,omitempty"`
+	LastHash      string `json:"last_hash"`
+	NextCursor    *int64 `json:"next_cursor,omitempty"`
+}
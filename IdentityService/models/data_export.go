@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// DataExportFormatVersion is bumped whenever the archive layout changes in
+// a way consumers must notice
+const DataExportFormatVersion = 1
+
+// DataExport is the machine-readable archive of everything the service
+// holds about one person, produced for data subject access requests.
+// Secrets are never included: password hashes, token hashes and API key
+// hashes stay behind, only their metadata is exported.
+//
+// LinkedIdentities and Consents are always present so the archive keeps
+// a stable shape; they stay empty until the service stores such data.
+type DataExport struct {
+	FormatVersion int       `json:"format_version"`
+	GeneratedAt   time.Time `json:"generated_at"`
+	// OrganizationID is set when the export is limited to one organization
+	OrganizationID   *int                   `json:"organization_id,omitempty"`
+	Profile          DataExportProfile      `json:"profile"`
+	Memberships      []DataExportMembership `json:"memberships"`
+	PlatformRoles    []string               `json:"platform_roles"`
+	Sessions         []Session              `json:"sessions"`
+	SignIns          []SignInEvent          `json:"sign_ins"`
+	TrustedDevices   []TrustedDevice        `json:"trusted_devices"`
+	APIKeys          []APIKey               `json:"api_keys"`
+	Invitations      []Invitation           `json:"invitations"`
+	AuditEvents      []AuditEvent           `json:"audit_events"`
+	LinkedIdentities []interface{}          `json:"linked_identities"`
+	MFAFactors       []MFAFactor            `json:"mfa_factors"`
+	Consents         []interface{}          `json:"consents"`
+	Exports          []DataExportRecord     `json:"exports"`
+}
+
+// DataExportProfile is the user's own record, with password metadata only
+type DataExportProfile struct {
+	ID                     int         `json:"id"`
+	Name                   string      `json:"name"`
+	Email                  string      `json:"email"`
+	DisplayName            string      `json:"display_name,omitempty"`
+	AvatarURL              string      `json:"avatar_url,omitempty"`
+	Locale                 string      `json:"locale,omitempty"`
+	TimeZone               string      `json:"time_zone,omitempty"`
+	PendingEmail           *string     `json:"pending_email,omitempty"`
+	EmailVerifiedAt        *time.Time  `json:"email_verified_at,omitempty"`
+	DisabledAt             *time.Time  `json:"disabled_at,omitempty"`
+	CreatedAt              *time.Time  `json:"created_at,omitempty"`
+	HasPassword            bool        `json:"has_password"`
+	PasswordChangedAt      *time.Time  `json:"password_changed_at,omitempty"`
+	PasswordChangeRequired bool        `json:"password_change_required"`
+	PasswordHistory        []time.Time `json:"password_history"`
+	LockedUntil            *time.Time  `json:"locked_until,omitempty"`
+	LastFailedLoginAt      *time.Time  `json:"last_failed_login_at,omitempty"`
+	FailedLoginCount       int         `json:"failed_login_count"`
+}
+
+// DataExportMembership is the user's membership of one organization
+type DataExportMembership struct {
+	OrganizationID   int                    `json:"organization_id"`
+	OrganizationName string                 `json:"organization_name"`
+	Active           bool                   `json:"active"`
+	ExternalID       string                 `json:"external_id,omitempty"`
+	JoinedAt         *time.Time             `json:"joined_at,omitempty"`
+	Roles            []string               `json:"roles"`
+	Groups           []string               `json:"groups"`
+	Attributes       map[string]interface{} `json:"attributes"`
+}
+
+// DataExportRecord notes that an export was produced, by whom and why
+type DataExportRecord struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"user_id"`
+	OrganizationID *int      `json:"organization_id,omitempty"`
+	RequestedBy    *int      `json:"requested_by,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// RectifyUserRequest corrects a user's personal data on their behalf
+type RectifyUserRequest struct {
+	Name   string `json:"name" binding:"required"`
+	Email  string `json:"email" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// DataExportRequest starts an export on a user's behalf
+type DataExportRequest struct {
+	Reason string `json:"reason"`
+}
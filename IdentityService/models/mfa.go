@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// One-time passcode delivery channels, which are also the factor types
+const (
+	FactorEmail = "email"
+	FactorSMS   = "sms"
+)
+
+// What a one-time passcode challenge proves
+const (
+	OTPPurposeSignIn = "sign_in"
+	OTPPurposeMFA    = "mfa"
+	OTPPurposeEnroll = "enroll"
+	OTPPurposeStepUp = "step_up"
+)
+
+// MFAFactor is a way to receive one-time passcodes. Destination is the
+// phone number of an SMS factor and empty for an email factor, which uses
+// the account's current address.
+type MFAFactor struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Type        string     `json:"type"`
+	Destination string     `json:"destination,omitempty"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
+
+// MFAFactorOption is a factor offered to complete sign-in, with its
+// destination masked
+type MFAFactorOption struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	Hint string `json:"hint"`
+}
+
+type EnrollFactorRequest struct {
+	Type  string `json:"type" binding:"required"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// FactorEnrollment is a new factor awaiting the code sent to it
+type FactorEnrollment struct {
+	Factor    MFAFactor `json:"factor"`
+	OTPToken  string    `json:"otp_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OTPChallenge is an outstanding one-time passcode. FirstFactor is the
+// sign-in method already proven for an MFA challenge; FactorID and
+// Channel say where the current code was sent. PendingPasswordHash
+// replaces an expired password once an MFA challenge is passed.
+type OTPChallenge struct {
+	ID                  int
+	UserID              int
+	Purpose             string
+	FirstFactor         string
+	FactorID            *int
+	Channel             string
+	CodeHash            string
+	Attempts            int
+	Sends               int
+	SentAt              *time.Time
+	ExpiresAt           time.Time
+	CreatedAt           time.Time
+	PendingPasswordHash string
+}
+
+// OTPSignInRequest asks for a sign-in code. Channel defaults to email;
+// sms delivers to the account's verified SMS factor.
+type OTPSignInRequest struct {
+	Email   string `json:"email" binding:"required"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// OTPResponse is returned whether or not a code was actually sent, so it
+// reveals nothing about the account
+type OTPResponse struct {
+	OTPToken  string    `json:"otp_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SendOTPRequest asks for a code, or a new one, for a challenge. For an
+// MFA challenge FactorID picks where to send it.
+type SendOTPRequest struct {
+	OTPToken string `json:"otp_token" binding:"required"`
+	FactorID int    `json:"factor_id,omitempty"`
+}
+
+// VerifyOTPRequest proves a challenge with the code sent for it.
+// RememberDevice asks for a trusted device token when the code is a
+// second factor.
+type VerifyOTPRequest struct {
+	OTPToken       string `json:"otp_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	RememberDevice bool   `json:"remember_device,omitempty"`
+}
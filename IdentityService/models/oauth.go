@@ -0,0 +1,117 @@
+package models
+
+import "time"
+
+// RFC 8693 token exchange identifiers
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// OAuthClient is a service of an organization that exchanges its users'
+// tokens for tokens meant for another audience. It may only request the
+// audiences and scopes listed; AllowImpersonation lets it obtain tokens
+// that do not name it as actor.
+type OAuthClient struct {
+	ID                 int        `json:"id"`
+	OrganizationID     int        `json:"organization_id"`
+	ClientID           string     `json:"client_id"`
+	Name               string     `json:"name"`
+	AllowedAudiences   []string   `json:"allowed_audiences"`
+	AllowedScopes      []string   `json:"allowed_scopes"`
+	AllowImpersonation bool       `json:"allow_impersonation"`
+	CreatedBy          *int       `json:"created_by,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	LastUsedAt         *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CreateOAuthClientRequest struct {
+	Name               string   `json:"name" binding:"required"`
+	AllowedAudiences   []string `json:"allowed_audiences" binding:"required"`
+	AllowedScopes      []string `json:"allowed_scopes" binding:"required"`
+	AllowImpersonation bool     `json:"allow_impersonation"`
+}
+
+// CreatedOAuthClient is returned once at creation; the plaintext secret is never stored
+type CreatedOAuthClient struct {
+	OAuthClient
+	ClientSecret string `json:"client_secret"`
+}
+
+// OAuthClientCredential is a client as resolved during client authentication
+type OAuthClientCredential struct {
+	OAuthClient
+	SecretHash string
+	RevokedAt  *time.Time
+}
+
+// TokenExchangeRequest holds the RFC 8693 request parameters. Scope is
+// space-delimited.
+type TokenExchangeRequest struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	Audience           string
+	Scope              string
+	RequestedTokenType string
+}
+
+// TokenResponse is a successful RFC 8693 token exchange response
+type TokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// AccessToken is a token issued by token exchange. SessionID is the
+// sign-in session it was ultimately derived from.
+type AccessToken struct {
+	ID             int64
+	ClientID       int
+	OrganizationID int
+	UserID         int
+	SessionID      int64
+	Audience       string
+	Scopes         []string
+	Act            *Actor
+	IssuedAt       time.Time
+	ExpiresAt      time.Time
+}
+
+// AccessTokenCredential is an exchanged token as resolved for reuse or
+// introspection. Revoked is true once its session or client is revoked,
+// or its user disabled.
+type AccessTokenCredential struct {
+	AccessToken
+	ClientPublicID string
+	UserEmail      string
+	Revoked        bool
+	// SessionAMR and SessionAuthTime describe how the session the token
+	// was exchanged from authenticated
+	SessionAMR      []string
+	SessionAuthTime time.Time
+}
+
+// IntrospectionResponse is an RFC 7662 token introspection response.
+// Only Active is set for a token that is not active.
+type IntrospectionResponse struct {
+	Active         bool                   `json:"active"`
+	Scope          string                 `json:"scope,omitempty"`
+	ClientID       string                 `json:"client_id,omitempty"`
+	Username       string                 `json:"username,omitempty"`
+	TokenType      string                 `json:"token_type,omitempty"`
+	Exp            int64                  `json:"exp,omitempty"`
+	Iat            int64                  `json:"iat,omitempty"`
+	Sub            string                 `json:"sub,omitempty"`
+	Aud            string                 `json:"aud,omitempty"`
+	Act            *Actor                 `json:"act,omitempty"`
+	ACR            string                 `json:"acr,omitempty"`
+	AMR            []string               `json:"amr,omitempty"`
+	AuthTime       int64                  `json:"auth_time,omitempty"`
+	OrganizationID int                    `json:"organization_id,omitempty"`
+	Roles          []string               `json:"roles,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PasswordPolicy is an organization's override of the password policy.
+// Nil fields fall back to the service defaults. Members of several
+// organizations get the strictest combination.
+type PasswordPolicy struct {
+	HistoryCount *int `json:"history_count"`
+	MaxAgeDays   *int `json:"max_age_days"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+	Slug string `json:"slug" binding:"required"`
+}
+
+type Invitation struct {
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	Status         string     `json:"status"`
+	InvitedBy      *int       `json:"invited_by,omitempty"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	RespondedAt    *time.Time `json:"responded_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type InvitationTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RevokeInvitationRequest struct {
+	ID int `json:"id" binding:"required"`
+}
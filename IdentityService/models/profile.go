@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Profile is the signed-in user's own account, shared by every
+// organization they belong to
+type Profile struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	DisplayName   string     `json:"display_name,omitempty"`
+	AvatarURL     string     `json:"avatar_url,omitempty"`
+	Locale        string     `json:"locale,omitempty"`
+	TimeZone      string     `json:"time_zone,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	// PendingEmail is the address awaiting verification, if any
+	PendingEmail *string `json:"pending_email,omitempty"`
+}
+
+// UpdateProfileRequest changes only the fields that are present. An empty
+// string clears an optional attribute; the name cannot be cleared.
+type UpdateProfileRequest struct {
+	Name        *string `json:"name"`
+	DisplayName *string `json:"display_name"`
+	AvatarURL   *string `json:"avatar_url"`
+	Locale      *string `json:"locale"`
+	TimeZone    *string `json:"time_zone"`
+}
+
+// ChangeEmailRequest starts an email change. The current password is
+// required whenever one is set.
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" binding:"required"`
+	CurrentPassword string `json:"current_password"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailChange is a requested email address awaiting verification. The
+// token itself is only sent to the new address.
+type EmailChange struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	NewEmail  string    `json:"new_email"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailVerification is an outstanding link proving control of the user's
+// address. It only verifies the address it was sent to.
+type EmailVerification struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
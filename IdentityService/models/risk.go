@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// What a sign-in's risk score leads to, from least to most restrictive
+const (
+	RiskAllow = "allow"
+	RiskMFA   = "mfa"
+	RiskBlock = "block"
+)
+
+// Signals that add to a sign-in's risk score
+const (
+	RiskSignalNewUserAgent     = "new_user_agent"
+	RiskSignalNewIP            = "new_ip"
+	RiskSignalNewASN           = "new_asn"
+	RiskSignalImpossibleTravel = "impossible_travel"
+	RiskSignalFailedAttempts   = "failed_attempts"
+)
+
+// SignInEvent is a completed sign-in, kept to judge later ones against.
+// The network and location come from the GeoIP database when one is
+// configured.
+type SignInEvent struct {
+	ID        int64     `json:"id"`
+	UserID    int       `json:"user_id"`
+	SessionID *int64    `json:"session_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ASN       *int      `json:"asn,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignInHistory is what a user's earlier sign-ins say about a new one.
+// Last is the most recent sign-in with a known location.
+type SignInHistory struct {
+	SignIns        int
+	KnownUserAgent bool
+	KnownIP        bool
+	KnownASN       bool
+	Last           *SignInEvent
+	IPFailures     int
+}
+
+// RiskAssessment is the score of a sign-in, the signals behind it and
+// what it leads to
+type RiskAssessment struct {
+	Score    int
+	Signals  []string
+	Decision string
+}
@@ -0,0 +1,14 @@
+package models
+
+type Role struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	BuiltIn     bool     `json:"built_in"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRoleRequest struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Role   string `json:"role" binding:"required"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// SCIMToken is a bearer token an identity provider uses to provision the
+// users and groups of one organization
+type SCIMToken struct {
+	ID             int        `json:"id"`
+	OrganizationID int        `json:"organization_id"`
+	Name           string     `json:"name"`
+	CreatedBy      *int       `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+type CreateSCIMTokenRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// CreatedSCIMToken is returned once at creation; the plaintext token is never stored
+type CreatedSCIMToken struct {
+	SCIMToken
+	Token string `json:"token"`
+}
+
+// SCIMTokenCredential is a token as resolved during authentication
+type SCIMTokenCredential struct {
+	SCIMToken
+	RevokedAt *time.Time
+}
+
+// SCIMMember is a member of an organization with the attributes an
+// identity provider manages
+type SCIMMember struct {
+	User
+	ExternalID string
+	CreatedAt  time.Time
+	UpdatedAt  *time.Time
+}
+
+// SCIMMemberMatch narrows a member lookup. Zero values mean "any".
+type SCIMMemberMatch struct {
+	Email      string
+	ExternalID string
+}
+
+// Group is a named set of an organization's members
+type Group struct {
+	ID             int           `json:"id"`
+	OrganizationID int           `json:"organization_id"`
+	DisplayName    string        `json:"display_name"`
+	ExternalID     string        `json:"external_id,omitempty"`
+	Members        []GroupMember `json:"members"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// GroupMember is a user in a group
+type GroupMember struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// GroupMatch narrows a group lookup. Zero values mean "any".
+type GroupMatch struct {
+	DisplayName string
+	ExternalID  string
+}
@@ -0,0 +1,172 @@
+package models
+
+import "time"
+
+// Session is a signed-in browser or client. The bearer token itself is
+// only returned once, at sign-in.
+type Session struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	// OrganizationID and ActorUserID are set for impersonation sessions,
+	// which are bound to one organization and act for another user
+	OrganizationID *int `json:"organization_id,omitempty"`
+	ActorUserID    *int `json:"actor_user_id,omitempty"`
+	// AMR lists the RFC 8176 methods of the latest authentication, at
+	// sign-in or a later step-up, and AuthTime is when it happened
+	AMR      []string  `json:"amr"`
+	AuthTime time.Time `json:"auth_time"`
+	// Current is set when listing the caller's own sessions
+	Current bool `json:"current,omitempty"`
+}
+
+// SessionCredential is a session together with its owner, as resolved during authentication
+type SessionCredential struct {
+	Session
+	UserEmail string
+	RevokedAt *time.Time
+	// UserDisabled is true while the owner's account, or the account of
+	// the impersonating actor, is disabled
+	UserDisabled bool
+}
+
+// SignInResponse carries a new session token. When a second factor is
+// required it instead carries an MFA challenge: send a code to one of
+// Factors with the OTPToken and verify it to get the session. ExpiresAt
+// is then when the challenge expires.
+type SignInResponse struct {
+	Token       string            `json:"token,omitempty"`
+	ExpiresAt   time.Time         `json:"expires_at"`
+	ACR         string            `json:"acr,omitempty"`
+	AMR         []string          `json:"amr,omitempty"`
+	MFARequired bool              `json:"mfa_required,omitempty"`
+	OTPToken    string            `json:"otp_token,omitempty"`
+	Factors     []MFAFactorOption `json:"factors,omitempty"`
+	// TrustedDeviceToken is set when the second factor was verified with
+	// remember_device, and skips it on later sign-ins from this device
+	TrustedDeviceToken     string     `json:"trusted_device_token,omitempty"`
+	TrustedDeviceExpiresAt *time.Time `json:"trusted_device_expires_at,omitempty"`
+}
+
+// TrustedDevice is a browser or device that skips the second factor at
+// sign-in until it expires or is revoked. The token itself is only
+// returned once, when the device is trusted.
+type TrustedDevice struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+}
+
+// SessionList is the caller's active sessions and trusted devices
+type SessionList struct {
+	Sessions       []Session       `json:"sessions"`
+	TrustedDevices []TrustedDevice `json:"trusted_devices"`
+}
+
+// Actor identifies the party acting on behalf of the subject, as in the
+// RFC 8693 "act" claim: a user, or a client acting on its own. Act nests
+// the actors of earlier delegations, most recent first.
+type Actor struct {
+	Subject  string `json:"sub,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Act      *Actor `json:"act,omitempty"`
+}
+
+// ImpersonationResponse carries a short-lived session token acting as
+// Subject on behalf of Act
+type ImpersonationResponse struct {
+	Token          string    `json:"token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Subject        string    `json:"sub"`
+	OrganizationID int       `json:"organization_id"`
+	Act            Actor     `json:"act"`
+}
+
+type SignInRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangeExpiredPasswordRequest replaces a password that sign-in refused as
+// expired or flagged for change, proving the current one again
+type ChangeExpiredPasswordRequest struct {
+	Email           string `json:"email" binding:"required"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// PasswordCredential is a user's password state, as resolved during sign-in
+type PasswordCredential struct {
+	UserID                 int
+	Name                   string
+	Email                  string
+	PasswordHash           string
+	PasswordChangedAt      *time.Time
+	PasswordChangeRequired bool
+	LockedUntil            *time.Time
+	DisabledAt             *time.Time
+	FailedLoginCount       int
+	LastFailedLoginAt      *time.Time
+}
+
+// MagicLinkRequest asks for a sign-in link to be emailed
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// MagicLinkResponse is returned whether or not the address has an account.
+// DeviceToken must accompany the link when it is redeemed, so the link
+// only signs in on the device that asked for it.
+type MagicLinkResponse struct {
+	DeviceToken string    `json:"device_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// RedeemMagicLinkRequest exchanges a sign-in link for a session
+type RedeemMagicLinkRequest struct {
+	Token       string `json:"token" binding:"required"`
+	DeviceToken string `json:"device_token" binding:"required"`
+}
+
+// MagicLink is an outstanding sign-in link. Only the address it was sent
+// to can be signed in with it.
+type MagicLink struct {
+	ID        int
+	UserID    int
+	Email     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// StepUpRequest re-authenticates the caller's session. Password is
+// required when the account has one.
+type StepUpRequest struct {
+	Password string `json:"password,omitempty"`
+}
+
+// StepUpResponse reports the caller's session after re-authenticating.
+// When CodeRequired is set the session is unchanged until a code for the
+// OTPToken is verified; send it to one of Factors, or with none listed it
+// has already been emailed. ExpiresAt is then when the challenge expires.
+type StepUpResponse struct {
+	ACR          string            `json:"acr,omitempty"`
+	AMR          []string          `json:"amr,omitempty"`
+	AuthTime     *time.Time        `json:"auth_time,omitempty"`
+	CodeRequired bool              `json:"code_required,omitempty"`
+	OTPToken     string            `json:"otp_token,omitempty"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"`
+	Factors      []MFAFactorOption `json:"factors,omitempty"`
+}
@@ -1,12 +1,49 @@
 package models
 
+import "time"
+
 type User struct {
 	ID    int    `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	// Profile attributes the user maintains themselves
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	TimeZone    string `json:"time_zone,omitempty"`
+	// Attributes holds the organization's custom attributes for the member
+	Attributes map[string]interface{} `json:"attributes"`
+	// Active is false for a member deactivated in the organization
+	Active bool `json:"active"`
+	// DisabledAt is set while an administrator has disabled the account,
+	// which blocks sign-in in every organization
+	DisabledAt    *time.Time `json:"disabled_at,omitempty"`
+	EmailVerified bool       `json:"email_verified"`
+	// LockedUntil is set while sign-in is blocked after repeated failures
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+type UnlockUserRequest struct {
+	UserID int `json:"user_id" binding:"required"`
 }
 
 type CreateUserRequest struct {
 	Name  string `json:"name" binding:"required"`
 	Email string `json:"email" binding:"required"`
+	// Password optionally sets an initial password for sign-in
+	Password string `json:"password,omitempty"`
+	// PasswordHash instead carries a hash migrated from another user store,
+	// in PHC, modular crypt or LDAP {SSHA} form
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// UserActionRequest carries the reason for an administrative action on a
+// user, which is recorded in the audit log
+type UserActionRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// SignOutUserResponse reports how many sessions a forced sign-out ended
+type SignOutUserResponse struct {
+	SessionsRevoked int `json:"sessions_revoked"`
 }
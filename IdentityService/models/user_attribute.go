@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Custom attribute types. Values are stored as the matching JSON type.
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+)
+
+// AttributeDefinition is one entry of an organization's custom user
+// attribute schema. Pattern applies to string values and Enum, when not
+// empty, lists the only values allowed. Claim marks attributes that may
+// be disclosed as claims in tokens issued for the member.
+//
+// Required and Unique are enforced whenever a member's attributes are
+// written; values stored before a rule was added are left alone.
+type AttributeDefinition struct {
+	ID             int           `json:"id"`
+	OrganizationID int           `json:"organization_id"`
+	Name           string        `json:"name"`
+	Type           string        `json:"type"`
+	Description    string        `json:"description,omitempty"`
+	Required       bool          `json:"required"`
+	Unique         bool          `json:"unique"`
+	Pattern        string        `json:"pattern,omitempty"`
+	Enum           []interface{} `json:"enum,omitempty"`
+	Claim          bool          `json:"claim"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+type AttributeDefinitionRequest struct {
+	Name        string        `json:"name" binding:"required"`
+	Type        string        `json:"type" binding:"required"`
+	Description string        `json:"description"`
+	Required    bool          `json:"required"`
+	Unique      bool          `json:"unique"`
+	Pattern     string        `json:"pattern"`
+	Enum        []interface{} `json:"enum"`
+	Claim       bool          `json:"claim"`
+}
+
+// SetAttributesRequest replaces all of a member's custom attributes
+type SetAttributesRequest struct {
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// UserFilter narrows a user listing. Attributes maps attribute names to
+// the value a member must have, as given in the query string.
+type UserFilter struct {
+	Attributes map[string]string
+}
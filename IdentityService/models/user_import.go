@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Bulk user file formats
+const (
+	UserFileCSV   = "csv"
+	UserFileJSONL = "jsonl"
+)
+
+// What an import did, or in a dry run would do, with a row
+const (
+	UserImportCreated  = "created"
+	UserImportUpdated  = "updated"
+	UserImportConflict = "conflict"
+)
+
+// UserImportRow is one user read from an import file. Password and
+// PasswordHash are optional and mutually exclusive.
+type UserImportRow struct {
+	Line         int    `json:"-"`
+	Name         string `json:"name"`
+	Email        string `json:"email"`
+	Password     string `json:"password,omitempty"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// UserImportEntry is a validated row ready to be written. PasswordHash is
+// empty when the row sets no password.
+type UserImportEntry struct {
+	Line         int
+	Name         string
+	Email        string
+	PasswordHash string
+}
+
+// UserImportOutcome is what happened to one entry of a batch
+type UserImportOutcome struct {
+	Line   int
+	Email  string
+	Action string
+	UserID int
+}
+
+// UserImportOptions controls an import. A dry run validates every row and
+// reports what would change without writing anything.
+type UserImportOptions struct {
+	Format string
+	DryRun bool
+}
+
+// UserImportProgress counts the rows processed so far
+type UserImportProgress struct {
+	Processed int `json:"processed"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Failed    int `json:"failed"`
+}
+
+// UserImportError explains why a row was not imported. Line is the line
+// of the file the row starts on.
+type UserImportError struct {
+	Line    int    `json:"line"`
+	Email   string `json:"email,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type UserImportResult struct {
+	UserImportProgress
+	DryRun bool              `json:"dry_run"`
+	Errors []UserImportError `json:"errors"`
+	// ErrorsTruncated is set when more rows failed than are listed
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
+
+// UserExportFields lists the fields an export may select, in default order
+var UserExportFields = []string{"id", "name", "email", "created_at", "locked_until", "password_changed_at"}
+
+// UserExportOptions controls an export. Fields defaults to every field.
+type UserExportOptions struct {
+	Format string
+	Fields []string
+}
+
+// UserExportRecord is one exported user. Password hashes are never exported.
+type UserExportRecord struct {
+	ID                int
+	Name              string
+	Email             string
+	CreatedAt         *time.Time
+	LockedUntil       *time.Time
+	PasswordChangedAt *time.Time
+}
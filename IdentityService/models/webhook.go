@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types published through the outbox
+const (
+	EventUserCreated             = "user.created"
+	EventUserDeleted             = "user.deleted"
+	EventUserEmailVerified       = "user.email_verified"
+	EventOrganizationMemberAdded = "organization.member_added"
+	EventSessionRevoked          = "session.revoked"
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+type WebhookEndpoint struct {
+	ID             int       `json:"id"`
+	OrganizationID int       `json:"organization_id"`
+	URL            string    `json:"url"`
+	EventTypes     []string  `json:"event_types"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type CreateWebhookEndpointRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types"`
+}
+
+// CreatedWebhookEndpoint is returned once at creation with the signing secret
+type CreatedWebhookEndpoint struct {
+	WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	EndpointID     int        `json:"endpoint_id"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type RetryWebhookDeliveryRequest struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// WebhookJob is a claimed delivery with everything needed to send it
+type WebhookJob struct {
+	DeliveryID     int64
+	Attempts       int
+	URL            string
+	Secret         string
+	EventID        int64
+	EventType      string
+	OrganizationID int
+	Payload        json.RawMessage
+	OccurredAt     time.Time
+}
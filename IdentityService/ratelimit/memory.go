@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often idle buckets are evicted
+const memorySweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore instance
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= memorySweepInterval {
+		s.sweep(now)
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = bucket
+	}
+
+	tokens, result := take(bucket.tokens, bucket.updated, limit, now)
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled, which are equivalent to new ones
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.full) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// Ensure MemoryStore implements Store interface
+var _ Store = (*MemoryStore)(nil)
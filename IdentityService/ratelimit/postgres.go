@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// postgresPruneInterval is how often refilled buckets are deleted
+const postgresPruneInterval = 5 * time.Minute
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// replica enforces the same limits
+type PostgresStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewPostgresStore creates a new PostgresStore instance
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.prune(ctx, now)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Will rollback if not committed
+
+	// Create the bucket full, then lock it so concurrent requests queue up
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (key) DO NOTHING`,
+		key, float64(limit.Requests), now,
+	); err != nil {
+		return Result{}, fmt.Errorf("failed to create rate limit bucket: %w", err)
+	}
+
+	var tokens float64
+	var updated time.Time
+	if err := tx.QueryRowContext(ctx,
+		"SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE",
+		key,
+	).Scan(&tokens, &updated); err != nil {
+		return Result{}, fmt.Errorf("failed to read rate limit bucket: %w", err)
+	}
+
+	tokens, result := take(tokens, updated, limit, now)
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1",
+		key, tokens, now, now.Add(result.Reset),
+	); err != nil {
+		return Result{}, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Result{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// prune deletes refilled buckets at most once per interval per replica
+func (s *PostgresStore) prune(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < postgresPruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	// Best effort: a failed prune only leaves stale rows behind
+	_, _ = s.db.ExecContext(ctx, "DELETE FROM rate_limit_buckets WHERE full_at <= $1", now)
+}
+
+// Ensure PostgresStore implements Store interface
+var _ Store = (*PostgresStore)(nil)
@@ -0,0 +1,55 @@
+// Package ratelimit implements token-bucket rate limiting over pluggable
+// stores so limits can be shared between replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit allows Requests per Window for a key, refilling continuously so a
+// client that waits Window/Requests earns one more request
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result describes the outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request would be allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store takes tokens from the bucket identified by key
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// take refills a bucket holding tokens since last and tries to spend one.
+// It returns the new token count and the result of the attempt.
+func take(tokens float64, last time.Time, limit Limit, now time.Time) (float64, Result) {
+	capacity := float64(limit.Requests)
+	perToken := limit.Window / time.Duration(limit.Requests)
+
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+float64(elapsed)/float64(perToken))
+	}
+
+	result := Result{Limit: limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((capacity - tokens) * float64(perToken))
+	return tokens, result
+}
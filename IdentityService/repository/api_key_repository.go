@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `
+	k.id, k.user_id, k.organization_id, k.name, k.prefix, k.scopes,
+	k.expires_at, k.last_used_at, k.last_used_ip, k.created_at
+`
+
+// apiKeyUsageInterval throttles last-used bookkeeping for busy keys
+const apiKeyUsageInterval = time.Minute
+
+type apiKeyRepository struct {
+	DB *database.Database
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository instance. Keys are
+// created in the caller's active organization under its row-level
+// security; a user's keys span organizations, so the rest bypass it.
+func NewAPIKeyRepository(db *database.Database) APIKeyRepository {
+	return &apiKeyRepository{DB: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey, keyHash string) (*models.APIKey, error) {
+	created := *key
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO api_keys (user_id, organization_id, name, prefix, key_hash, scopes, expires_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, key.UserID, key.OrganizationID, key.Name, key.Prefix, keyHash, pq.Array(key.Scopes), key.ExpiresAt, key.CreatedAt,
+		).Scan(&created.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *apiKeyRepository) GetForUser(ctx context.Context, userID int) ([]models.APIKey, error) {
+	keys := []models.APIKey{}
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+apiKeyColumns+`
+			FROM api_keys k
+			WHERE k.user_id = $1 AND k.revoked_at IS NULL
+			ORDER BY k.created_at DESC
+		`, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			key, err := scanAPIKey(rows)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, *key)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetByPrefix resolves a presented key before any tenant is known, so the
+// owner lookup runs outside row-level security.
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKeyCredential, error) {
+	var cred models.APIKeyCredential
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var orgID sql.NullInt64
+		var lastUsedAt, revokedAt sql.NullTime
+		var lastUsedIP sql.NullString
+
+		err := tx.QueryRowContext(ctx, `
+			SELECT k.id, k.user_id, k.organization_id, k.name, k.prefix, k.scopes,
+				k.expires_at, k.last_used_at, k.last_used_ip, k.created_at,
+				k.key_hash, k.revoked_at, u.email, u.disabled_at IS NOT NULL
+			FROM api_keys k
+			JOIN users u ON u.id = k.user_id
+			WHERE k.prefix = $1
+		`, prefix).Scan(
+			&cred.ID, &cred.UserID, &orgID, &cred.Name, &cred.Prefix, pq.Array(&cred.Scopes),
+			&cred.ExpiresAt, &lastUsedAt, &lastUsedIP, &cred.CreatedAt,
+			&cred.KeyHash, &revokedAt, &cred.UserEmail, &cred.UserDisabled,
+		)
+		if err != nil {
+			return err
+		}
+
+		if orgID.Valid {
+			id := int(orgID.Int64)
+			cred.OrganizationID = &id
+		}
+		if lastUsedAt.Valid {
+			cred.LastUsedAt = &lastUsedAt.Time
+		}
+		if lastUsedIP.Valid {
+			cred.LastUsedIP = &lastUsedIP.String
+		}
+		if revokedAt.Valid {
+			cred.RevokedAt = &revokedAt.Time
+		}
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &cred, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, userID, id int, now time.Time) (bool, error) {
+	var revoked bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE api_keys
+			SET revoked_at = $3
+			WHERE user_id = $1 AND id = $2 AND revoked_at IS NULL
+		`, userID, id, now)
+		if err != nil {
+			return err
+		}
+		revoked, err = rowsAffected(result)
+		return err
+	})
+	return revoked, err
+}
+
+func (r *apiKeyRepository) RecordUsage(ctx context.Context, id int, ip string, now time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE api_keys
+			SET last_used_at = $3, last_used_ip = $2
+			WHERE id = $1
+			AND (last_used_at IS NULL OR last_used_at < $4 OR last_used_ip IS DISTINCT FROM $2)
+		`, id, ip, now, now.Add(-apiKeyUsageInterval))
+		return err
+	})
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var orgID sql.NullInt64
+	var lastUsedAt sql.NullTime
+	var lastUsedIP sql.NullString
+
+	err := row.Scan(
+		&key.ID, &key.UserID, &orgID, &key.Name, &key.Prefix, pq.Array(&key.Scopes),
+		&key.ExpiresAt, &lastUsedAt, &lastUsedIP, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if orgID.Valid {
+		id := int(orgID.Int64)
+		key.OrganizationID = &id
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if lastUsedIP.Valid {
+		key.LastUsedIP = &lastUsedIP.String
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+)
+
+const attributeDefinitionColumns = `
+	id, organization_id, name, type, description, required, unique_values,
+	pattern, enum, claim, created_at, updated_at
+`
+
+type attributeRepository struct {
+	DB *database.Database
+}
+
+// NewAttributeRepository creates a new AttributeRepository instance.
+// Every query is scoped to a single organization and additionally runs
+// under the tenant's row-level security policies.
+func NewAttributeRepository(db *database.Database) AttributeRepository {
+	return &attributeRepository{DB: db}
+}
+
+// GetDefinitions returns the organization's attribute schema in name order
+func (r *attributeRepository) GetDefinitions(ctx context.Context, orgID int) ([]models.AttributeDefinition, error) {
+	defs := []models.AttributeDefinition{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+attributeDefinitionColumns+`
+			FROM user_attribute_definitions
+			WHERE organization_id = $1
+			ORDER BY name
+		`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			def, err := scanAttributeDefinition(rows)
+			if err != nil {
+				return err
+			}
+			defs = append(defs, *def)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+func (r *attributeRepository) CreateDefinition(ctx context.Context, def *models.AttributeDefinition) (*models.AttributeDefinition, error) {
+	enum, err := json.Marshal(attributeEnum(def.Enum))
+	if err != nil {
+		return nil, err
+	}
+
+	created := *def
+	err = r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO user_attribute_definitions (
+				organization_id, name, type, description, required, unique_values,
+				pattern, enum, claim, created_at, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+			RETURNING id
+		`,
+			def.OrganizationID, def.Name, def.Type, def.Description, def.Required, def.Unique,
+			def.Pattern, enum, def.Claim, def.CreatedAt,
+		).Scan(&created.ID)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created.UpdatedAt = created.CreatedAt
+	return &created, nil
+}
+
+// UpdateDefinition replaces a definition. Renaming an attribute moves the
+// stored values of every member to the new name.
+func (r *attributeRepository) UpdateDefinition(ctx context.Context, def *models.AttributeDefinition) (bool, error) {
+	enum, err := json.Marshal(attributeEnum(def.Enum))
+	if err != nil {
+		return false, err
+	}
+
+	var updated bool
+	err = r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		var previous string
+		err := tx.QueryRowContext(ctx, `
+			SELECT name FROM user_attribute_definitions
+			WHERE organization_id = $1 AND id = $2
+			FOR UPDATE
+		`, def.OrganizationID, def.ID).Scan(&previous)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE user_attribute_definitions SET
+				name = $3, type = $4, description = $5, required = $6, unique_values = $7,
+				pattern = $8, enum = $9, claim = $10, updated_at = $11
+			WHERE organization_id = $1 AND id = $2
+		`,
+			def.OrganizationID, def.ID, def.Name, def.Type, def.Description, def.Required, def.Unique,
+			def.Pattern, enum, def.Claim, def.UpdatedAt,
+		)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		updated = true
+
+		if previous == def.Name {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE organization_members
+			SET attributes = (attributes - $2::text) || jsonb_build_object($3::text, attributes -> $2::text)
+			WHERE organization_id = $1 AND attributes ? $2::text
+		`, def.OrganizationID, previous, def.Name)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+// DeleteDefinition removes a definition together with every member's value
+func (r *attributeRepository) DeleteDefinition(ctx context.Context, orgID, id int) (*models.AttributeDefinition, error) {
+	var def *models.AttributeDefinition
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		def, err = scanAttributeDefinition(tx.QueryRowContext(ctx, `
+			DELETE FROM user_attribute_definitions
+			WHERE organization_id = $1 AND id = $2
+			RETURNING `+attributeDefinitionColumns,
+			orgID, id,
+		))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE organization_members SET attributes = attributes - $2::text
+			WHERE organization_id = $1 AND attributes ? $2::text
+		`, orgID, def.Name)
+		return err
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return def, nil
+}
+
+func (r *attributeRepository) GetAttributes(ctx context.Context, orgID, userID int) (map[string]interface{}, error) {
+	var raw []byte
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx,
+			"SELECT attributes FROM organization_members WHERE organization_id = $1 AND user_id = $2",
+			orgID, userID,
+		).Scan(&raw)
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// SetAttributes replaces a member's attributes. Values of the attributes
+// named in unique must not be held by another member of the organization,
+// otherwise nothing is written and ErrConflict is returned. Writers are
+// serialized per organization so the check cannot race.
+func (r *attributeRepository) SetAttributes(ctx context.Context, orgID, userID int, attrs map[string]interface{}, unique []string, now time.Time) (bool, error) {
+	encoded, err := json.Marshal(attrs)
+	if err != nil {
+		return false, err
+	}
+
+	var updated bool
+	err = r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			"SELECT pg_advisory_xact_lock(hashtext('user_attributes'), $1)",
+			orgID,
+		); err != nil {
+			return err
+		}
+
+		for _, name := range unique {
+			value, ok := attrs[name]
+			if !ok || value == nil {
+				continue
+			}
+			encodedValue, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+
+			var taken bool
+			err = tx.QueryRowContext(ctx, `
+				SELECT EXISTS(
+					SELECT 1 FROM organization_members
+					WHERE organization_id = $1 AND user_id <> $2 AND attributes -> $3::text = $4::jsonb
+				)
+			`, orgID, userID, name, encodedValue).Scan(&taken)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return ErrConflict
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			UPDATE organization_members SET attributes = $3, updated_at = $4
+			WHERE organization_id = $1 AND user_id = $2
+		`, orgID, userID, encoded, now)
+		if err != nil {
+			return err
+		}
+		updated, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+func scanAttributeDefinition(row rowScanner) (*models.AttributeDefinition, error) {
+	var def models.AttributeDefinition
+	var enum []byte
+
+	err := row.Scan(
+		&def.ID, &def.OrganizationID, &def.Name, &def.Type, &def.Description, &def.Required, &def.Unique,
+		&def.Pattern, &enum, &def.Claim, &def.CreatedAt, &def.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(enum, &def.Enum); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// attributeEnum stores a missing enum as an empty list
+func attributeEnum(enum []interface{}) []interface{} {
+	if enum == nil {
+		return []interface{}{}
+	}
+	return enum
+}
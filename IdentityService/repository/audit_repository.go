@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"identity-service/database"
+	"identity-service/models"
+	"strings"
+)
+
+// AuditGenesisHash is the previous hash of the first event in the chain
+const AuditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+const auditColumns = `
+	id, occurred_at, action, outcome, actor_user_id, actor_api_key_id, organization_id,
+	target_type, target_id, ip, user_agent, request_id, details, prev_hash, hash
+`
+
+type auditRepository struct {
+	DB *database.Database
+}
+
+// NewAuditRepository creates a new AuditRepository instance. The hash
+// chain spans every organization, so appends and verification bypass
+// row-level security; queries for one organization run under it.
+func NewAuditRepository(db *database.Database) AuditRepository {
+	return &auditRepository{DB: db}
+}
+
+// Append links the event to the current end of the hash chain and stores it.
+// Appends are serialized so the chain never forks.
+func (r *auditRepository) Append(ctx context.Context, event *models.AuditEvent, seal func(*models.AuditEvent) (string, error)) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('audit_events'))"); err != nil {
+			return err
+		}
+
+		prevHash := AuditGenesisHash
+		err := tx.QueryRowContext(ctx, "SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		event.PrevHash = prevHash
+		event.Hash, err = seal(event)
+		if err != nil {
+			return err
+		}
+
+		details, err := json.Marshal(event.Details)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO audit_events (
+				occurred_at, action, outcome, actor_user_id, actor_api_key_id, organization_id,
+				target_type, target_id, ip, user_agent, request_id, details, prev_hash, hash
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+			RETURNING id
+		`,
+			event.OccurredAt, event.Action, event.Outcome, event.ActorUserID, event.ActorAPIKeyID, event.OrganizationID,
+			event.TargetType, event.TargetID, event.IP, event.UserAgent, event.RequestID, details, event.PrevHash, event.Hash,
+		).Scan(&event.ID)
+	})
+}
+
+func (r *auditRepository) Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.OrganizationID != 0 {
+		add("organization_id = $%d", filter.OrganizationID)
+	}
+	if filter.ActorUserID != 0 {
+		add("actor_user_id = $%d", filter.ActorUserID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		add("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		add("target_id = $%d", filter.TargetID)
+	}
+	if filter.Since != nil {
+		add("occurred_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		add("occurred_at < $%d", *filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		add("id < $%d", filter.BeforeID)
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	inTx := r.DB.BypassTx
+	if filter.OrganizationID != 0 {
+		inTx = r.DB.TenantTx
+	}
+	return r.queryEvents(ctx, inTx, query, args...)
+}
+
+func (r *auditRepository) GetAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEvent, error) {
+	return r.queryEvents(ctx, r.DB.BypassTx,
+		"SELECT "+auditColumns+" FROM audit_events WHERE id > $1 ORDER BY id LIMIT $2",
+		afterID, limit,
+	)
+}
+
+// GetHash returns the hash of one event, the checkpoint a resumed
+// verification continues from
+func (r *auditRepository) GetHash(ctx context.Context, id int64) (string, error) {
+	var hash string
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT hash FROM audit_events WHERE id = $1", id).Scan(&hash)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+func (r *auditRepository) queryEvents(ctx context.Context, inTx func(context.Context, func(*sql.Tx) error) error, query string, args ...interface{}) ([]models.AuditEvent, error) {
+	events := []models.AuditEvent{}
+	err := inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			event, err := scanAuditEvent(rows)
+			if err != nil {
+				return err
+			}
+			events = append(events, *event)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func scanAuditEvent(row rowScanner) (*models.AuditEvent, error) {
+	var event models.AuditEvent
+	var actorUserID, actorAPIKeyID, orgID sql.NullInt64
+	var details []byte
+
+	if err := row.Scan(
+		&event.ID, &event.OccurredAt, &event.Action, &event.Outcome, &actorUserID, &actorAPIKeyID, &orgID,
+		&event.TargetType, &event.TargetID, &event.IP, &event.UserAgent, &event.RequestID, &details,
+		&event.PrevHash, &event.Hash,
+	); err != nil {
+		return nil, err
+	}
+
+	event.ActorUserID = nullableInt(actorUserID)
+	event.ActorAPIKeyID = nullableInt(actorAPIKeyID)
+	event.OrganizationID = nullableInt(orgID)
+	if err := json.Unmarshal(details, &event.Details); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"strings"
+	"time"
+)
+
+type credentialRepository struct {
+	DB *database.Database
+}
+
+// NewCredentialRepository creates a new CredentialRepository instance.
+// Passwords are looked up before any tenant is known, so every query runs
+// outside row-level security.
+func NewCredentialRepository(db *database.Database) CredentialRepository {
+	return &credentialRepository{DB: db}
+}
+
+func (r *credentialRepository) GetByEmail(ctx context.Context, email string) (*models.PasswordCredential, error) {
+	return r.get(ctx, "u.email = $1", strings.ToLower(email))
+}
+
+func (r *credentialRepository) GetByUserID(ctx context.Context, userID int) (*models.PasswordCredential, error) {
+	return r.get(ctx, "u.id = $1", userID)
+}
+
+func (r *credentialRepository) get(ctx context.Context, where string, arg interface{}) (*models.PasswordCredential, error) {
+	var cred models.PasswordCredential
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var passwordHash sql.NullString
+		var changedAt, lockedUntil, disabledAt, lastFailedAt sql.NullTime
+
+		err := tx.QueryRowContext(ctx, `
+			SELECT u.id, u.name, u.email, u.password_hash, u.password_changed_at,
+				u.password_change_required, u.locked_until, u.disabled_at,
+				u.failed_login_count, u.last_failed_login_at
+			FROM users u
+			WHERE `+where,
+			arg,
+		).Scan(
+			&cred.UserID, &cred.Name, &cred.Email, &passwordHash, &changedAt,
+			&cred.PasswordChangeRequired, &lockedUntil, &disabledAt,
+			&cred.FailedLoginCount, &lastFailedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		cred.PasswordHash = passwordHash.String
+		if changedAt.Valid {
+			cred.PasswordChangedAt = &changedAt.Time
+		}
+		if lockedUntil.Valid {
+			cred.LockedUntil = &lockedUntil.Time
+		}
+		cred.DisabledAt = nullableTime(disabledAt)
+		cred.LastFailedLoginAt = nullableTime(lastFailedAt)
+		return nil
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &cred, nil
+}
+
+// GetHistory returns the user's most recent password hashes, newest first
+func (r *credentialRepository) GetHistory(ctx context.Context, userID, limit int) ([]string, error) {
+	var hashes []string
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT password_hash FROM password_history
+			WHERE user_id = $1
+			ORDER BY id DESC
+			LIMIT $2
+		`, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		hashes, err = scanStrings(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// SetPassword stores a new password hash, clears any pending change
+// requirement and records the hash in the history, keeping at most
+// keepHistory entries
+func (r *credentialRepository) SetPassword(ctx context.Context, userID int, hash string, now time.Time, keepHistory int) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users SET password_hash = $2, password_changed_at = $3, password_change_required = FALSE
+			WHERE id = $1
+		`, userID, hash, now)
+		if err != nil {
+			return err
+		}
+		updated, err := rowsAffected(result)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrNotFound
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, $3)",
+			userID, hash, now,
+		); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM password_history
+			WHERE user_id = $1 AND id NOT IN (
+				SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+			)
+		`, userID, keepHistory)
+		return err
+	})
+}
+
+// UpdatePasswordHash replaces a stored hash of the same password with a
+// stronger one, without counting as a password change. It does nothing if
+// the password was changed since oldHash was read.
+func (r *credentialRepository) UpdatePasswordHash(ctx context.Context, userID int, oldHash, newHash string) (bool, error) {
+	var updated bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2",
+			userID, oldHash, newHash,
+		)
+		if err != nil {
+			return err
+		}
+		if updated, err = rowsAffected(result); err != nil || !updated {
+			return err
+		}
+
+		// The weak copy in the history goes too
+		_, err = tx.ExecContext(ctx,
+			"UPDATE password_history SET password_hash = $3 WHERE user_id = $1 AND password_hash = $2",
+			userID, oldHash, newHash,
+		)
+		return err
+	})
+	return updated, err
+}
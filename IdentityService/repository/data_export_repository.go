@@ -0,0 +1,473 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type dataExportRepository struct {
+	DB *database.Database
+}
+
+// NewDataExportRepository creates a new DataExportRepository instance.
+// A person's data spans every organization they belong to, so reads run
+// outside row-level security and filter by user, and by organization when
+// an export is limited to one.
+func NewDataExportRepository(db *database.Database) DataExportRepository {
+	return &dataExportRepository{DB: db}
+}
+
+// Collect gathers everything stored about a user in one transaction. A
+// non-zero orgID limits the archive to that organization: sessions,
+// trusted devices, sign-in history, MFA factors and platform-wide roles
+// are left out, and a user who is not a member is reported as ErrNotFound.
+func (r *dataExportRepository) Collect(ctx context.Context, userID, orgID int) (*models.DataExport, error) {
+	export := &models.DataExport{
+		FormatVersion:    models.DataExportFormatVersion,
+		Memberships:      []models.DataExportMembership{},
+		PlatformRoles:    []string{},
+		Sessions:         []models.Session{},
+		SignIns:          []models.SignInEvent{},
+		TrustedDevices:   []models.TrustedDevice{},
+		APIKeys:          []models.APIKey{},
+		Invitations:      []models.Invitation{},
+		AuditEvents:      []models.AuditEvent{},
+		LinkedIdentities: []interface{}{},
+		MFAFactors:       []models.MFAFactor{},
+		Consents:         []interface{}{},
+		Exports:          []models.DataExportRecord{},
+	}
+	if orgID != 0 {
+		export.OrganizationID = &orgID
+	}
+
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		if err := exportProfile(ctx, tx, userID, orgID, &export.Profile); err != nil {
+			return err
+		}
+
+		var err error
+		if export.Memberships, err = exportMemberships(ctx, tx, userID, orgID); err != nil {
+			return err
+		}
+		if export.APIKeys, err = exportAPIKeys(ctx, tx, userID, orgID); err != nil {
+			return err
+		}
+		if export.Invitations, err = exportInvitations(ctx, tx, export.Profile.Email, orgID); err != nil {
+			return err
+		}
+		if export.AuditEvents, err = exportAuditEvents(ctx, tx, userID, orgID); err != nil {
+			return err
+		}
+		if export.Exports, err = getDataExports(ctx, tx, userID, orgID); err != nil {
+			return err
+		}
+		if orgID != 0 {
+			return nil
+		}
+
+		if export.PlatformRoles, err = exportPlatformRoles(ctx, tx, userID); err != nil {
+			return err
+		}
+		if export.MFAFactors, err = exportMFAFactors(ctx, tx, userID); err != nil {
+			return err
+		}
+		if export.SignIns, err = exportSignIns(ctx, tx, userID); err != nil {
+			return err
+		}
+		if export.TrustedDevices, err = exportTrustedDevices(ctx, tx, userID); err != nil {
+			return err
+		}
+		export.Sessions, err = exportSessions(ctx, tx, userID)
+		return err
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// Record notes that an export was produced
+func (r *dataExportRepository) Record(ctx context.Context, record *models.DataExportRecord) (*models.DataExportRecord, error) {
+	created := *record
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO data_exports (user_id, organization_id, requested_by, reason, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, record.UserID, record.OrganizationID, record.RequestedBy, record.Reason, record.CreatedAt).Scan(&created.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// GetRecords lists a user's exports, newest first. A non-zero orgID limits
+// the result to exports produced for that organization.
+func (r *dataExportRepository) GetRecords(ctx context.Context, userID, orgID int) ([]models.DataExportRecord, error) {
+	var records []models.DataExportRecord
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		records, err = getDataExports(ctx, tx, userID, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func exportProfile(ctx context.Context, tx *sql.Tx, userID, orgID int, profile *models.DataExportProfile) error {
+	var createdAt, passwordChangedAt, lockedUntil, lastFailedLoginAt sql.NullTime
+	var emailVerifiedAt, disabledAt sql.NullTime
+	var pendingEmail sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT u.id, u.name, u.email, COALESCE(u.display_name, ''), COALESCE(u.avatar_url, ''),
+			COALESCE(u.locale, ''), COALESCE(u.time_zone, ''), ec.new_email,
+			u.email_verified_at, u.disabled_at, u.created_at, u.password_hash IS NOT NULL,
+			u.password_changed_at, u.password_change_required,
+			u.locked_until, u.last_failed_login_at, u.failed_login_count
+		FROM users u
+		LEFT JOIN email_changes ec ON ec.user_id = u.id
+		WHERE u.id = $1
+			AND ($2 = 0 OR EXISTS (
+				SELECT 1 FROM organization_members om
+				WHERE om.user_id = u.id AND om.organization_id = $2
+			))
+	`, userID, orgID).Scan(
+		&profile.ID, &profile.Name, &profile.Email, &profile.DisplayName, &profile.AvatarURL,
+		&profile.Locale, &profile.TimeZone, &pendingEmail,
+		&emailVerifiedAt, &disabledAt, &createdAt, &profile.HasPassword,
+		&passwordChangedAt, &profile.PasswordChangeRequired,
+		&lockedUntil, &lastFailedLoginAt, &profile.FailedLoginCount,
+	)
+	if err != nil {
+		return err
+	}
+
+	profile.EmailVerifiedAt = nullableTime(emailVerifiedAt)
+	profile.DisabledAt = nullableTime(disabledAt)
+	profile.CreatedAt = nullableTime(createdAt)
+	profile.PasswordChangedAt = nullableTime(passwordChangedAt)
+	profile.LockedUntil = nullableTime(lockedUntil)
+	profile.LastFailedLoginAt = nullableTime(lastFailedLoginAt)
+	if pendingEmail.Valid {
+		profile.PendingEmail = &pendingEmail.String
+	}
+
+	// Dates of earlier passwords only, never the hashes themselves
+	rows, err := tx.QueryContext(ctx,
+		"SELECT created_at FROM password_history WHERE user_id = $1 ORDER BY id DESC",
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	profile.PasswordHistory = []time.Time{}
+	for rows.Next() {
+		var changedAt time.Time
+		if err := rows.Scan(&changedAt); err != nil {
+			return err
+		}
+		profile.PasswordHistory = append(profile.PasswordHistory, changedAt)
+	}
+
+	return rows.Err()
+}
+
+func exportMemberships(ctx context.Context, tx *sql.Tx, userID, orgID int) ([]models.DataExportMembership, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT o.id, o.name, om.active, COALESCE(om.external_id, ''), om.created_at, om.attributes,
+			ARRAY(
+				SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+				WHERE ur.user_id = om.user_id AND ur.organization_id = om.organization_id
+				ORDER BY r.name
+			),
+			ARRAY(
+				SELECT g.display_name FROM group_members gm JOIN groups g ON g.id = gm.group_id
+				WHERE gm.user_id = om.user_id AND gm.organization_id = om.organization_id
+				ORDER BY g.display_name
+			)
+		FROM organization_members om
+		JOIN organizations o ON o.id = om.organization_id
+		WHERE om.user_id = $1 AND ($2 = 0 OR om.organization_id = $2)
+		ORDER BY o.id
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []models.DataExportMembership{}
+	for rows.Next() {
+		var membership models.DataExportMembership
+		var joinedAt sql.NullTime
+		var attributes []byte
+		if err := rows.Scan(
+			&membership.OrganizationID, &membership.OrganizationName, &membership.Active,
+			&membership.ExternalID, &joinedAt, &attributes, pq.Array(&membership.Roles), pq.Array(&membership.Groups),
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attributes, &membership.Attributes); err != nil {
+			return nil, err
+		}
+		membership.JoinedAt = nullableTime(joinedAt)
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, rows.Err()
+}
+
+func exportPlatformRoles(ctx context.Context, tx *sql.Tx, userID int) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1 AND ur.organization_id IS NULL
+		ORDER BY r.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func exportSessions(ctx context.Context, tx *sql.Tx, userID int) ([]models.Session, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, user_id, created_at, expires_at, last_used_at, COALESCE(ip, ''), COALESCE(user_agent, ''),
+			amr, auth_time
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(
+			&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt, &lastUsedAt,
+			&session.IP, &session.UserAgent, pq.Array(&session.AMR), &session.AuthTime,
+		); err != nil {
+			return nil, err
+		}
+		session.LastUsedAt = nullableTime(lastUsedAt)
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func exportSignIns(ctx context.Context, tx *sql.Tx, userID int) ([]models.SignInEvent, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+signInEventColumns+`
+		FROM sign_in_events
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.SignInEvent{}
+	for rows.Next() {
+		event, err := scanSignInEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+func exportTrustedDevices(ctx context.Context, tx *sql.Tx, userID int) ([]models.TrustedDevice, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+trustedDeviceColumns+`
+		FROM trusted_devices
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.TrustedDevice{}
+	for rows.Next() {
+		device, err := scanTrustedDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	return devices, rows.Err()
+}
+
+// exportMFAFactors includes factors that were never verified
+func exportMFAFactors(ctx context.Context, tx *sql.Tx, userID int) ([]models.MFAFactor, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+mfaFactorColumns+`
+		FROM mfa_factors
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	factors := []models.MFAFactor{}
+	for rows.Next() {
+		factor, err := scanMFAFactor(rows)
+		if err != nil {
+			return nil, err
+		}
+		factors = append(factors, *factor)
+	}
+
+	return factors, rows.Err()
+}
+
+// exportAPIKeys includes revoked keys; within one organization only the
+// keys bound to it are exported
+func exportAPIKeys(ctx context.Context, tx *sql.Tx, userID, orgID int) ([]models.APIKey, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys k
+		WHERE k.user_id = $1 AND ($2 = 0 OR k.organization_id = $2)
+		ORDER BY k.id
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+func exportInvitations(ctx context.Context, tx *sql.Tx, email string, orgID int) ([]models.Invitation, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+invitationColumns+`
+		FROM organization_invitations i
+		JOIN roles r ON r.id = i.role_id
+		WHERE lower(i.email) = lower($1) AND ($2 = 0 OR i.organization_id = $2)
+		ORDER BY i.id
+	`, email, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+// exportAuditEvents returns the events the user performed or was the
+// target of, oldest first
+func exportAuditEvents(ctx context.Context, tx *sql.Tx, userID, orgID int) ([]models.AuditEvent, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+auditColumns+`
+		FROM audit_events
+		WHERE (actor_user_id = $1 OR (target_type = 'user' AND target_id = $2))
+			AND ($3 = 0 OR organization_id = $3)
+		ORDER BY id
+	`, userID, strconv.Itoa(userID), orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+func getDataExports(ctx context.Context, tx *sql.Tx, userID, orgID int) ([]models.DataExportRecord, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, user_id, organization_id, requested_by, reason, created_at
+		FROM data_exports
+		WHERE user_id = $1 AND ($2 = 0 OR organization_id = $2)
+		ORDER BY created_at DESC, id DESC
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.DataExportRecord{}
+	for rows.Next() {
+		var record models.DataExportRecord
+		var orgID, requestedBy sql.NullInt64
+		if err := rows.Scan(
+			&record.ID, &record.UserID, &orgID, &requestedBy, &record.Reason, &record.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		record.OrganizationID = nullableInt(orgID)
+		record.RequestedBy = nullableInt(requestedBy)
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ErrNotFound is returned when a lookup matches no rows
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a write would break a uniqueness constraint
+var ErrConflict = errors.New("record already exists")
+
+// ErrInvalidReference is returned when a write refers to a record that does
+// not exist or is out of reach, such as a group member from another tenant
+var ErrInvalidReference = errors.New("referenced record does not exist")
+
+// isUniqueViolation reports whether err is a Postgres unique_violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+
+	"github.com/lib/pq"
+)
+
+type groupRepository struct {
+	DB *database.Database
+}
+
+// NewGroupRepository creates a new GroupRepository instance.
+// Every query is scoped to a single organization and additionally runs
+// under the tenant's row-level security policies.
+func NewGroupRepository(db *database.Database) GroupRepository {
+	return &groupRepository{DB: db}
+}
+
+// GetAll returns the organization's groups with their members, in id
+// order. The match narrows the result in the database.
+func (r *groupRepository) GetAll(ctx context.Context, orgID int, match models.GroupMatch) ([]models.Group, error) {
+	groups := []models.Group{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, organization_id, display_name, COALESCE(external_id, ''), created_at, updated_at
+			FROM groups
+			WHERE organization_id = $1
+				AND ($2 = '' OR lower(display_name) = lower($2))
+				AND ($3 = '' OR external_id = $3)
+			ORDER BY id
+		`, orgID, match.DisplayName, match.ExternalID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			group, err := scanGroup(rows)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, *group)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(groups))
+		for i := range groups {
+			ids[i] = int64(groups[i].ID)
+		}
+		members, err := groupMembers(ctx, tx, orgID, ids)
+		if err != nil {
+			return err
+		}
+		for i := range groups {
+			groups[i].Members = append([]models.GroupMember{}, members[groups[i].ID]...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+func (r *groupRepository) GetByID(ctx context.Context, orgID, id int) (*models.Group, error) {
+	var group *models.Group
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if group, err = getGroup(ctx, tx, orgID, id); err != nil {
+			return err
+		}
+
+		members, err := groupMembers(ctx, tx, orgID, []int64{int64(id)})
+		if err != nil {
+			return err
+		}
+		group.Members = append([]models.GroupMember{}, members[id]...)
+		return nil
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// Create inserts a group with its members. Every member must belong to the
+// organization, otherwise nothing is written and ErrInvalidReference is
+// returned.
+func (r *groupRepository) Create(ctx context.Context, group *models.Group) (*models.Group, error) {
+	created := *group
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO groups (organization_id, display_name, external_id, created_at, updated_at)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $4)
+			RETURNING id
+		`, group.OrganizationID, group.DisplayName, group.ExternalID, group.CreatedAt).Scan(&created.ID)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		return setGroupMembers(ctx, tx, group.OrganizationID, created.ID, group.Members)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(ctx, group.OrganizationID, created.ID)
+}
+
+// Update replaces a group's name, external id and members, with the same
+// member rule as Create. It reports false when the group does not exist.
+func (r *groupRepository) Update(ctx context.Context, group *models.Group) (bool, error) {
+	var updated bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE groups SET display_name = $3, external_id = NULLIF($4, ''), updated_at = $5
+			WHERE organization_id = $1 AND id = $2
+		`, group.OrganizationID, group.ID, group.DisplayName, group.ExternalID, group.UpdatedAt)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		if updated, err = rowsAffected(result); err != nil || !updated {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM group_members WHERE organization_id = $1 AND group_id = $2",
+			group.OrganizationID, group.ID,
+		); err != nil {
+			return err
+		}
+		return setGroupMembers(ctx, tx, group.OrganizationID, group.ID, group.Members)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+func (r *groupRepository) Delete(ctx context.Context, orgID, id int) (bool, error) {
+	var deleted bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM groups WHERE organization_id = $1 AND id = $2", orgID, id)
+		if err != nil {
+			return err
+		}
+		deleted, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+func getGroup(ctx context.Context, tx *sql.Tx, orgID, id int) (*models.Group, error) {
+	return scanGroup(tx.QueryRowContext(ctx, `
+		SELECT id, organization_id, display_name, COALESCE(external_id, ''), created_at, updated_at
+		FROM groups
+		WHERE organization_id = $1 AND id = $2
+	`, orgID, id))
+}
+
+func scanGroup(row rowScanner) (*models.Group, error) {
+	var group models.Group
+	err := row.Scan(&group.ID, &group.OrganizationID, &group.DisplayName, &group.ExternalID, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// groupMembers loads the members of the given groups keyed by group id
+func groupMembers(ctx context.Context, tx *sql.Tx, orgID int, groupIDs []int64) (map[int][]models.GroupMember, error) {
+	members := make(map[int][]models.GroupMember, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return members, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT gm.group_id, u.id, u.name, u.email
+		FROM group_members gm
+		JOIN users u ON u.id = gm.user_id
+		WHERE gm.organization_id = $1 AND gm.group_id = ANY($2)
+		ORDER BY gm.group_id, u.id
+	`, orgID, pq.Array(groupIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupID int
+		var member models.GroupMember
+		if err := rows.Scan(&groupID, &member.UserID, &member.Name, &member.Email); err != nil {
+			return nil, err
+		}
+		members[groupID] = append(members[groupID], member)
+	}
+
+	return members, rows.Err()
+}
+
+// setGroupMembers adds members to a group, failing with ErrInvalidReference
+// if any of them is not a member of the organization
+func setGroupMembers(ctx context.Context, tx *sql.Tx, orgID, groupID int, members []models.GroupMember) error {
+	seen := make(map[int]bool, len(members))
+	userIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		if !seen[member.UserID] {
+			seen[member.UserID] = true
+			userIDs = append(userIDs, int64(member.UserID))
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO group_members (group_id, organization_id, user_id)
+		SELECT $2, om.organization_id, om.user_id
+		FROM organization_members om
+		WHERE om.organization_id = $1 AND om.user_id = ANY($3)
+	`, orgID, groupID, pq.Array(userIDs))
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(userIDs) {
+		return ErrInvalidReference
+	}
+	return nil
+}
@@ -3,11 +3,219 @@ package repository
 import (
 	"context"
 	"identity-service/models"
+	"time"
 )
 
-// UserRepository defines the interface for user data operations
+// UserRepository defines the interface for tenant-scoped user data operations
 type UserRepository interface {
-	GetAll(ctx context.Context) ([]models.User, error)
-	Create(ctx context.Context, name, email string) (*models.User, error)
+	GetAll(ctx context.Context, orgID int, attributes map[string]interface{}) ([]models.User, error)
+	GetByID(ctx context.Context, orgID, id int) (*models.User, error)
+	Create(ctx context.Context, orgID int, name, email, passwordHash, defaultRole string, now time.Time) (*models.User, error)
+	Update(ctx context.Context, orgID, id int, name, email string, now time.Time) (bool, error)
+	SetActive(ctx context.Context, orgID, id int, active bool, now time.Time) (bool, error)
+	SetDisabled(ctx context.Context, orgID, id int, disabled bool, now time.Time) (bool, error)
+	RequirePasswordChange(ctx context.Context, orgID, id int) (bool, error)
+	RemoveMember(ctx context.Context, orgID, id int) (bool, error)
 	EmailExists(ctx context.Context, email string) (bool, error)
+	MemberElsewhere(ctx context.Context, orgID, id int) (bool, error)
+	ImportBatch(ctx context.Context, orgID int, entries []models.UserImportEntry, now time.Time, keepHistory int, dryRun bool) ([]models.UserImportOutcome, error)
+	Export(ctx context.Context, orgID int, fn func(*models.UserExportRecord) error) error
+}
+
+// ProfileRepository defines the interface for a user's own profile, email
+// changes awaiting verification and verification of the current address
+type ProfileRepository interface {
+	GetProfile(ctx context.Context, userID int, now time.Time) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, profile *models.Profile, now time.Time) (bool, error)
+	CreateEmailChange(ctx context.Context, change *models.EmailChange, tokenHash string) (*models.EmailChange, error)
+	CompleteEmailChange(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, string, error)
+	DeleteEmailChange(ctx context.Context, userID int) (bool, error)
+	CreateEmailVerification(ctx context.Context, verification *models.EmailVerification, tokenHash string) (*models.EmailVerification, error)
+	CompleteEmailVerification(ctx context.Context, tokenHash string, now time.Time) (*models.EmailVerification, error)
+	DeleteAccount(ctx context.Context, userID int) (bool, error)
+}
+
+// RoleRepository defines the interface for role and permission data operations.
+// An orgID of zero refers to platform-wide role grants.
+type RoleRepository interface {
+	GetAll(ctx context.Context) ([]models.Role, error)
+	GetRoleNamesForUser(ctx context.Context, orgID, userID int) ([]string, error)
+	GetPermissionsForUser(ctx context.Context, orgID, userID int) ([]string, error)
+	AssignRole(ctx context.Context, orgID, userID int, roleName string) (bool, error)
+	RemoveRole(ctx context.Context, orgID, userID int, roleName string) (bool, error)
+	RoleExists(ctx context.Context, roleName string) (bool, error)
+}
+
+// OrganizationRepository defines the interface for organization data operations
+type OrganizationRepository interface {
+	Create(ctx context.Context, name, slug string, ownerID int, ownerRole string) (*models.Organization, error)
+	GetByID(ctx context.Context, id int) (*models.Organization, error)
+	GetForUser(ctx context.Context, userID int) ([]models.Organization, error)
+	SlugExists(ctx context.Context, slug string) (bool, error)
+	IsMember(ctx context.Context, orgID, userID int) (bool, error)
+	GetPasswordPolicy(ctx context.Context, orgID int) (*models.PasswordPolicy, error)
+	SetPasswordPolicy(ctx context.Context, orgID int, policy models.PasswordPolicy) error
+	GetPasswordPoliciesForUser(ctx context.Context, userID int) ([]models.PasswordPolicy, error)
+}
+
+// InvitationRepository defines the interface for organization invitation data operations
+type InvitationRepository interface {
+	Create(ctx context.Context, inv *models.Invitation, tokenHash string) (*models.Invitation, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+	GetForOrganization(ctx context.Context, orgID int) ([]models.Invitation, error)
+	PendingExists(ctx context.Context, orgID int, email string, now time.Time) (bool, error)
+	UpdateStatus(ctx context.Context, orgID, id int, status string, now time.Time) (bool, error)
+	Accept(ctx context.Context, id, userID int, now time.Time) error
+}
+
+// APIKeyRepository defines the interface for personal API key data operations
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey, keyHash string) (*models.APIKey, error)
+	GetForUser(ctx context.Context, userID int) ([]models.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKeyCredential, error)
+	Revoke(ctx context.Context, userID, id int, now time.Time) (bool, error)
+	RecordUsage(ctx context.Context, id int, ip string, now time.Time) error
+}
+
+// SCIMRepository defines the interface for SCIM provisioning tokens and the
+// provisioning attributes of organization members
+type SCIMRepository interface {
+	CreateToken(ctx context.Context, token *models.SCIMToken, tokenHash string) (*models.SCIMToken, error)
+	GetTokens(ctx context.Context, orgID int) ([]models.SCIMToken, error)
+	GetTokenByHash(ctx context.Context, tokenHash string) (*models.SCIMTokenCredential, error)
+	RevokeToken(ctx context.Context, orgID, id int, now time.Time) (bool, error)
+	RecordTokenUsage(ctx context.Context, id int, now time.Time) error
+	GetMembers(ctx context.Context, orgID int, match models.SCIMMemberMatch) ([]models.SCIMMember, error)
+	GetMember(ctx context.Context, orgID, userID int) (*models.SCIMMember, error)
+	SetExternalID(ctx context.Context, orgID, userID int, externalID string, now time.Time) (bool, error)
+}
+
+// OAuthRepository defines the interface for OAuth clients and the tokens
+// they obtain through token exchange
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient, secretHash string) (*models.OAuthClient, error)
+	GetClients(ctx context.Context, orgID int) ([]models.OAuthClient, error)
+	GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClientCredential, error)
+	RevokeClient(ctx context.Context, orgID, id int, now time.Time) (bool, error)
+	RecordClientUsage(ctx context.Context, id int, now time.Time) error
+	CreateAccessToken(ctx context.Context, token *models.AccessToken, tokenHash string) (*models.AccessToken, error)
+	GetAccessTokenByHash(ctx context.Context, tokenHash string) (*models.AccessTokenCredential, error)
+}
+
+// GroupRepository defines the interface for tenant-scoped group data operations
+type GroupRepository interface {
+	GetAll(ctx context.Context, orgID int, match models.GroupMatch) ([]models.Group, error)
+	GetByID(ctx context.Context, orgID, id int) (*models.Group, error)
+	Create(ctx context.Context, group *models.Group) (*models.Group, error)
+	Update(ctx context.Context, group *models.Group) (bool, error)
+	Delete(ctx context.Context, orgID, id int) (bool, error)
+}
+
+// DataExportRepository defines the interface for data subject archives and
+// the record of when they were produced
+type DataExportRepository interface {
+	Collect(ctx context.Context, userID, orgID int) (*models.DataExport, error)
+	Record(ctx context.Context, record *models.DataExportRecord) (*models.DataExportRecord, error)
+	GetRecords(ctx context.Context, userID, orgID int) ([]models.DataExportRecord, error)
+}
+
+// AttributeRepository defines the interface for an organization's custom
+// user attribute schema and its members' values
+type AttributeRepository interface {
+	GetDefinitions(ctx context.Context, orgID int) ([]models.AttributeDefinition, error)
+	CreateDefinition(ctx context.Context, def *models.AttributeDefinition) (*models.AttributeDefinition, error)
+	UpdateDefinition(ctx context.Context, def *models.AttributeDefinition) (bool, error)
+	DeleteDefinition(ctx context.Context, orgID, id int) (*models.AttributeDefinition, error)
+	GetAttributes(ctx context.Context, orgID, userID int) (map[string]interface{}, error)
+	SetAttributes(ctx context.Context, orgID, userID int, attrs map[string]interface{}, unique []string, now time.Time) (bool, error)
+}
+
+// AuditRepository defines the interface for the append-only audit log
+type AuditRepository interface {
+	Append(ctx context.Context, event *models.AuditEvent, seal func(*models.AuditEvent) (string, error)) error
+	Query(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, error)
+	GetAfter(ctx context.Context, afterID int64, limit int) ([]models.AuditEvent, error)
+	GetHash(ctx context.Context, id int64) (string, error)
+}
+
+// WebhookRepository defines the interface for webhook endpoints, deliveries and outbox dispatch
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint, secret string) (*models.WebhookEndpoint, error)
+	GetEndpoints(ctx context.Context, orgID int) ([]models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, orgID, id int) (bool, error)
+	GetDeliveries(ctx context.Context, orgID int, status string, limit int) ([]models.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, orgID int, id int64, now time.Time) (bool, error)
+	FanOut(ctx context.Context, now time.Time, limit int) (int, error)
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookJob, error)
+	MarkSucceeded(ctx context.Context, id int64, statusCode int, now time.Time) error
+	MarkFailed(ctx context.Context, id int64, statusCode int, lastError string, nextAttempt *time.Time) error
+}
+
+// LockoutRepository defines the interface for failed sign-in tracking per account and per IP
+type LockoutRepository interface {
+	RecordUserFailure(ctx context.Context, userID int, now time.Time, window time.Duration) (int, string, error)
+	LockUser(ctx context.Context, userID int, until time.Time) error
+	ResetUser(ctx context.Context, userID int) (bool, error)
+	RecordIPFailure(ctx context.Context, ip string, now time.Time, window time.Duration) (int, error)
+	LockIP(ctx context.Context, ip string, until time.Time) error
+	IPLockedUntil(ctx context.Context, ip string) (*time.Time, error)
+	PruneIPFailures(ctx context.Context, cutoff time.Time) error
+}
+
+// CredentialRepository defines the interface for password credentials and history
+type CredentialRepository interface {
+	GetByEmail(ctx context.Context, email string) (*models.PasswordCredential, error)
+	GetByUserID(ctx context.Context, userID int) (*models.PasswordCredential, error)
+	GetHistory(ctx context.Context, userID, limit int) ([]string, error)
+	SetPassword(ctx context.Context, userID int, hash string, now time.Time, keepHistory int) error
+	UpdatePasswordHash(ctx context.Context, userID int, oldHash, newHash string) (bool, error)
+}
+
+// SessionRepository defines the interface for sign-in session data operations
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session, tokenHash string) (*models.Session, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.SessionCredential, error)
+	GetForUser(ctx context.Context, userID int, now time.Time) ([]models.Session, error)
+	StepUp(ctx context.Context, id int64, amr []string, authTime time.Time) (bool, error)
+	RecordUsage(ctx context.Context, id int64, ip string, now time.Time) error
+	Revoke(ctx context.Context, userID int, id int64, now time.Time) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID int, keepID int64, now time.Time) (int, error)
+}
+
+// TrustedDeviceRepository defines the interface for devices that skip the
+// second factor at sign-in
+type TrustedDeviceRepository interface {
+	Create(ctx context.Context, device *models.TrustedDevice, tokenHash string) (*models.TrustedDevice, error)
+	Use(ctx context.Context, userID int, tokenHash, ip string, since, now time.Time) (*models.TrustedDevice, error)
+	GetForUser(ctx context.Context, userID int, now time.Time) ([]models.TrustedDevice, error)
+	Revoke(ctx context.Context, userID int, id int64, now time.Time) (bool, error)
+}
+
+// MagicLinkRepository defines the interface for outstanding passwordless
+// sign-in links
+type MagicLinkRepository interface {
+	Create(ctx context.Context, link *models.MagicLink, tokenHash, deviceHash string) (*models.MagicLink, error)
+	Redeem(ctx context.Context, tokenHash, deviceHash string, now time.Time) (*models.MagicLink, error)
+}
+
+// MFARepository defines the interface for one-time passcode factors and
+// the challenges codes are sent for
+type MFARepository interface {
+	CreateFactor(ctx context.Context, factor *models.MFAFactor) (*models.MFAFactor, error)
+	GetFactors(ctx context.Context, userID int) ([]models.MFAFactor, error)
+	VerifyFactor(ctx context.Context, userID, id int, now time.Time) (*models.MFAFactor, error)
+	DeleteFactor(ctx context.Context, userID, id int) (bool, error)
+	RecordFactorUsage(ctx context.Context, id int, now time.Time) error
+	CreateChallenge(ctx context.Context, challenge *models.OTPChallenge, tokenHash string) (*models.OTPChallenge, error)
+	GetChallenge(ctx context.Context, tokenHash string) (*models.OTPChallenge, error)
+	SetCode(ctx context.Context, id int, factorID *int, channel, codeHash string, now, sentBefore time.Time, maxSends int) (bool, error)
+	RecordAttempt(ctx context.Context, id, maxAttempts int) (bool, error)
+	DeleteChallenge(ctx context.Context, id int) (bool, error)
+}
+
+// RiskRepository defines the interface for the sign-in history that risk
+// scoring compares new sign-ins with
+type RiskRepository interface {
+	GetHistory(ctx context.Context, userID int, ip, userAgent string, asn int, since, failuresSince time.Time) (*models.SignInHistory, error)
+	RecordSignIn(ctx context.Context, event *models.SignInEvent, pruneBefore time.Time) (*models.SignInEvent, error)
 }
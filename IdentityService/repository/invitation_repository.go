@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+)
+
+const invitationColumns = `
+	i.id, i.organization_id, i.email, r.name, i.status,
+	i.invited_by, i.expires_at, i.responded_at, i.created_at
+`
+
+type invitationRepository struct {
+	DB *database.Database
+}
+
+// NewInvitationRepository creates a new InvitationRepository instance.
+// Queries run under the tenant in the context except GetByTokenHash.
+func NewInvitationRepository(db *database.Database) InvitationRepository {
+	return &invitationRepository{DB: db}
+}
+
+func (r *invitationRepository) Create(ctx context.Context, inv *models.Invitation, tokenHash string) (*models.Invitation, error) {
+	created := *inv
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		// Free the pending slot held by an invitation that has already lapsed
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE organization_invitations
+			SET status = 'expired'
+			WHERE organization_id = $1 AND email = $2 AND status = 'pending' AND expires_at <= $3
+		`, inv.OrganizationID, inv.Email, inv.CreatedAt); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO organization_invitations
+				(organization_id, email, role_id, token_hash, status, invited_by, expires_at, created_at)
+			SELECT $1::int, $2::text, id, $4::text, 'pending', $5::int, $6::timestamp, $7::timestamp
+			FROM roles WHERE name = $3
+			RETURNING id
+		`, inv.OrganizationID, inv.Email, inv.Role, tokenHash, inv.InvitedBy, inv.ExpiresAt, inv.CreatedAt,
+		).Scan(&created.ID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	created.Status = models.InvitationPending
+	return &created, nil
+}
+
+// GetByTokenHash looks an invitation up by its secret token. The token holder
+// has no tenant yet, so this single-row lookup runs outside RLS.
+func (r *invitationRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	var inv *models.Invitation
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, `
+			SELECT `+invitationColumns+`
+			FROM organization_invitations i
+			JOIN roles r ON r.id = i.role_id
+			WHERE i.token_hash = $1
+		`, tokenHash)
+
+		var err error
+		inv, err = scanInvitation(row)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+func (r *invitationRepository) GetForOrganization(ctx context.Context, orgID int) ([]models.Invitation, error) {
+	invitations := []models.Invitation{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+invitationColumns+`
+			FROM organization_invitations i
+			JOIN roles r ON r.id = i.role_id
+			WHERE i.organization_id = $1
+			ORDER BY i.created_at DESC
+		`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			inv, err := scanInvitation(rows)
+			if err != nil {
+				return err
+			}
+			invitations = append(invitations, *inv)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (r *invitationRepository) PendingExists(ctx context.Context, orgID int, email string, now time.Time) (bool, error) {
+	var exists bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM organization_invitations
+				WHERE organization_id = $1 AND email = $2 AND status = 'pending' AND expires_at > $3
+			)
+		`, orgID, email, now).Scan(&exists)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *invitationRepository) UpdateStatus(ctx context.Context, orgID, id int, status string, now time.Time) (bool, error) {
+	var updated bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE organization_invitations
+			SET status = $3, responded_at = $4
+			WHERE organization_id = $1 AND id = $2 AND status = 'pending'
+		`, orgID, id, status, now)
+		if err != nil {
+			return err
+		}
+
+		updated, err = rowsAffected(result)
+		return err
+	})
+
+	return updated, err
+}
+
+func (r *invitationRepository) Accept(ctx context.Context, id, userID int, now time.Time) error {
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		var orgID, roleID int
+		err := tx.QueryRowContext(ctx, `
+			UPDATE organization_invitations
+			SET status = 'accepted', responded_at = $2
+			WHERE id = $1 AND status = 'pending'
+			RETURNING organization_id, role_id
+		`, id, now).Scan(&orgID, &roleID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO organization_members (organization_id, user_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, orgID, userID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_roles (user_id, role_id, organization_id)
+			VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+		`, userID, roleID, orgID); err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, models.EventOrganizationMemberAdded, orgID, map[string]int{
+			"organization_id": orgID,
+			"user_id":         userID,
+			"invitation_id":   id,
+		})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	var inv models.Invitation
+	var invitedBy sql.NullInt64
+	var respondedAt sql.NullTime
+
+	err := row.Scan(
+		&inv.ID, &inv.OrganizationID, &inv.Email, &inv.Role, &inv.Status,
+		&invitedBy, &inv.ExpiresAt, &respondedAt, &inv.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitedBy.Valid {
+		id := int(invitedBy.Int64)
+		inv.InvitedBy = &id
+	}
+	if respondedAt.Valid {
+		inv.RespondedAt = &respondedAt.Time
+	}
+
+	return &inv, nil
+}
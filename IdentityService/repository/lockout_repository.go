@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"time"
+)
+
+type lockoutRepository struct {
+	DB *database.Database
+}
+
+// NewLockoutRepository creates a new LockoutRepository instance.
+// Failures are recorded before any tenant is known, so every query runs
+// outside row-level security.
+func NewLockoutRepository(db *database.Database) LockoutRepository {
+	return &lockoutRepository{DB: db}
+}
+
+// RecordUserFailure counts a failed attempt, restarting the count when the
+// previous failure is older than window. It returns the new count and the
+// user's email for notifications.
+func (r *lockoutRepository) RecordUserFailure(ctx context.Context, userID int, now time.Time, window time.Duration) (int, string, error) {
+	var failures int
+	var email string
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			UPDATE users SET
+				failed_login_count = CASE
+					WHEN last_failed_login_at IS NULL OR last_failed_login_at < $3::timestamp THEN 1
+					ELSE failed_login_count + 1
+				END,
+				last_failed_login_at = $2
+			WHERE id = $1
+			RETURNING failed_login_count, email
+		`, userID, now, now.Add(-window)).Scan(&failures, &email)
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrNotFound
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	return failures, email, nil
+}
+
+func (r *lockoutRepository) LockUser(ctx context.Context, userID int, until time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET locked_until = $2 WHERE id = $1", userID, until)
+		return err
+	})
+}
+
+// ResetUser clears the failure count and any lock. It reports whether the
+// user had anything to clear.
+func (r *lockoutRepository) ResetUser(ctx context.Context, userID int) (bool, error) {
+	var reset bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users SET failed_login_count = 0, last_failed_login_at = NULL, locked_until = NULL
+			WHERE id = $1 AND (failed_login_count > 0 OR locked_until IS NOT NULL)
+		`, userID)
+		if err != nil {
+			return err
+		}
+		reset, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return reset, nil
+}
+
+// RecordIPFailure counts a failed attempt from ip within a fixed window
+func (r *lockoutRepository) RecordIPFailure(ctx context.Context, ip string, now time.Time, window time.Duration) (int, error) {
+	var failures int
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO ip_auth_failures (ip, failures, window_started_at)
+			VALUES ($1, 1, $2)
+			ON CONFLICT (ip) DO UPDATE SET
+				failures = CASE
+					WHEN ip_auth_failures.window_started_at < $3::timestamp THEN 1
+					ELSE ip_auth_failures.failures + 1
+				END,
+				window_started_at = CASE
+					WHEN ip_auth_failures.window_started_at < $3::timestamp THEN $2
+					ELSE ip_auth_failures.window_started_at
+				END
+			RETURNING failures
+		`, ip, now, now.Add(-window)).Scan(&failures)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+func (r *lockoutRepository) LockIP(ctx context.Context, ip string, until time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE ip_auth_failures SET locked_until = $2 WHERE ip = $1", ip, until)
+		return err
+	})
+}
+
+// IPLockedUntil returns when the lock on ip expires, or nil if it has none
+func (r *lockoutRepository) IPLockedUntil(ctx context.Context, ip string) (*time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx,
+			"SELECT locked_until FROM ip_auth_failures WHERE ip = $1",
+			ip,
+		).Scan(&lockedUntil)
+	})
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !lockedUntil.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &lockedUntil.Time, nil
+}
+
+// PruneIPFailures deletes counters whose window and lock both ended before cutoff
+func (r *lockoutRepository) PruneIPFailures(ctx context.Context, cutoff time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM ip_auth_failures
+			WHERE window_started_at < $1 AND (locked_until IS NULL OR locked_until < $1)
+		`, cutoff)
+		return err
+	})
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"strings"
+	"time"
+)
+
+type magicLinkRepository struct {
+	DB *database.Database
+}
+
+// NewMagicLinkRepository creates a new MagicLinkRepository instance
+func NewMagicLinkRepository(db *database.Database) MagicLinkRepository {
+	return &magicLinkRepository{DB: db}
+}
+
+// Create stores a link, replacing any the user already has
+func (r *magicLinkRepository) Create(ctx context.Context, link *models.MagicLink, tokenHash, deviceHash string) (*models.MagicLink, error) {
+	created := *link
+	created.Email = strings.ToLower(link.Email)
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO magic_links (user_id, email, token_hash, device_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET
+			email = EXCLUDED.email, token_hash = EXCLUDED.token_hash, device_hash = EXCLUDED.device_hash,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+		RETURNING id
+	`, link.UserID, created.Email, tokenHash, deviceHash, link.ExpiresAt, link.CreatedAt).Scan(&created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// Redeem consumes the unexpired link the token belongs to when it was
+// requested from the same device, and marks the user's address verified
+// since the link proves control of it. ErrNotFound means no such link is
+// pending or the user's address has changed since it was sent; a link
+// presented from another device is left in place.
+func (r *magicLinkRepository) Redeem(ctx context.Context, tokenHash, deviceHash string, now time.Time) (*models.MagicLink, error) {
+	var link models.MagicLink
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM magic_links
+			WHERE token_hash = $1 AND device_hash = $2 AND expires_at > $3
+			RETURNING id, user_id, email, expires_at, created_at
+		`, tokenHash, deviceHash, now).Scan(
+			&link.ID, &link.UserID, &link.Email, &link.ExpiresAt, &link.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return verifyEmail(ctx, tx, link.UserID, link.Email, now)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+)
+
+const mfaFactorColumns = `id, user_id, type, destination, verified_at, created_at, last_used_at`
+
+const otpChallengeColumns = `
+	id, user_id, purpose, first_factor, factor_id, channel, COALESCE(code_hash, ''),
+	attempts, sends, sent_at, expires_at, created_at, COALESCE(pending_password_hash, '')
+`
+
+type mfaRepository struct {
+	DB *database.Database
+}
+
+// NewMFARepository creates a new MFARepository instance
+func NewMFARepository(db *database.Database) MFARepository {
+	return &mfaRepository{DB: db}
+}
+
+// CreateFactor adds an unverified factor. Enrolling a factor that is still
+// unverified again reuses it; ErrConflict means it is already verified.
+func (r *mfaRepository) CreateFactor(ctx context.Context, factor *models.MFAFactor) (*models.MFAFactor, error) {
+	created := *factor
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO mfa_factors (user_id, type, destination, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, type, destination) DO UPDATE SET created_at = EXCLUDED.created_at
+		WHERE mfa_factors.verified_at IS NULL
+		RETURNING id
+	`, factor.UserID, factor.Type, factor.Destination, factor.CreatedAt).Scan(&created.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// GetFactors returns the user's factors, verified or not, oldest first
+func (r *mfaRepository) GetFactors(ctx context.Context, userID int) ([]models.MFAFactor, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+mfaFactorColumns+`
+		FROM mfa_factors
+		WHERE user_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	factors := []models.MFAFactor{}
+	for rows.Next() {
+		factor, err := scanMFAFactor(rows)
+		if err != nil {
+			return nil, err
+		}
+		factors = append(factors, *factor)
+	}
+
+	return factors, rows.Err()
+}
+
+func (r *mfaRepository) VerifyFactor(ctx context.Context, userID, id int, now time.Time) (*models.MFAFactor, error) {
+	factor, err := scanMFAFactor(r.DB.QueryRowContext(ctx, `
+		UPDATE mfa_factors SET verified_at = COALESCE(verified_at, $3)
+		WHERE user_id = $1 AND id = $2
+		RETURNING `+mfaFactorColumns,
+		userID, id, now,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return factor, nil
+}
+
+func (r *mfaRepository) DeleteFactor(ctx context.Context, userID, id int) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM mfa_factors WHERE user_id = $1 AND id = $2", userID, id)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+func (r *mfaRepository) RecordFactorUsage(ctx context.Context, id int, now time.Time) error {
+	_, err := r.DB.ExecContext(ctx, "UPDATE mfa_factors SET last_used_at = $2 WHERE id = $1", id, now)
+	return err
+}
+
+// CreateChallenge stores a challenge, replacing the user's challenge for
+// the same purpose. The send count and time of the replaced challenge are
+// kept until it would have expired.
+func (r *mfaRepository) CreateChallenge(ctx context.Context, challenge *models.OTPChallenge, tokenHash string) (*models.OTPChallenge, error) {
+	created, err := scanOTPChallenge(r.DB.QueryRowContext(ctx, `
+		INSERT INTO otp_challenges (
+			user_id, purpose, token_hash, first_factor, factor_id, expires_at, created_at, pending_password_hash
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		ON CONFLICT (user_id, purpose) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash, first_factor = EXCLUDED.first_factor,
+			factor_id = EXCLUDED.factor_id, channel = '', code_hash = NULL, attempts = 0,
+			sends = CASE WHEN otp_challenges.expires_at > EXCLUDED.created_at THEN otp_challenges.sends ELSE 0 END,
+			sent_at = CASE WHEN otp_challenges.expires_at > EXCLUDED.created_at THEN otp_challenges.sent_at END,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at,
+			pending_password_hash = EXCLUDED.pending_password_hash
+		RETURNING `+otpChallengeColumns,
+		challenge.UserID, challenge.Purpose, tokenHash, challenge.FirstFactor, challenge.FactorID,
+		challenge.ExpiresAt, challenge.CreatedAt, challenge.PendingPasswordHash,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (r *mfaRepository) GetChallenge(ctx context.Context, tokenHash string) (*models.OTPChallenge, error) {
+	challenge, err := scanOTPChallenge(r.DB.QueryRowContext(ctx, `
+		SELECT `+otpChallengeColumns+`
+		FROM otp_challenges
+		WHERE token_hash = $1
+	`, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// SetCode replaces the challenge's code unless the last one was sent after
+// sentBefore or maxSends codes have been sent. It reports whether the code
+// was stored and may be delivered.
+func (r *mfaRepository) SetCode(ctx context.Context, id int, factorID *int, channel, codeHash string, now, sentBefore time.Time, maxSends int) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, `
+		UPDATE otp_challenges
+		SET factor_id = $2, channel = $3, code_hash = $4, attempts = 0, sends = sends + 1, sent_at = $5
+		WHERE id = $1 AND expires_at > $5 AND sends < $7 AND (sent_at IS NULL OR sent_at <= $6)
+	`, id, factorID, channel, codeHash, now, sentBefore, maxSends)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+// RecordAttempt counts a guess at the challenge's code, reporting false
+// once maxAttempts guesses have been made
+func (r *mfaRepository) RecordAttempt(ctx context.Context, id, maxAttempts int) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, `
+		UPDATE otp_challenges SET attempts = attempts + 1
+		WHERE id = $1 AND attempts < $2
+	`, id, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+// DeleteChallenge removes a challenge, reporting whether it still existed
+// so that only one caller can consume it
+func (r *mfaRepository) DeleteChallenge(ctx context.Context, id int) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM otp_challenges WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+func scanMFAFactor(row rowScanner) (*models.MFAFactor, error) {
+	var factor models.MFAFactor
+	var verifiedAt, lastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&factor.ID, &factor.UserID, &factor.Type, &factor.Destination,
+		&verifiedAt, &factor.CreatedAt, &lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	factor.VerifiedAt = nullableTime(verifiedAt)
+	factor.LastUsedAt = nullableTime(lastUsedAt)
+	return &factor, nil
+}
+
+func scanOTPChallenge(row rowScanner) (*models.OTPChallenge, error) {
+	var challenge models.OTPChallenge
+	var factorID sql.NullInt64
+	var sentAt sql.NullTime
+
+	err := row.Scan(
+		&challenge.ID, &challenge.UserID, &challenge.Purpose, &challenge.FirstFactor, &factorID,
+		&challenge.Channel, &challenge.CodeHash, &challenge.Attempts, &challenge.Sends, &sentAt,
+		&challenge.ExpiresAt, &challenge.CreatedAt, &challenge.PendingPasswordHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge.FactorID = nullableInt(factorID)
+	challenge.SentAt = nullableTime(sentAt)
+	return &challenge, nil
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// oauthClientUsageInterval throttles last-used bookkeeping for busy clients
+const oauthClientUsageInterval = time.Minute
+
+const oauthClientColumns = `
+	id, organization_id, client_id, name, allowed_audiences, allowed_scopes,
+	allow_impersonation, created_by, created_at, last_used_at
+`
+
+type oauthRepository struct {
+	DB *database.Database
+}
+
+// NewOAuthRepository creates a new OAuthRepository instance.
+// Clients and tokens are resolved before any tenant is known, outside
+// row-level security; managing clients is scoped to one organization and
+// runs under its row-level security.
+func NewOAuthRepository(db *database.Database) OAuthRepository {
+	return &oauthRepository{DB: db}
+}
+
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient, secretHash string) (*models.OAuthClient, error) {
+	created := *client
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO oauth_clients (
+				organization_id, client_id, name, secret_hash, allowed_audiences, allowed_scopes,
+				allow_impersonation, created_by, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`,
+			client.OrganizationID, client.ClientID, client.Name, secretHash,
+			pq.Array(client.AllowedAudiences), pq.Array(client.AllowedScopes),
+			client.AllowImpersonation, client.CreatedBy, client.CreatedAt,
+		).Scan(&created.ID)
+	})
+	if isUniqueViolation(err) {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *oauthRepository) GetClients(ctx context.Context, orgID int) ([]models.OAuthClient, error) {
+	clients := []models.OAuthClient{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+oauthClientColumns+`
+			FROM oauth_clients
+			WHERE organization_id = $1 AND revoked_at IS NULL
+			ORDER BY created_at DESC
+		`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			client, err := scanOAuthClient(rows)
+			if err != nil {
+				return err
+			}
+			clients = append(clients, *client)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (r *oauthRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClientCredential, error) {
+	var cred models.OAuthClientCredential
+	var createdBy sql.NullInt64
+	var lastUsedAt, revokedAt sql.NullTime
+
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			SELECT `+oauthClientColumns+`, secret_hash, revoked_at
+			FROM oauth_clients
+			WHERE client_id = $1
+		`, clientID).Scan(
+			&cred.ID, &cred.OrganizationID, &cred.ClientID, &cred.Name,
+			pq.Array(&cred.AllowedAudiences), pq.Array(&cred.AllowedScopes),
+			&cred.AllowImpersonation, &createdBy, &cred.CreatedAt, &lastUsedAt,
+			&cred.SecretHash, &revokedAt,
+		)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cred.CreatedBy = nullableInt(createdBy)
+	cred.LastUsedAt = nullableTime(lastUsedAt)
+	cred.RevokedAt = nullableTime(revokedAt)
+	return &cred, nil
+}
+
+func (r *oauthRepository) RevokeClient(ctx context.Context, orgID, id int, now time.Time) (bool, error) {
+	var revoked bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE oauth_clients
+			SET revoked_at = $3
+			WHERE organization_id = $1 AND id = $2 AND revoked_at IS NULL
+		`, orgID, id, now)
+		if err != nil {
+			return err
+		}
+		revoked, err = rowsAffected(result)
+		return err
+	})
+	return revoked, err
+}
+
+func (r *oauthRepository) RecordClientUsage(ctx context.Context, id int, now time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE oauth_clients
+			SET last_used_at = $2
+			WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+		`, id, now, now.Add(-oauthClientUsageInterval))
+		return err
+	})
+}
+
+func (r *oauthRepository) CreateAccessToken(ctx context.Context, token *models.AccessToken, tokenHash string) (*models.AccessToken, error) {
+	var act []byte
+	if token.Act != nil {
+		var err error
+		if act, err = json.Marshal(token.Act); err != nil {
+			return nil, err
+		}
+	}
+
+	created := *token
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO oauth_access_tokens (
+				token_hash, client_id, organization_id, user_id, session_id,
+				audience, scopes, act, issued_at, expires_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id
+		`,
+			tokenHash, token.ClientID, token.OrganizationID, token.UserID, token.SessionID,
+			token.Audience, pq.Array(token.Scopes), act, token.IssuedAt, token.ExpiresAt,
+		).Scan(&created.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// GetAccessTokenByHash resolves an exchanged token. Memberships are
+// tenant data, so the lookup bypasses row-level security.
+func (r *oauthRepository) GetAccessTokenByHash(ctx context.Context, tokenHash string) (*models.AccessTokenCredential, error) {
+	var cred models.AccessTokenCredential
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var act []byte
+
+		err := tx.QueryRowContext(ctx, `
+			SELECT t.id, t.client_id, t.organization_id, t.user_id, t.session_id,
+				t.audience, t.scopes, t.act, t.issued_at, t.expires_at, c.client_id, u.email,
+				s.amr, s.auth_time,
+				s.revoked_at IS NOT NULL OR c.revoked_at IS NOT NULL
+					OR u.disabled_at IS NOT NULL OR a.disabled_at IS NOT NULL
+					OR NOT EXISTS(
+						SELECT 1 FROM organization_members om
+						WHERE om.organization_id = t.organization_id AND om.user_id = t.user_id AND om.active
+					)
+			FROM oauth_access_tokens t
+			JOIN oauth_clients c ON c.id = t.client_id
+			JOIN sessions s ON s.id = t.session_id
+			JOIN users u ON u.id = t.user_id
+			LEFT JOIN users a ON a.id = s.actor_user_id
+			WHERE t.token_hash = $1
+		`, tokenHash).Scan(
+			&cred.ID, &cred.ClientID, &cred.OrganizationID, &cred.UserID, &cred.SessionID,
+			&cred.Audience, pq.Array(&cred.Scopes), &act, &cred.IssuedAt, &cred.ExpiresAt,
+			&cred.ClientPublicID, &cred.UserEmail, pq.Array(&cred.SessionAMR), &cred.SessionAuthTime,
+			&cred.Revoked,
+		)
+		if err != nil {
+			return err
+		}
+
+		if act != nil {
+			cred.Act = &models.Actor{}
+			return json.Unmarshal(act, cred.Act)
+		}
+		return nil
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &cred, nil
+}
+
+func scanOAuthClient(row rowScanner) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	var createdBy sql.NullInt64
+	var lastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&client.ID, &client.OrganizationID, &client.ClientID, &client.Name,
+		pq.Array(&client.AllowedAudiences), pq.Array(&client.AllowedScopes),
+		&client.AllowImpersonation, &createdBy, &client.CreatedAt, &lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client.CreatedBy = nullableInt(createdBy)
+	client.LastUsedAt = nullableTime(lastUsedAt)
+	return &client, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+)
+
+type organizationRepository struct {
+	DB *database.Database
+}
+
+// NewOrganizationRepository creates a new OrganizationRepository instance.
+// Membership lookups span tenants by nature and run outside row-level security.
+func NewOrganizationRepository(db *database.Database) OrganizationRepository {
+	return &organizationRepository{DB: db}
+}
+
+func (r *organizationRepository) Create(ctx context.Context, name, slug string, ownerID int, ownerRole string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			"INSERT INTO organizations (name, slug) VALUES ($1, $2) RETURNING id, name, slug, created_at",
+			name, slug,
+		).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO organization_members (organization_id, user_id) VALUES ($1, $2)",
+			org.ID, ownerID,
+		); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO user_roles (user_id, role_id, organization_id)
+			SELECT $1::int, id, $2::int FROM roles WHERE name = $3
+		`, ownerID, org.ID, ownerRole)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (r *organizationRepository) GetByID(ctx context.Context, id int) (*models.Organization, error) {
+	var org models.Organization
+	err := r.DB.QueryRowContext(
+		ctx,
+		"SELECT id, name, slug, created_at FROM organizations WHERE id = $1",
+		id,
+	).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (r *organizationRepository) GetForUser(ctx context.Context, userID int) ([]models.Organization, error) {
+	orgs := []models.Organization{}
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT o.id, o.name, o.slug, o.created_at
+			FROM organizations o
+			JOIN organization_members om ON om.organization_id = o.id
+			WHERE om.user_id = $1 AND om.active
+			ORDER BY o.name
+		`, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var org models.Organization
+			if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt); err != nil {
+				return err
+			}
+			orgs = append(orgs, org)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+func (r *organizationRepository) SlugExists(ctx context.Context, slug string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM organizations WHERE slug = $1)",
+		slug,
+	).Scan(&exists)
+
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// IsMember reports whether the user is an active member; deactivated
+// members keep their row but not their access
+func (r *organizationRepository) IsMember(ctx context.Context, orgID, userID int) (bool, error) {
+	var exists bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(
+			ctx,
+			"SELECT EXISTS(SELECT 1 FROM organization_members WHERE organization_id = $1 AND user_id = $2 AND active)",
+			orgID, userID,
+		).Scan(&exists)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *organizationRepository) GetPasswordPolicy(ctx context.Context, orgID int) (*models.PasswordPolicy, error) {
+	var historyCount, maxAgeDays sql.NullInt64
+	err := r.DB.QueryRowContext(
+		ctx,
+		"SELECT password_history_count, password_max_age_days FROM organizations WHERE id = $1",
+		orgID,
+	).Scan(&historyCount, &maxAgeDays)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PasswordPolicy{
+		HistoryCount: nullableInt(historyCount),
+		MaxAgeDays:   nullableInt(maxAgeDays),
+	}, nil
+}
+
+func (r *organizationRepository) SetPasswordPolicy(ctx context.Context, orgID int, policy models.PasswordPolicy) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE organizations SET password_history_count = $2, password_max_age_days = $3
+		WHERE id = $1
+	`, orgID, policy.HistoryCount, policy.MaxAgeDays)
+	return err
+}
+
+// GetPasswordPoliciesForUser returns the overrides of every organization the
+// user belongs to that sets at least one of them
+func (r *organizationRepository) GetPasswordPoliciesForUser(ctx context.Context, userID int) ([]models.PasswordPolicy, error) {
+	var policies []models.PasswordPolicy
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT o.password_history_count, o.password_max_age_days
+			FROM organizations o
+			JOIN organization_members om ON om.organization_id = o.id
+			WHERE om.user_id = $1
+				AND (o.password_history_count IS NOT NULL OR o.password_max_age_days IS NOT NULL)
+		`, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var historyCount, maxAgeDays sql.NullInt64
+			if err := rows.Scan(&historyCount, &maxAgeDays); err != nil {
+				return err
+			}
+			policies = append(policies, models.PasswordPolicy{
+				HistoryCount: nullableInt(historyCount),
+				MaxAgeDays:   nullableInt(maxAgeDays),
+			})
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// insertOutboxEvent records a domain event in the caller's transaction so it
+// is published if, and only if, the change that produced it commits
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, eventType string, orgID int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_type, organization_id, payload)
+		VALUES ($1, NULLIF($2::int, 0), $3)
+	`, eventType, orgID, payload)
+	return err
+}
+
+// insertUserOutboxEvent records an event about a user, who is not tied to
+// one tenant, once for each organization they belong to. Memberships in
+// other tenants are read, so tx must bypass row-level security.
+func insertUserOutboxEvent(ctx context.Context, tx *sql.Tx, eventType string, userID int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO outbox_events (event_type, organization_id, payload)
+		SELECT $1, organization_id, $3 FROM organization_members WHERE user_id = $2
+	`, eventType, userID, payload)
+	return err
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"strings"
+	"time"
+)
+
+type profileRepository struct {
+	DB *database.Database
+}
+
+// NewProfileRepository creates a new ProfileRepository instance.
+// A profile is the user's global identity rather than a membership, so
+// queries filter by user and run outside tenant row-level security.
+func NewProfileRepository(db *database.Database) ProfileRepository {
+	return &profileRepository{DB: db}
+}
+
+// GetProfile returns a user's profile with the pending email change, if
+// one has not expired by now
+func (r *profileRepository) GetProfile(ctx context.Context, userID int, now time.Time) (*models.Profile, error) {
+	var profile models.Profile
+	var createdAt, updatedAt sql.NullTime
+	var pendingEmail sql.NullString
+
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			SELECT u.id, u.name, u.email, COALESCE(u.display_name, ''), COALESCE(u.avatar_url, ''),
+				COALESCE(u.locale, ''), COALESCE(u.time_zone, ''), u.created_at, u.updated_at,
+				u.email_verified_at IS NOT NULL, ec.new_email
+			FROM users u
+			LEFT JOIN email_changes ec ON ec.user_id = u.id AND ec.expires_at > $2
+			WHERE u.id = $1
+		`, userID, now).Scan(
+			&profile.ID, &profile.Name, &profile.Email, &profile.DisplayName, &profile.AvatarURL,
+			&profile.Locale, &profile.TimeZone, &createdAt, &updatedAt,
+			&profile.EmailVerified, &pendingEmail,
+		)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	profile.CreatedAt = nullableTime(createdAt)
+	profile.UpdatedAt = nullableTime(updatedAt)
+	if pendingEmail.Valid {
+		profile.PendingEmail = &pendingEmail.String
+	}
+	return &profile, nil
+}
+
+// UpdateProfile stores the name and profile attributes. Empty optional
+// attributes are stored as NULL.
+func (r *profileRepository) UpdateProfile(ctx context.Context, profile *models.Profile, now time.Time) (bool, error) {
+	var updated bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users SET
+				name = $2, display_name = NULLIF($3, ''), avatar_url = NULLIF($4, ''),
+				locale = NULLIF($5, ''), time_zone = NULLIF($6, ''), updated_at = $7
+			WHERE id = $1
+		`, profile.ID, profile.Name, profile.DisplayName, profile.AvatarURL, profile.Locale, profile.TimeZone, now)
+		if err != nil {
+			return err
+		}
+		updated, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+// CreateEmailChange stores a pending email change, replacing any earlier
+// one of the same user
+func (r *profileRepository) CreateEmailChange(ctx context.Context, change *models.EmailChange, tokenHash string) (*models.EmailChange, error) {
+	created := *change
+	created.NewEmail = strings.ToLower(change.NewEmail)
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO email_changes (user_id, new_email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+		RETURNING id
+	`, change.UserID, created.NewEmail, tokenHash, change.ExpiresAt, change.CreatedAt).Scan(&created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// CompleteEmailChange consumes the unexpired change the token belongs to
+// and switches the user to the new address, which the token proves to be
+// verified, returning the change and the previous address. ErrNotFound means no such change is pending;
+// ErrConflict means another account took the address in the meantime.
+func (r *profileRepository) CompleteEmailChange(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, string, error) {
+	var change models.EmailChange
+	var previous string
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM email_changes
+			WHERE token_hash = $1 AND expires_at > $2
+			RETURNING id, user_id, new_email, expires_at, created_at
+		`, tokenHash, now).Scan(&change.ID, &change.UserID, &change.NewEmail, &change.ExpiresAt, &change.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx,
+			"SELECT email FROM users WHERE id = $1 FOR UPDATE",
+			change.UserID,
+		).Scan(&previous)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE users SET email = $2, email_verified_at = $3, updated_at = $3 WHERE id = $1",
+			change.UserID, change.NewEmail, now,
+		)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &change, previous, nil
+}
+
+func (r *profileRepository) DeleteEmailChange(ctx context.Context, userID int) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM email_changes WHERE user_id = $1", userID)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+// CreateEmailVerification stores a verification link for the user's
+// current address, replacing any earlier one
+func (r *profileRepository) CreateEmailVerification(ctx context.Context, verification *models.EmailVerification, tokenHash string) (*models.EmailVerification, error) {
+	created := *verification
+	created.Email = strings.ToLower(verification.Email)
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO email_verifications (user_id, email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			email = EXCLUDED.email, token_hash = EXCLUDED.token_hash,
+			expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+		RETURNING id
+	`, verification.UserID, created.Email, tokenHash, verification.ExpiresAt, verification.CreatedAt).Scan(&created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// CompleteEmailVerification consumes the unexpired link the token belongs
+// to and marks the user's address verified. ErrNotFound means no such link
+// is pending or the user's address has changed since it was sent.
+func (r *profileRepository) CompleteEmailVerification(ctx context.Context, tokenHash string, now time.Time) (*models.EmailVerification, error) {
+	var verification models.EmailVerification
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM email_verifications
+			WHERE token_hash = $1 AND expires_at > $2
+			RETURNING id, user_id, email, expires_at, created_at
+		`, tokenHash, now).Scan(
+			&verification.ID, &verification.UserID, &verification.Email,
+			&verification.ExpiresAt, &verification.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		return verifyEmail(ctx, tx, verification.UserID, verification.Email, now)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &verification, nil
+}
+
+// DeleteAccount deletes a user outright, ending their memberships,
+// sessions and credentials, and publishes user.deleted to every
+// organization they belonged to. Other tenants' memberships are read, so
+// this runs outside row-level security.
+func (r *profileRepository) DeleteAccount(ctx context.Context, userID int) (bool, error) {
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var email string
+		err := tx.QueryRowContext(ctx,
+			"SELECT email FROM users WHERE id = $1 FOR UPDATE",
+			userID,
+		).Scan(&email)
+		if err != nil {
+			return err
+		}
+
+		if err := insertUserOutboxEvent(ctx, tx, models.EventUserDeleted, userID, map[string]interface{}{
+			"user_id": userID,
+			"email":   email,
+		}); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// verifyEmail marks the user's address as verified if it is still email,
+// returning ErrNotFound otherwise, and publishes user.email_verified the
+// first time. Other tenants' memberships are read, so tx must bypass
+// row-level security.
+func verifyEmail(ctx context.Context, tx *sql.Tx, userID int, email string, now time.Time) error {
+	var alreadyVerified bool
+	err := tx.QueryRowContext(ctx,
+		"SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1 AND email = $2 FOR UPDATE",
+		userID, email,
+	).Scan(&alreadyVerified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil || alreadyVerified {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE users SET email_verified_at = $2 WHERE id = $1",
+		userID, now,
+	); err != nil {
+		return err
+	}
+	return insertUserOutboxEvent(ctx, tx, models.EventUserEmailVerified, userID, map[string]interface{}{
+		"user_id": userID,
+		"email":   email,
+	})
+}
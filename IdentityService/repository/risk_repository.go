@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+)
+
+const signInEventColumns = `id, user_id, session_id, ip, user_agent, asn, country, latitude, longitude, created_at`
+
+type riskRepository struct {
+	DB *database.Database
+}
+
+// NewRiskRepository creates a new RiskRepository instance. Sign-in history
+// belongs to users rather than tenants.
+func NewRiskRepository(db *database.Database) RiskRepository {
+	return &riskRepository{DB: db}
+}
+
+// GetHistory summarises the user's sign-ins since the cutoff as they bear
+// on one from ip and userAgent within network asn, which is zero when
+// unknown, together with the failures counted against ip since
+// failuresSince
+func (r *riskRepository) GetHistory(ctx context.Context, userID int, ip, userAgent string, asn int, since, failuresSince time.Time) (*models.SignInHistory, error) {
+	var history models.SignInHistory
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			COALESCE(BOOL_OR(user_agent = $2), FALSE),
+			COALESCE(BOOL_OR(ip = $3), FALSE),
+			COALESCE(BOOL_OR(asn = $4), FALSE),
+			COALESCE((
+				SELECT failures FROM ip_auth_failures
+				WHERE ip = $3 AND window_started_at > $6
+			), 0)
+		FROM sign_in_events
+		WHERE user_id = $1 AND created_at > $5
+	`, userID, userAgent, ip, nullableASN(asn), since, failuresSince).Scan(
+		&history.SignIns, &history.KnownUserAgent, &history.KnownIP, &history.KnownASN, &history.IPFailures,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := scanSignInEvent(r.DB.QueryRowContext(ctx, `
+		SELECT `+signInEventColumns+`
+		FROM sign_in_events
+		WHERE user_id = $1 AND created_at > $2 AND latitude IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID, since))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	history.Last = last
+
+	return &history, nil
+}
+
+// RecordSignIn adds a sign-in to the user's history and forgets those made
+// before pruneBefore
+func (r *riskRepository) RecordSignIn(ctx context.Context, event *models.SignInEvent, pruneBefore time.Time) (*models.SignInEvent, error) {
+	created := *event
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO sign_in_events (user_id, session_id, ip, user_agent, asn, country, latitude, longitude, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`,
+		event.UserID, event.SessionID, event.IP, event.UserAgent, event.ASN, event.Country,
+		event.Latitude, event.Longitude, event.CreatedAt,
+	).Scan(&created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.DB.ExecContext(ctx,
+		"DELETE FROM sign_in_events WHERE user_id = $1 AND created_at < $2",
+		event.UserID, pruneBefore,
+	); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func scanSignInEvent(row rowScanner) (*models.SignInEvent, error) {
+	var event models.SignInEvent
+	var sessionID, asn sql.NullInt64
+	var latitude, longitude sql.NullFloat64
+
+	err := row.Scan(
+		&event.ID, &event.UserID, &sessionID, &event.IP, &event.UserAgent, &asn, &event.Country,
+		&latitude, &longitude, &event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionID.Valid {
+		event.SessionID = &sessionID.Int64
+	}
+	event.ASN = nullableInt(asn)
+	if latitude.Valid && longitude.Valid {
+		event.Latitude, event.Longitude = &latitude.Float64, &longitude.Float64
+	}
+	return &event, nil
+}
+
+// nullableASN stores an unknown network as NULL
+func nullableASN(asn int) interface{} {
+	if asn == 0 {
+		return nil
+	}
+	return asn
+}
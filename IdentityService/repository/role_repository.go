@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"identity-service/models"
+)
+
+type roleRepository struct {
+	DB *sql.DB
+}
+
+// NewRoleRepository creates a new RoleRepository instance
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{DB: db}
+}
+
+func (r *roleRepository) GetAll(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT r.id, r.name, r.description, r.built_in, COALESCE(rp.permission, '')
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		ORDER BY r.id, rp.permission
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		var permission string
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.BuiltIn, &permission); err != nil {
+			return nil, err
+		}
+		if n := len(roles); n == 0 || roles[n-1].ID != role.ID {
+			role.Permissions = []string{}
+			roles = append(roles, role)
+		}
+		if permission != "" {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission)
+		}
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *roleRepository) GetRoleNamesForUser(ctx context.Context, orgID, userID int) ([]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT DISTINCT r.name
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		AND (ur.organization_id IS NULL OR ur.organization_id = $2)
+		ORDER BY r.name
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanStrings(rows)
+}
+
+func (r *roleRepository) GetPermissionsForUser(ctx context.Context, orgID, userID int) ([]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT DISTINCT rp.permission
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		WHERE ur.user_id = $1
+		AND (ur.organization_id IS NULL OR ur.organization_id = $2)
+		ORDER BY rp.permission
+	`, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanStrings(rows)
+}
+
+func (r *roleRepository) AssignRole(ctx context.Context, orgID, userID int, roleName string) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role_id, organization_id)
+		SELECT $1::int, id, NULLIF($3::int, 0) FROM roles WHERE name = $2
+		ON CONFLICT DO NOTHING
+	`, userID, roleName, orgID)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+func (r *roleRepository) RemoveRole(ctx context.Context, orgID, userID int, roleName string) (bool, error) {
+	result, err := r.DB.ExecContext(ctx, `
+		DELETE FROM user_roles
+		WHERE user_id = $1
+		AND role_id = (SELECT id FROM roles WHERE name = $2)
+		AND organization_id IS NOT DISTINCT FROM NULLIF($3::int, 0)
+	`, userID, roleName, orgID)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+func (r *roleRepository) RoleExists(ctx context.Context, roleName string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)",
+		roleName,
+	).Scan(&exists)
+
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func scanStrings(rows *sql.Rows) ([]string, error) {
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func rowsAffected(result sql.Result) (bool, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+)
+
+// scimTokenUsageInterval throttles last-used bookkeeping for busy tokens
+const scimTokenUsageInterval = time.Minute
+
+type scimRepository struct {
+	DB *database.Database
+}
+
+// NewSCIMRepository creates a new SCIMRepository instance.
+// Tokens are resolved before any tenant is known, outside row-level
+// security; managing tokens and member queries are scoped to one
+// organization and run under its row-level security.
+func NewSCIMRepository(db *database.Database) SCIMRepository {
+	return &scimRepository{DB: db}
+}
+
+func (r *scimRepository) CreateToken(ctx context.Context, token *models.SCIMToken, tokenHash string) (*models.SCIMToken, error) {
+	created := *token
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO scim_tokens (organization_id, name, token_hash, created_by, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, token.OrganizationID, token.Name, tokenHash, token.CreatedBy, token.CreatedAt).Scan(&created.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *scimRepository) GetTokens(ctx context.Context, orgID int) ([]models.SCIMToken, error) {
+	tokens := []models.SCIMToken{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, organization_id, name, created_by, created_at, last_used_at
+			FROM scim_tokens
+			WHERE organization_id = $1 AND revoked_at IS NULL
+			ORDER BY created_at DESC
+		`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			token, err := scanSCIMToken(rows)
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, *token)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *scimRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.SCIMTokenCredential, error) {
+	var cred models.SCIMTokenCredential
+	var createdBy sql.NullInt64
+	var lastUsedAt, revokedAt sql.NullTime
+
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			SELECT id, organization_id, name, created_by, created_at, last_used_at, revoked_at
+			FROM scim_tokens
+			WHERE token_hash = $1
+		`, tokenHash).Scan(
+			&cred.ID, &cred.OrganizationID, &cred.Name, &createdBy, &cred.CreatedAt, &lastUsedAt, &revokedAt,
+		)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cred.CreatedBy = nullableInt(createdBy)
+	cred.LastUsedAt = nullableTime(lastUsedAt)
+	cred.RevokedAt = nullableTime(revokedAt)
+	return &cred, nil
+}
+
+func (r *scimRepository) RevokeToken(ctx context.Context, orgID, id int, now time.Time) (bool, error) {
+	var revoked bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE scim_tokens
+			SET revoked_at = $3
+			WHERE organization_id = $1 AND id = $2 AND revoked_at IS NULL
+		`, orgID, id, now)
+		if err != nil {
+			return err
+		}
+		revoked, err = rowsAffected(result)
+		return err
+	})
+	return revoked, err
+}
+
+func (r *scimRepository) RecordTokenUsage(ctx context.Context, id int, now time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE scim_tokens
+			SET last_used_at = $2
+			WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)
+		`, id, now, now.Add(-scimTokenUsageInterval))
+		return err
+	})
+}
+
+func scanSCIMToken(row rowScanner) (*models.SCIMToken, error) {
+	var token models.SCIMToken
+	var createdBy sql.NullInt64
+	var lastUsedAt sql.NullTime
+
+	err := row.Scan(&token.ID, &token.OrganizationID, &token.Name, &createdBy, &token.CreatedAt, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.CreatedBy = nullableInt(createdBy)
+	token.LastUsedAt = nullableTime(lastUsedAt)
+	return &token, nil
+}
+
+const scimMemberColumns = `
+	u.id, u.name, u.email, om.active, u.locked_until,
+	COALESCE(om.external_id, ''), om.created_at, om.updated_at
+`
+
+// GetMembers returns the organization's members, deactivated ones
+// included, in id order. The match narrows the result in the database.
+func (r *scimRepository) GetMembers(ctx context.Context, orgID int, match models.SCIMMemberMatch) ([]models.SCIMMember, error) {
+	members := []models.SCIMMember{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+scimMemberColumns+`
+			FROM users u
+			JOIN organization_members om ON om.user_id = u.id
+			WHERE om.organization_id = $1
+				AND ($2 = '' OR lower(u.email) = lower($2))
+				AND ($3 = '' OR om.external_id = $3)
+			ORDER BY u.id
+		`, orgID, match.Email, match.ExternalID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			member, err := scanSCIMMember(rows)
+			if err != nil {
+				return err
+			}
+			members = append(members, *member)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (r *scimRepository) GetMember(ctx context.Context, orgID, userID int) (*models.SCIMMember, error) {
+	var member *models.SCIMMember
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		member, err = scanSCIMMember(tx.QueryRowContext(ctx, `
+			SELECT `+scimMemberColumns+`
+			FROM users u
+			JOIN organization_members om ON om.user_id = u.id
+			WHERE om.organization_id = $1 AND u.id = $2
+		`, orgID, userID))
+		return err
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// SetExternalID records the identity provider's id for a member. An empty
+// id clears it.
+func (r *scimRepository) SetExternalID(ctx context.Context, orgID, userID int, externalID string, now time.Time) (bool, error) {
+	var updated bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE organization_members SET external_id = NULLIF($3, ''), updated_at = $4
+			WHERE organization_id = $1 AND user_id = $2
+				AND external_id IS DISTINCT FROM NULLIF($3, '')
+		`, orgID, userID, externalID, now)
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		updated, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+func scanSCIMMember(row rowScanner) (*models.SCIMMember, error) {
+	var member models.SCIMMember
+	var lockedUntil, createdAt, updatedAt sql.NullTime
+
+	err := row.Scan(
+		&member.ID, &member.Name, &member.Email, &member.Active, &lockedUntil,
+		&member.ExternalID, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	member.LockedUntil = nullableTime(lockedUntil)
+	member.CreatedAt = createdAt.Time
+	member.UpdatedAt = nullableTime(updatedAt)
+	return &member, nil
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type sessionRepository struct {
+	DB *database.Database
+}
+
+// NewSessionRepository creates a new SessionRepository instance.
+// Sessions belong to users rather than tenants, and are resolved before any
+// tenant is known, so the owner lookup runs outside row-level security.
+func NewSessionRepository(db *database.Database) SessionRepository {
+	return &sessionRepository{DB: db}
+}
+
+func (r *sessionRepository) Create(ctx context.Context, session *models.Session, tokenHash string) (*models.Session, error) {
+	created := *session
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO sessions (
+			user_id, token_hash, created_at, expires_at, ip, user_agent, organization_id, actor_user_id,
+			amr, auth_time
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`,
+		session.UserID, tokenHash, session.CreatedAt, session.ExpiresAt, session.IP, session.UserAgent,
+		session.OrganizationID, session.ActorUserID, pq.Array(session.AMR), session.AuthTime,
+	).Scan(&created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *sessionRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.SessionCredential, error) {
+	var cred models.SessionCredential
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var lastUsedAt, revokedAt sql.NullTime
+		var ip, userAgent sql.NullString
+		var orgID, actorID sql.NullInt64
+
+		err := tx.QueryRowContext(ctx, `
+			SELECT s.id, s.user_id, s.created_at, s.expires_at, s.last_used_at,
+				s.ip, s.user_agent, s.revoked_at, s.organization_id, s.actor_user_id, s.amr, s.auth_time,
+				u.email, u.disabled_at IS NOT NULL OR a.disabled_at IS NOT NULL
+			FROM sessions s
+			JOIN users u ON u.id = s.user_id
+			LEFT JOIN users a ON a.id = s.actor_user_id
+			WHERE s.token_hash = $1
+		`, tokenHash).Scan(
+			&cred.ID, &cred.UserID, &cred.CreatedAt, &cred.ExpiresAt, &lastUsedAt,
+			&ip, &userAgent, &revokedAt, &orgID, &actorID, pq.Array(&cred.AMR), &cred.AuthTime,
+			&cred.UserEmail, &cred.UserDisabled,
+		)
+		if err != nil {
+			return err
+		}
+
+		cred.OrganizationID = nullableInt(orgID)
+		cred.ActorUserID = nullableInt(actorID)
+
+		if lastUsedAt.Valid {
+			cred.LastUsedAt = &lastUsedAt.Time
+		}
+		cred.IP = ip.String
+		cred.UserAgent = userAgent.String
+		if revokedAt.Valid {
+			cred.RevokedAt = &revokedAt.Time
+		}
+		return nil
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &cred, nil
+}
+
+// GetForUser lists the user's unexpired, unrevoked sessions, most recently
+// used first
+func (r *sessionRepository) GetForUser(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT id, user_id, created_at, expires_at, last_used_at, COALESCE(ip, ''), COALESCE(user_agent, ''),
+			organization_id, actor_user_id, amr, auth_time
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY COALESCE(last_used_at, created_at) DESC
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		var lastUsedAt sql.NullTime
+		var orgID, actorID sql.NullInt64
+		if err := rows.Scan(
+			&session.ID, &session.UserID, &session.CreatedAt, &session.ExpiresAt, &lastUsedAt,
+			&session.IP, &session.UserAgent, &orgID, &actorID, pq.Array(&session.AMR), &session.AuthTime,
+		); err != nil {
+			return nil, err
+		}
+		session.LastUsedAt = nullableTime(lastUsedAt)
+		session.OrganizationID = nullableInt(orgID)
+		session.ActorUserID = nullableInt(actorID)
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// RecordUsage updates when and from where a session was last used. Writes
+// are skipped when the session was used within the last minute.
+func (r *sessionRepository) RecordUsage(ctx context.Context, id int64, ip string, now time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `
+		UPDATE sessions SET last_used_at = $2, ip = $3
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $4)
+	`, id, now, ip, now.Add(-time.Minute))
+	return err
+}
+
+// StepUp records a re-authentication of an active session
+func (r *sessionRepository) StepUp(ctx context.Context, id int64, amr []string, authTime time.Time) (bool, error) {
+	result, err := r.DB.ExecContext(ctx,
+		"UPDATE sessions SET amr = $2, auth_time = $3 WHERE id = $1 AND revoked_at IS NULL",
+		id, pq.Array(amr), authTime,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+// Revoke ends one of the user's sessions and publishes session.revoked
+func (r *sessionRepository) Revoke(ctx context.Context, userID int, id int64, now time.Time) (bool, error) {
+	var revoked bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"UPDATE sessions SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+			id, userID, now,
+		)
+		if err != nil {
+			return err
+		}
+		if revoked, err = rowsAffected(result); err != nil || !revoked {
+			return err
+		}
+		return insertSessionRevoked(ctx, tx, userID, id)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// RevokeAllForUser revokes every active session of a user except keepID,
+// which may be zero to revoke them all
+func (r *sessionRepository) RevokeAllForUser(ctx context.Context, userID int, keepID int64, now time.Time) (int, error) {
+	var revoked int
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		revoked, err = revokeSessions(ctx, tx, userID, keepID, now)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// revokeSessions revokes every active session of a user except keepID and
+// publishes session.revoked for each, in the caller's transaction
+func revokeSessions(ctx context.Context, tx *sql.Tx, userID int, keepID int64, now time.Time) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE sessions SET revoked_at = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > $3
+		RETURNING id
+	`, userID, keepID, now)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := insertSessionRevoked(ctx, tx, userID, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+func insertSessionRevoked(ctx context.Context, tx *sql.Tx, userID int, sessionID int64) error {
+	return insertUserOutboxEvent(ctx, tx, models.EventSessionRevoked, userID, map[string]interface{}{
+		"user_id":    userID,
+		"session_id": sessionID,
+	})
+}
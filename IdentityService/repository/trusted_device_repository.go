@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+)
+
+// trustedDeviceColumns lists the columns scanned by scanTrustedDevice
+const trustedDeviceColumns = `id, user_id, created_at, expires_at, last_used_at, COALESCE(ip, ''), COALESCE(user_agent, '')`
+
+type trustedDeviceRepository struct {
+	DB *database.Database
+}
+
+// NewTrustedDeviceRepository creates a new TrustedDeviceRepository instance
+func NewTrustedDeviceRepository(db *database.Database) TrustedDeviceRepository {
+	return &trustedDeviceRepository{DB: db}
+}
+
+func (r *trustedDeviceRepository) Create(ctx context.Context, device *models.TrustedDevice, tokenHash string) (*models.TrustedDevice, error) {
+	created := *device
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO trusted_devices (user_id, token_hash, created_at, expires_at, ip, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, device.UserID, tokenHash, device.CreatedAt, device.ExpiresAt, device.IP, device.UserAgent).Scan(&created.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// Use records a sign-in from the user's unexpired, unrevoked device with
+// the token hash, trusted no earlier than since, returning ErrNotFound when
+// there is none
+func (r *trustedDeviceRepository) Use(ctx context.Context, userID int, tokenHash, ip string, since, now time.Time) (*models.TrustedDevice, error) {
+	device, err := scanTrustedDevice(r.DB.QueryRowContext(ctx, `
+		UPDATE trusted_devices SET last_used_at = $5, ip = $3
+		WHERE token_hash = $2 AND user_id = $1 AND revoked_at IS NULL AND created_at >= $4 AND expires_at > $5
+		RETURNING `+trustedDeviceColumns,
+		userID, tokenHash, ip, since, now,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// GetForUser lists the user's unexpired, unrevoked devices, newest first
+func (r *trustedDeviceRepository) GetForUser(ctx context.Context, userID int, now time.Time) ([]models.TrustedDevice, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT `+trustedDeviceColumns+`
+		FROM trusted_devices
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+	`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.TrustedDevice{}
+	for rows.Next() {
+		device, err := scanTrustedDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, *device)
+	}
+
+	return devices, rows.Err()
+}
+
+func (r *trustedDeviceRepository) Revoke(ctx context.Context, userID int, id int64, now time.Time) (bool, error) {
+	result, err := r.DB.ExecContext(ctx,
+		"UPDATE trusted_devices SET revoked_at = $3 WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		id, userID, now,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected(result)
+}
+
+func scanTrustedDevice(row rowScanner) (*models.TrustedDevice, error) {
+	var device models.TrustedDevice
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&device.ID, &device.UserID, &device.CreatedAt, &device.ExpiresAt, &lastUsedAt,
+		&device.IP, &device.UserAgent,
+	); err != nil {
+		return nil, err
+	}
+	device.LastUsedAt = nullableTime(lastUsedAt)
+
+	return &device, nil
+}
@@ -3,46 +3,136 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"identity-service/auth"
+	"identity-service/database"
 	"identity-service/models"
 	"strings"
+	"time"
 )
 
 type userRepository struct {
-	DB *sql.DB
+	DB *database.Database
 }
 
-// NewUserRepository creates a new UserRepository instance
-func NewUserRepository(db *sql.DB) UserRepository {
+// NewUserRepository creates a new UserRepository instance.
+// Every query except EmailExists and Create is scoped to a single organization
+// and additionally runs under the tenant's row-level security policies.
+func NewUserRepository(db *database.Database) UserRepository {
 	return &userRepository{DB: db}
 }
 
-func (r *userRepository) GetAll(ctx context.Context) ([]models.User, error) {
-	rows, err := r.DB.QueryContext(ctx, "SELECT id, name, email FROM users ORDER BY id")
-	if err != nil {
-		return nil, err
+// GetAll returns the organization's members in id order. attributes, when
+// not empty, is a JSON object every returned member's attributes contain.
+func (r *userRepository) GetAll(ctx context.Context, orgID int, attributes map[string]interface{}) ([]models.User, error) {
+	contains := []byte("{}")
+	if len(attributes) > 0 {
+		var err error
+		if contains, err = json.Marshal(attributes); err != nil {
+			return nil, err
+		}
 	}
-	defer rows.Close()
 
 	var users []models.User
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email); err != nil {
-			return nil, err
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+userColumns+`
+			FROM users u
+			JOIN organization_members om ON om.user_id = u.id
+			WHERE om.organization_id = $1 AND om.attributes @> $2::jsonb
+			ORDER BY u.id
+		`, orgID, contains)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			user, err := scanUser(rows)
+			if err != nil {
+				return err
+			}
+			users = append(users, *user)
 		}
-		users = append(users, user)
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return users, nil
 }
 
-func (r *userRepository) Create(ctx context.Context, name, email string) (*models.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, orgID, id int) (*models.User, error) {
+	var user *models.User
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRowContext(ctx, `
+			SELECT `+userColumns+`
+			FROM users u
+			JOIN organization_members om ON om.user_id = u.id
+			WHERE om.organization_id = $1 AND u.id = $2
+		`, orgID, id))
+		return err
+	})
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Create inserts a global user identity, its first membership, the
+// platform-wide defaultRole and, when passwordHash is not empty, the first
+// password, and publishes user.created in the same transaction so a
+// failure never leaves a half-created user behind. The new row has no
+// membership until the second insert, so this runs outside RLS.
+func (r *userRepository) Create(ctx context.Context, orgID int, name, email, passwordHash, defaultRole string, now time.Time) (*models.User, error) {
 	var user models.User
-	err := r.DB.QueryRowContext(
-		ctx,
-		"INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, name, email",
-		name, email,
-	).Scan(&user.ID, &user.Name, &user.Email)
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (name, email, password_hash, password_changed_at)
+			VALUES ($1, $2, NULLIF($3, ''), CASE WHEN $3 <> '' THEN $4::timestamp END)
+			RETURNING id, name, email
+		`, name, email, passwordHash, now).Scan(&user.ID, &user.Name, &user.Email)
+		if err != nil {
+			return err
+		}
+		user.Active = true
+		user.Attributes = map[string]interface{}{}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO organization_members (organization_id, user_id) VALUES ($1, $2)",
+			orgID, user.ID,
+		); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_roles (user_id, role_id, organization_id)
+			SELECT $1, id, NULL FROM roles WHERE name = $2
+		`, user.ID, defaultRole); err != nil {
+			return err
+		}
+
+		if passwordHash != "" {
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, $3)",
+				user.ID, passwordHash, now,
+			); err != nil {
+				return err
+			}
+		}
 
+		return insertOutboxEvent(ctx, tx, models.EventUserCreated, orgID, user)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -50,13 +140,204 @@ func (r *userRepository) Create(ctx context.Context, name, email string) (*model
 	return &user, nil
 }
 
+const userColumns = `
+	u.id, u.name, u.email, COALESCE(u.display_name, ''), COALESCE(u.avatar_url, ''),
+	COALESCE(u.locale, ''), COALESCE(u.time_zone, ''), om.attributes, om.active, u.locked_until,
+	u.disabled_at, u.email_verified_at IS NOT NULL
+`
+
+// scanUser reads userColumns
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var lockedUntil, disabledAt sql.NullTime
+	var attributes []byte
+	if err := row.Scan(
+		&user.ID, &user.Name, &user.Email, &user.DisplayName, &user.AvatarURL,
+		&user.Locale, &user.TimeZone, &attributes, &user.Active, &lockedUntil,
+		&disabledAt, &user.EmailVerified,
+	); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(attributes, &user.Attributes); err != nil {
+		return nil, err
+	}
+	user.LockedUntil = nullableTime(lockedUntil)
+	user.DisabledAt = nullableTime(disabledAt)
+	return &user, nil
+}
+
+// Update changes a member's name and email. Email is a global identity
+// attribute, so the change is visible in every organization of the user.
+// A changed address is no longer verified.
+func (r *userRepository) Update(ctx context.Context, orgID, id int, name, email string, now time.Time) (bool, error) {
+	var updated bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users u SET name = $3, email = $4,
+				email_verified_at = CASE WHEN u.email = $4 THEN u.email_verified_at END
+			FROM organization_members om
+			WHERE om.user_id = u.id AND om.organization_id = $1 AND u.id = $2
+		`, orgID, id, name, strings.ToLower(email))
+		if isUniqueViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+		if updated, err = rowsAffected(result); err != nil || !updated {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx,
+			"UPDATE organization_members SET updated_at = $3 WHERE organization_id = $1 AND user_id = $2",
+			orgID, id, now,
+		)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return updated, nil
+}
+
+// SetActive deactivates or reactivates a membership. It reports false when
+// the user is not a member or already in that state.
+func (r *userRepository) SetActive(ctx context.Context, orgID, id int, active bool, now time.Time) (bool, error) {
+	var changed bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE organization_members SET active = $3, updated_at = $4
+			WHERE organization_id = $1 AND user_id = $2 AND active <> $3
+		`, orgID, id, active, now)
+		if err != nil {
+			return err
+		}
+		changed, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return changed, nil
+}
+
+// SetDisabled disables or re-enables a member's account. It reports false
+// when the user is not a member or already in that state.
+func (r *userRepository) SetDisabled(ctx context.Context, orgID, id int, disabled bool, now time.Time) (bool, error) {
+	var changed bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users u SET disabled_at = CASE WHEN $3 THEN $4::timestamp END
+			FROM organization_members om
+			WHERE om.user_id = u.id AND om.organization_id = $1 AND u.id = $2
+				AND (u.disabled_at IS NOT NULL) <> $3
+		`, orgID, id, disabled, now)
+		if err != nil {
+			return err
+		}
+		changed, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return changed, nil
+}
+
+// RequirePasswordChange makes a member change their password before their
+// next sign-in. It reports false when the user is not a member or the
+// change is already required.
+func (r *userRepository) RequirePasswordChange(ctx context.Context, orgID, id int) (bool, error) {
+	var changed bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users u SET password_change_required = TRUE
+			FROM organization_members om
+			WHERE om.user_id = u.id AND om.organization_id = $1 AND u.id = $2
+				AND NOT u.password_change_required
+		`, orgID, id)
+		if err != nil {
+			return err
+		}
+		changed, err = rowsAffected(result)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return changed, nil
+}
+
+// RemoveMember removes a user from an organization together with their
+// roles and groups there. A user left without any organization is deleted,
+// which also ends their sessions and API keys, and user.deleted is
+// published to the organization. Other tenants' memberships are invisible
+// under RLS, so this runs outside it.
+func (r *userRepository) RemoveMember(ctx context.Context, orgID, id int) (bool, error) {
+	var removed bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2",
+			orgID, id,
+		)
+		if err != nil {
+			return err
+		}
+		if removed, err = rowsAffected(result); err != nil || !removed {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM user_roles WHERE organization_id = $1 AND user_id = $2",
+			orgID, id,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM group_members WHERE organization_id = $1 AND user_id = $2",
+			orgID, id,
+		); err != nil {
+			return err
+		}
+
+		var email string
+		err = tx.QueryRowContext(ctx, `
+			DELETE FROM users u
+			WHERE u.id = $1 AND NOT EXISTS (SELECT 1 FROM organization_members om WHERE om.user_id = u.id)
+			RETURNING u.email
+		`, id).Scan(&email)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return insertOutboxEvent(ctx, tx, models.EventUserDeleted, orgID, map[string]interface{}{
+			"user_id": id,
+			"email":   email,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed, nil
+}
+
+// EmailExists checks global email uniqueness across all tenants
 func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, error) {
 	var exists bool
-	err := r.DB.QueryRowContext(
-		ctx,
-		"SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)",
-		strings.ToLower(email),
-	).Scan(&exists)
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(
+			ctx,
+			"SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)",
+			strings.ToLower(email),
+		).Scan(&exists)
+	})
 
 	if err != nil {
 		return false, err
@@ -64,3 +345,188 @@ func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, e
 
 	return exists, nil
 }
+
+// MemberElsewhere reports whether a user also belongs to an organization
+// other than orgID. Those memberships are invisible under RLS, so this
+// runs outside it.
+func (r *userRepository) MemberElsewhere(ctx context.Context, orgID, id int) (bool, error) {
+	var elsewhere bool
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			SELECT EXISTS(
+				SELECT 1 FROM organization_members
+				WHERE user_id = $2 AND organization_id <> $1
+			)
+		`, orgID, id).Scan(&elsewhere)
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return elsewhere, nil
+}
+
+// ImportBatch creates or updates a batch of users in one transaction,
+// matching existing users by email. Only users whose sole membership is
+// the organization are updated; any other existing user is left alone
+// and reported as a conflict, since their name and password are shared
+// with organizations the importer does not manage. In a dry run nothing
+// is written and the outcomes describe what would happen.
+func (r *userRepository) ImportBatch(ctx context.Context, orgID int, entries []models.UserImportEntry, now time.Time, keepHistory int, dryRun bool) ([]models.UserImportOutcome, error) {
+	outcomes := make([]models.UserImportOutcome, 0, len(entries))
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		for _, entry := range entries {
+			outcome := models.UserImportOutcome{Line: entry.Line, Email: entry.Email}
+
+			var member bool
+			err := tx.QueryRowContext(ctx, `
+				SELECT u.id, EXISTS(
+					SELECT 1 FROM organization_members om
+					WHERE om.organization_id = $2 AND om.user_id = u.id
+				) AND NOT EXISTS(
+					SELECT 1 FROM organization_members om
+					WHERE om.organization_id <> $2 AND om.user_id = u.id
+				)
+				FROM users u WHERE u.email = $1
+				FOR UPDATE
+			`, entry.Email, orgID).Scan(&outcome.UserID, &member)
+
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				outcome.Action = models.UserImportCreated
+				if !dryRun {
+					if outcome.UserID, err = importCreate(ctx, tx, orgID, entry); err != nil {
+						return err
+					}
+				}
+			case err != nil:
+				return err
+			case !member:
+				outcome.Action = models.UserImportConflict
+			default:
+				outcome.Action = models.UserImportUpdated
+				if !dryRun {
+					if _, err := tx.ExecContext(ctx, "UPDATE users SET name = $2 WHERE id = $1", outcome.UserID, entry.Name); err != nil {
+						return err
+					}
+				}
+			}
+
+			if !dryRun && entry.PasswordHash != "" && outcome.Action != models.UserImportConflict {
+				if err := importPassword(ctx, tx, outcome.UserID, entry.PasswordHash, now, keepHistory); err != nil {
+					return err
+				}
+			}
+			outcomes = append(outcomes, outcome)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
+
+// importCreate inserts a user with its membership and default role, and
+// publishes user.created as Create does
+func importCreate(ctx context.Context, tx *sql.Tx, orgID int, entry models.UserImportEntry) (int, error) {
+	var user models.User
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO users (name, email) VALUES ($1, $2) RETURNING id, name, email",
+		entry.Name, entry.Email,
+	).Scan(&user.ID, &user.Name, &user.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO organization_members (organization_id, user_id) VALUES ($1, $2)",
+		orgID, user.ID,
+	); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1::int, id FROM roles WHERE name = $2
+		ON CONFLICT DO NOTHING
+	`, user.ID, auth.RoleUser); err != nil {
+		return 0, err
+	}
+
+	return user.ID, insertOutboxEvent(ctx, tx, models.EventUserCreated, orgID, user)
+}
+
+// importPassword sets a password the way CredentialRepository.SetPassword
+// does and signs the user out everywhere
+func importPassword(ctx context.Context, tx *sql.Tx, userID int, hash string, now time.Time, keepHistory int) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users SET password_hash = $2, password_changed_at = $3, password_change_required = FALSE
+		WHERE id = $1
+	`, userID, hash, now); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, $3)",
+		userID, hash, now,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2
+		)
+	`, userID, keepHistory); err != nil {
+		return err
+	}
+
+	_, err := revokeSessions(ctx, tx, userID, 0, now)
+	return err
+}
+
+// Export streams every member of an organization to fn, in id order,
+// without loading them all into memory
+func (r *userRepository) Export(ctx context.Context, orgID int, fn func(*models.UserExportRecord) error) error {
+	return r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT u.id, u.name, u.email, u.created_at, u.locked_until, u.password_changed_at
+			FROM users u
+			JOIN organization_members om ON om.user_id = u.id
+			WHERE om.organization_id = $1
+			ORDER BY u.id
+		`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var record models.UserExportRecord
+			var createdAt, lockedUntil, changedAt sql.NullTime
+			if err := rows.Scan(&record.ID, &record.Name, &record.Email, &createdAt, &lockedUntil, &changedAt); err != nil {
+				return err
+			}
+			record.CreatedAt = nullableTime(createdAt)
+			record.LockedUntil = nullableTime(lockedUntil)
+			record.PasswordChangedAt = nullableTime(changedAt)
+
+			if err := fn(&record); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	})
+}
+
+func nullableTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"identity-service/database"
+	"identity-service/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const webhookDeliveryColumns = `
+	d.id, d.event_id, o.event_type, d.endpoint_id, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at
+`
+
+type webhookRepository struct {
+	DB *database.Database
+}
+
+// NewWebhookRepository creates a new WebhookRepository instance.
+// Endpoints and deliveries are managed under the organization's row-level
+// security; the dispatcher works across every organization and bypasses it.
+func NewWebhookRepository(db *database.Database) WebhookRepository {
+	return &webhookRepository{DB: db}
+}
+
+func (r *webhookRepository) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint, secret string) (*models.WebhookEndpoint, error) {
+	created := *endpoint
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO webhook_endpoints (organization_id, url, secret, event_types)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, active, created_at
+		`, endpoint.OrganizationID, endpoint.URL, secret, pq.Array(endpoint.EventTypes),
+		).Scan(&created.ID, &created.Active, &created.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (r *webhookRepository) GetEndpoints(ctx context.Context, orgID int) ([]models.WebhookEndpoint, error) {
+	endpoints := []models.WebhookEndpoint{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, organization_id, url, event_types, active, created_at
+			FROM webhook_endpoints
+			WHERE organization_id = $1
+			ORDER BY id
+		`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var ep models.WebhookEndpoint
+			if err := rows.Scan(&ep.ID, &ep.OrganizationID, &ep.URL, pq.Array(&ep.EventTypes), &ep.Active, &ep.CreatedAt); err != nil {
+				return err
+			}
+			endpoints = append(endpoints, ep)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (r *webhookRepository) DeleteEndpoint(ctx context.Context, orgID, id int) (bool, error) {
+	var deleted bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"DELETE FROM webhook_endpoints WHERE organization_id = $1 AND id = $2",
+			orgID, id,
+		)
+		if err != nil {
+			return err
+		}
+		deleted, err = rowsAffected(result)
+		return err
+	})
+	return deleted, err
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, orgID int, status string, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT `+webhookDeliveryColumns+`
+			FROM webhook_deliveries d
+			JOIN outbox_events o ON o.id = d.event_id
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE e.organization_id = $1 AND ($2 = '' OR d.status = $2)
+			ORDER BY d.id DESC
+			LIMIT $3
+		`, orgID, status, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d models.WebhookDelivery
+			var statusCode sql.NullInt64
+			var lastError sql.NullString
+			var deliveredAt sql.NullTime
+
+			if err := rows.Scan(
+				&d.ID, &d.EventID, &d.EventType, &d.EndpointID, &d.Status, &d.Attempts,
+				&d.NextAttemptAt, &statusCode, &lastError, &deliveredAt, &d.CreatedAt,
+			); err != nil {
+				return err
+			}
+
+			d.LastStatusCode = nullableInt(statusCode)
+			if lastError.Valid {
+				d.LastError = &lastError.String
+			}
+			if deliveredAt.Valid {
+				d.DeliveredAt = &deliveredAt.Time
+			}
+			deliveries = append(deliveries, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) RetryDelivery(ctx context.Context, orgID int, id int64, now time.Time) (bool, error) {
+	var retried bool
+	err := r.DB.TenantTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries d
+			SET status = 'pending', attempts = 0, next_attempt_at = $3
+			FROM webhook_endpoints e
+			WHERE e.id = d.endpoint_id AND e.organization_id = $1 AND d.id = $2 AND d.status = 'dead'
+		`, orgID, id, now)
+		if err != nil {
+			return err
+		}
+		retried, err = rowsAffected(result)
+		return err
+	})
+	return retried, err
+}
+
+// FanOut turns undispatched outbox events into one pending delivery per
+// subscribed endpoint. SKIP LOCKED lets several replicas share the work.
+func (r *webhookRepository) FanOut(ctx context.Context, now time.Time, limit int) (int, error) {
+	var n int64
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			WITH batch AS (
+				SELECT id, event_type, organization_id
+				FROM outbox_events
+				WHERE dispatched_at IS NULL
+				ORDER BY id
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			), fanout AS (
+				INSERT INTO webhook_deliveries (event_id, endpoint_id, next_attempt_at)
+				SELECT b.id, e.id, $1::timestamp
+				FROM batch b
+				JOIN webhook_endpoints e ON e.organization_id = b.organization_id
+				WHERE e.active AND (cardinality(e.event_types) = 0 OR b.event_type = ANY(e.event_types))
+				ON CONFLICT DO NOTHING
+			)
+			UPDATE outbox_events SET dispatched_at = $1::timestamp
+			WHERE id IN (SELECT id FROM batch)
+		`, now, limit)
+		if err != nil {
+			return err
+		}
+		n, err = result.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
+
+// ClaimDue leases due deliveries by pushing their next attempt past the
+// lease, so a crashed worker's jobs are picked up again afterwards
+func (r *webhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookJob, error) {
+	var jobs []models.WebhookJob
+	err := r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			WITH due AS (
+				SELECT id FROM webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= $1
+				ORDER BY next_attempt_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE webhook_deliveries d
+			SET next_attempt_at = $2
+			FROM due, outbox_events o, webhook_endpoints e
+			WHERE d.id = due.id AND o.id = d.event_id AND e.id = d.endpoint_id
+			RETURNING d.id, d.attempts, e.url, e.secret, o.id, o.event_type,
+				COALESCE(o.organization_id, 0), o.payload, o.created_at
+		`, now, now.Add(lease), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var job models.WebhookJob
+			if err := rows.Scan(
+				&job.DeliveryID, &job.Attempts, &job.URL, &job.Secret, &job.EventID, &job.EventType,
+				&job.OrganizationID, &job.Payload, &job.OccurredAt,
+			); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (r *webhookRepository) MarkSucceeded(ctx context.Context, id int64, statusCode int, now time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'succeeded', attempts = attempts + 1, last_status_code = $2,
+				last_error = NULL, delivered_at = $3
+			WHERE id = $1
+		`, id, statusCode, now)
+		return err
+	})
+}
+
+// MarkFailed records a failed attempt; a nil nextAttempt dead-letters the delivery
+func (r *webhookRepository) MarkFailed(ctx context.Context, id int64, statusCode int, lastError string, nextAttempt *time.Time) error {
+	return r.DB.BypassTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1,
+				last_status_code = NULLIF($2::int, 0),
+				last_error = $3,
+				status = CASE WHEN $4::timestamp IS NULL THEN 'dead' ELSE 'pending' END,
+				next_attempt_at = COALESCE($4::timestamp, next_attempt_at)
+			WHERE id = $1
+		`, id, statusCode, lastError, nextAttempt)
+		return err
+	})
+}
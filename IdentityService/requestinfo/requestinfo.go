@@ -0,0 +1,27 @@
+package requestinfo
+
+import (
+	"context"
+)
+
+type infoKey struct{}
+
+// Info describes the HTTP request a piece of work is running on behalf of.
+// TrustedDevice is the trusted device token the client presented, if any.
+type Info struct {
+	ID            string
+	IP            string
+	UserAgent     string
+	TrustedDevice string
+}
+
+// With returns a copy of ctx carrying the request info
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+// From returns the request info stored in ctx, or the zero Info
+func From(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey{}).(Info)
+	return info
+}
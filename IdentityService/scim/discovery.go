@@ -0,0 +1,123 @@
+package scim
+
+// Discovery documents (RFC 7644 section 4). They describe what this
+// implementation supports and are the same for every tenant.
+
+// ServiceProviderConfig returns the service provider configuration.
+// baseURL is the SCIM root, such as https://id.example.com/scim/v2.
+func ServiceProviderConfig(baseURL string) map[string]interface{} {
+	unsupported := map[string]interface{}{"supported": false}
+	return map[string]interface{}{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          map[string]interface{}{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": MaxCount},
+		"changePassword": unsupported,
+		"sort":           unsupported,
+		"etag":           unsupported,
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "A SCIM token issued to the organization, sent as an Authorization bearer token",
+			"primary":     true,
+		}},
+		"meta": map[string]interface{}{
+			"resourceType": "ServiceProviderConfig",
+			"location":     baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+// ResourceTypes returns the supported resource types
+func ResourceTypes(baseURL string) []map[string]interface{} {
+	resourceType := func(name, endpoint, schema string) map[string]interface{} {
+		return map[string]interface{}{
+			"schemas":     []string{SchemaResourceType},
+			"id":          name,
+			"name":        name,
+			"endpoint":    endpoint,
+			"description": name + " account",
+			"schema":      schema,
+			"meta": map[string]interface{}{
+				"resourceType": "ResourceType",
+				"location":     baseURL + "/ResourceTypes/" + name,
+			},
+		}
+	}
+	return []map[string]interface{}{
+		resourceType("User", "/Users", SchemaUser),
+		resourceType("Group", "/Groups", SchemaGroup),
+	}
+}
+
+// Schemas returns the definitions of the supported attributes
+func Schemas(baseURL string) []map[string]interface{} {
+	user := map[string]interface{}{
+		"id":          SchemaUser,
+		"name":        "User",
+		"description": "User account",
+		"attributes": []map[string]interface{}{
+			attribute("userName", "string", "server", "readWrite", false, true),
+			complexAttribute("name", false, false,
+				attribute("formatted", "string", "none", "readWrite", false, false),
+				attribute("familyName", "string", "none", "readWrite", false, false),
+				attribute("givenName", "string", "none", "readWrite", false, false),
+			),
+			attribute("displayName", "string", "none", "readWrite", false, false),
+			complexAttribute("emails", true, false,
+				attribute("value", "string", "none", "readWrite", false, false),
+				attribute("type", "string", "none", "readWrite", false, false),
+				attribute("primary", "boolean", "none", "readWrite", false, false),
+			),
+			attribute("active", "boolean", "none", "readWrite", false, false),
+			withReturned(attribute("password", "string", "none", "writeOnly", false, false), "never"),
+		},
+	}
+	group := map[string]interface{}{
+		"id":          SchemaGroup,
+		"name":        "Group",
+		"description": "Group",
+		"attributes": []map[string]interface{}{
+			attribute("displayName", "string", "none", "readWrite", false, true),
+			complexAttribute("members", true, false,
+				attribute("value", "string", "none", "immutable", false, false),
+				withReturned(attribute("$ref", "reference", "none", "immutable", false, false), "default"),
+				attribute("display", "string", "none", "readOnly", false, false),
+			),
+		},
+	}
+
+	schemas := []map[string]interface{}{user, group}
+	for _, schema := range schemas {
+		schema["schemas"] = []string{SchemaSchema}
+		schema["meta"] = map[string]interface{}{
+			"resourceType": "Schema",
+			"location":     baseURL + "/Schemas/" + schema["id"].(string),
+		}
+	}
+	return schemas
+}
+
+func attribute(name, kind, uniqueness, mutability string, multiValued, required bool) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"type":        kind,
+		"multiValued": multiValued,
+		"required":    required,
+		"caseExact":   false,
+		"mutability":  mutability,
+		"returned":    "default",
+		"uniqueness":  uniqueness,
+	}
+}
+
+func complexAttribute(name string, multiValued, required bool, subAttributes ...map[string]interface{}) map[string]interface{} {
+	attr := attribute(name, "complex", "none", "readWrite", multiValued, required)
+	attr["subAttributes"] = subAttributes
+	return attr
+}
+
+func withReturned(attr map[string]interface{}, returned string) map[string]interface{} {
+	attr["returned"] = returned
+	return attr
+}
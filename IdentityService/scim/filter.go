@@ -0,0 +1,482 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed filter expression (RFC 7644 section 3.4.2.2),
+// evaluated against resources in their generic JSON form
+type Filter interface {
+	Match(resource map[string]interface{}) bool
+}
+
+// caseExact lists the attributes compared case-sensitively; every other
+// string attribute of the core schemas is case-insensitive
+var caseExact = map[string]bool{"id": true, "externalid": true}
+
+// AttrPath is an attribute with an optional sub-attribute, such as
+// name.givenName. Names are kept as written and matched case-insensitively.
+type AttrPath struct {
+	Attr string
+	Sub  string
+}
+
+func parseAttrPath(s string) AttrPath {
+	s = stripSchema(s)
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		// Extension attributes are not supported and never match
+		return AttrPath{Attr: s}
+	}
+	attr, sub, _ := strings.Cut(s, ".")
+	return AttrPath{Attr: attr, Sub: sub}
+}
+
+func (p AttrPath) String() string {
+	if p.Sub == "" {
+		return p.Attr
+	}
+	return p.Attr + "." + p.Sub
+}
+
+// values returns the values the path selects. Elements of a multi-valued
+// attribute are flattened, and a complex element without a sub-attribute
+// stands for its "value".
+func (p AttrPath) values(resource map[string]interface{}) []interface{} {
+	key, ok := lookupKey(resource, p.Attr)
+	if !ok {
+		return nil
+	}
+
+	var out []interface{}
+	collect := func(v interface{}) {
+		if m, ok := v.(map[string]interface{}); ok {
+			sub := p.Sub
+			if sub == "" {
+				sub = "value"
+			}
+			if key, ok := lookupKey(m, sub); ok {
+				out = append(out, m[key])
+			}
+			return
+		}
+		if p.Sub == "" && v != nil {
+			out = append(out, v)
+		}
+	}
+
+	if list, ok := resource[key].([]interface{}); ok {
+		for _, v := range list {
+			collect(v)
+		}
+		return out
+	}
+	collect(resource[key])
+	return out
+}
+
+type compareFilter struct {
+	path  AttrPath
+	op    string
+	value interface{}
+}
+
+func (f *compareFilter) Match(resource map[string]interface{}) bool {
+	values := f.path.values(resource)
+	if f.op == "pr" {
+		for _, v := range values {
+			if s, ok := v.(string); !ok || s != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if f.op == "ne" {
+		return !(&compareFilter{path: f.path, op: "eq", value: f.value}).Match(resource)
+	}
+	if f.value == nil {
+		// "eq null" matches an absent attribute
+		return f.op == "eq" && len(values) == 0
+	}
+
+	exact := caseExact[strings.ToLower(f.path.Attr)] && f.path.Sub == ""
+	for _, v := range values {
+		if compare(v, f.op, f.value, exact) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare applies a comparison operator to one attribute value
+func compare(actual interface{}, op string, expected interface{}, exact bool) bool {
+	switch want := expected.(type) {
+	case string:
+		got, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		// Timestamps order chronologically rather than lexically
+		if gotTime, err := time.Parse(time.RFC3339, got); err == nil {
+			if wantTime, err := time.Parse(time.RFC3339, want); err == nil {
+				return ordered(gotTime.Compare(wantTime), op)
+			}
+		}
+		if !exact {
+			got, want = strings.ToLower(got), strings.ToLower(want)
+		}
+		switch op {
+		case "co":
+			return strings.Contains(got, want)
+		case "sw":
+			return strings.HasPrefix(got, want)
+		case "ew":
+			return strings.HasSuffix(got, want)
+		}
+		return ordered(strings.Compare(got, want), op)
+
+	case float64:
+		got, ok := actual.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case got < want:
+			return ordered(-1, op)
+		case got > want:
+			return ordered(1, op)
+		}
+		return ordered(0, op)
+
+	case bool:
+		got, ok := actual.(bool)
+		return ok && op == "eq" && got == want
+	}
+	return false
+}
+
+// ordered reports whether a three-way comparison result satisfies op
+func ordered(cmp int, op string) bool {
+	switch op {
+	case "eq":
+		return cmp == 0
+	case "gt":
+		return cmp > 0
+	case "ge":
+		return cmp >= 0
+	case "lt":
+		return cmp < 0
+	case "le":
+		return cmp <= 0
+	}
+	return false
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right Filter
+}
+
+func (f *logicalFilter) Match(resource map[string]interface{}) bool {
+	if f.and {
+		return f.left.Match(resource) && f.right.Match(resource)
+	}
+	return f.left.Match(resource) || f.right.Match(resource)
+}
+
+type notFilter struct {
+	inner Filter
+}
+
+func (f *notFilter) Match(resource map[string]interface{}) bool {
+	return !f.inner.Match(resource)
+}
+
+// valuePathFilter matches when an element of a multi-valued attribute
+// satisfies the inner filter, as in emails[type eq "work"]
+type valuePathFilter struct {
+	attr  string
+	inner Filter
+}
+
+func (f *valuePathFilter) Match(resource map[string]interface{}) bool {
+	for _, element := range elements(resource, f.attr) {
+		if f.inner.Match(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// elements returns the complex values of an attribute
+func elements(resource map[string]interface{}, attr string) []map[string]interface{} {
+	key, ok := lookupKey(resource, attr)
+	if !ok {
+		return nil
+	}
+
+	switch v := resource[key].(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(v))
+		for _, element := range v {
+			if m, ok := element.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// EqualValue returns the value f requires attr to equal, if f is a single
+// "attr eq value" comparison on that top-level attribute. Stores use it to
+// look up one resource instead of scanning them all.
+func EqualValue(f Filter, attr string) (string, bool) {
+	cmp, ok := f.(*compareFilter)
+	if !ok || cmp.op != "eq" || cmp.path.Sub != "" || !strings.EqualFold(cmp.path.Attr, attr) {
+		return "", false
+	}
+	value, ok := cmp.value.(string)
+	return value, ok
+}
+
+// ParseFilter parses a filter expression
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, badRequest(ErrInvalidFilter, "unexpected %q", tok.text)
+	}
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenNumber
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, text: "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, text: "]"})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(input) && input[end] != '"'; end++ {
+				if input[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(input) {
+				return nil, badRequest(ErrInvalidFilter, "unterminated string")
+			}
+			var s string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &s); err != nil {
+				return nil, badRequest(ErrInvalidFilter, "invalid string %s", input[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: input[i : end+1], value: s})
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(input) && strings.IndexByte("0123456789.eE+-", input[end]) >= 0 {
+				end++
+			}
+			var n float64
+			if err := json.Unmarshal([]byte(input[i:end]), &n); err != nil {
+				return nil, badRequest(ErrInvalidFilter, "invalid number %s", input[i:end])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[i:end], value: n})
+			i = end
+		case isWordByte(rune(c)):
+			end := i
+			for end < len(input) && isWordByte(rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[i:end]})
+			i = end
+		default:
+			return nil, badRequest(ErrInvalidFilter, "unexpected character %q", c)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isWordByte(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_$:.", r)
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given case-insensitive word
+func (p *filterParser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, word)
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (Filter, error) {
+	if p.keyword("not") && p.tokens[p.pos+1].kind == tokenLParen {
+		p.next()
+		inner, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{inner: inner}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *filterParser) parseAtom() (Filter, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, badRequest(ErrInvalidFilter, "missing )")
+		}
+		return inner, nil
+	case tokenWord:
+	default:
+		return nil, badRequest(ErrInvalidFilter, "expected an attribute, found %q", tok.text)
+	}
+
+	path := parseAttrPath(tok.text)
+	if p.peek().kind == tokenLBracket {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRBracket {
+			return nil, badRequest(ErrInvalidFilter, "missing ]")
+		}
+		return &valuePathFilter{attr: path.Attr, inner: inner}, nil
+	}
+
+	opTok := p.next()
+	op := strings.ToLower(opTok.text)
+	if opTok.kind != tokenWord {
+		return nil, badRequest(ErrInvalidFilter, "expected an operator after %s", path)
+	}
+	switch op {
+	case "pr":
+		return &compareFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+	default:
+		return nil, badRequest(ErrInvalidFilter, "unknown operator %q", opTok.text)
+	}
+
+	valueTok := p.next()
+	var value interface{}
+	switch valueTok.kind {
+	case tokenString, tokenNumber:
+		value = valueTok.value
+	case tokenWord:
+		switch strings.ToLower(valueTok.text) {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			return nil, badRequest(ErrInvalidFilter, "invalid value %q", valueTok.text)
+		}
+	default:
+		return nil, badRequest(ErrInvalidFilter, "expected a value after %s %s", path, op)
+	}
+
+	if _, isString := value.(string); !isString && (op == "co" || op == "sw" || op == "ew") {
+		return nil, badRequest(ErrInvalidFilter, "%s needs a string value", op)
+	}
+	return &compareFilter{path: path, op: op, value: value}, nil
+}
@@ -0,0 +1,328 @@
+package scim
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Path is the target of a PATCH operation (RFC 7644 section 3.5.2): an
+// attribute, optionally narrowed to the elements matching Filter, and
+// optionally a sub-attribute of it or of those elements.
+type Path struct {
+	Attr   string
+	Filter Filter
+	// seed holds what Filter requires of an element, used to create the
+	// element when an add or replace matches none
+	seed map[string]interface{}
+	Sub  string
+}
+
+// ParsePath parses a PATCH path such as members, name.givenName or
+// emails[type eq "work"].value
+func ParsePath(s string) (Path, error) {
+	s = strings.TrimSpace(s)
+	open := strings.IndexByte(s, '[')
+	if open < 0 {
+		attrPath := parseAttrPath(s)
+		if attrPath.Attr == "" {
+			return Path{}, badRequest(ErrInvalidPath, "invalid path %q", s)
+		}
+		return Path{Attr: attrPath.Attr, Sub: attrPath.Sub}, nil
+	}
+
+	closing := strings.LastIndexByte(s, ']')
+	if closing < open {
+		return Path{}, badRequest(ErrInvalidPath, "invalid path %q", s)
+	}
+	filter, err := ParseFilter(s[open+1 : closing])
+	if err != nil {
+		return Path{}, badRequest(ErrInvalidPath, "invalid filter in path %q", s)
+	}
+
+	path := Path{Attr: parseAttrPath(s[:open]).Attr, Filter: filter, seed: seedOf(filter)}
+	if rest := s[closing+1:]; rest != "" {
+		sub, ok := strings.CutPrefix(rest, ".")
+		if !ok || sub == "" {
+			return Path{}, badRequest(ErrInvalidPath, "invalid path %q", s)
+		}
+		path.Sub = sub
+	}
+	return path, nil
+}
+
+// seedOf returns the attribute values an "eq" filter, or a conjunction of
+// them, requires; nil when the filter says anything else
+func seedOf(f Filter) map[string]interface{} {
+	switch v := f.(type) {
+	case *compareFilter:
+		if v.op == "eq" && v.path.Sub == "" && v.value != nil {
+			return map[string]interface{}{v.path.Attr: v.value}
+		}
+	case *logicalFilter:
+		if v.and {
+			left, right := seedOf(v.left), seedOf(v.right)
+			if left == nil || right == nil {
+				return nil
+			}
+			for key, value := range right {
+				left[key] = value
+			}
+			return left
+		}
+	}
+	return nil
+}
+
+// Apply applies PATCH operations in order to a resource in its generic
+// JSON form. The caller decides which attributes may change.
+func Apply(resource map[string]interface{}, ops []PatchOperation) error {
+	if len(ops) == 0 {
+		return badRequest(ErrInvalidValue, "no operations")
+	}
+
+	for _, op := range ops {
+		var value interface{}
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return badRequest(ErrInvalidSyntax, "invalid value: %s", err.Error())
+			}
+		}
+
+		name := strings.ToLower(op.Op)
+		switch name {
+		case "add", "replace":
+		case "remove":
+			if op.Path == "" {
+				return badRequest(ErrNoTarget, "remove needs a path")
+			}
+		default:
+			return badRequest(ErrInvalidSyntax, "unknown operation %q", op.Op)
+		}
+
+		// Without a path the value is an object of attributes to change
+		if op.Path == "" {
+			attrs, ok := value.(map[string]interface{})
+			if !ok {
+				return badRequest(ErrInvalidValue, "%s without a path needs an object value", name)
+			}
+			for attr, attrValue := range attrs {
+				path, err := ParsePath(attr)
+				if err != nil {
+					return err
+				}
+				if err := apply(resource, name, path, attrValue); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := ParsePath(op.Path)
+		if err != nil {
+			return err
+		}
+		if name != "remove" && len(op.Value) == 0 {
+			return badRequest(ErrInvalidValue, "%s needs a value", name)
+		}
+		if err := apply(resource, name, path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(resource map[string]interface{}, op string, path Path, value interface{}) error {
+	key, exists := lookupKey(resource, path.Attr)
+	if !exists {
+		key = path.Attr
+	}
+
+	if path.Filter != nil {
+		return applyFiltered(resource, key, op, path, value)
+	}
+
+	if path.Sub != "" {
+		if _, isList := resource[key].([]interface{}); isList {
+			return badRequest(ErrInvalidPath, "%s is multi-valued; select elements with a filter", path.Attr)
+		}
+		complexValue, _ := resource[key].(map[string]interface{})
+		if op == "remove" {
+			if complexValue != nil {
+				deleteKey(complexValue, path.Sub)
+			}
+			return nil
+		}
+		if complexValue == nil {
+			complexValue = map[string]interface{}{}
+			resource[key] = complexValue
+		}
+		setKey(complexValue, path.Sub, value)
+		return nil
+	}
+
+	existing := resource[key]
+	switch op {
+	case "remove":
+		// A value narrows the removal to those elements, as some clients
+		// remove group members this way
+		if list, ok := existing.([]interface{}); ok && value != nil {
+			resource[key] = removeValues(list, value)
+			return nil
+		}
+		delete(resource, key)
+
+	case "add":
+		switch current := existing.(type) {
+		case []interface{}:
+			resource[key] = appendValues(current, value)
+		case map[string]interface{}:
+			merge(current, value)
+		default:
+			resource[key] = value
+		}
+
+	case "replace":
+		if current, ok := existing.(map[string]interface{}); ok {
+			merge(current, value)
+			return nil
+		}
+		resource[key] = value
+	}
+	return nil
+}
+
+// applyFiltered applies an operation to the elements of a multi-valued
+// attribute that match the path filter
+func applyFiltered(resource map[string]interface{}, key, op string, path Path, value interface{}) error {
+	list, _ := resource[key].([]interface{})
+
+	matched := 0
+	kept := list[:0:0]
+	for _, element := range list {
+		m, ok := element.(map[string]interface{})
+		if !ok || !path.Filter.Match(m) {
+			kept = append(kept, element)
+			continue
+		}
+		matched++
+
+		switch {
+		case op == "remove" && path.Sub == "":
+			continue
+		case op == "remove":
+			deleteKey(m, path.Sub)
+		case path.Sub != "":
+			setKey(m, path.Sub, value)
+		default:
+			merge(m, value)
+		}
+		kept = append(kept, m)
+	}
+
+	if matched == 0 {
+		if op == "remove" {
+			return nil
+		}
+		// Clients commonly set emails[type eq "work"].value before any
+		// work email exists
+		if path.seed == nil {
+			return badRequest(ErrNoTarget, "no %s element matches the path filter", path.Attr)
+		}
+		element := make(map[string]interface{}, len(path.seed)+1)
+		for seedKey, seedValue := range path.seed {
+			element[seedKey] = seedValue
+		}
+		if path.Sub != "" {
+			setKey(element, path.Sub, value)
+		} else {
+			merge(element, value)
+		}
+		kept = append(kept, element)
+	}
+
+	resource[key] = kept
+	return nil
+}
+
+// merge copies the attributes of value, if it is an object, into m
+func merge(m map[string]interface{}, value interface{}) {
+	attrs, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for attr, attrValue := range attrs {
+		setKey(m, attr, attrValue)
+	}
+}
+
+// appendValues adds values to a multi-valued attribute, skipping any
+// already present
+func appendValues(list []interface{}, value interface{}) []interface{} {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	for _, v := range values {
+		if !containsValue(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// removeValues drops the elements of list that match any of the given values
+func removeValues(list []interface{}, value interface{}) []interface{} {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+
+	kept := list[:0:0]
+	for _, element := range list {
+		if !containsValue(values, element) {
+			kept = append(kept, element)
+		}
+	}
+	return kept
+}
+
+// containsValue reports whether list holds v. Complex values are the same
+// when their "value" sub-attributes are.
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, element := range list {
+		if sameValue(element, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameValue(a, b interface{}) bool {
+	am, aComplex := a.(map[string]interface{})
+	bm, bComplex := b.(map[string]interface{})
+	if aComplex && bComplex {
+		aKey, aOK := lookupKey(am, "value")
+		bKey, bOK := lookupKey(bm, "value")
+		if aOK && bOK {
+			return reflect.DeepEqual(am[aKey], bm[bKey])
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// setKey sets an attribute, reusing the existing spelling of its name
+func setKey(m map[string]interface{}, name string, value interface{}) {
+	if key, ok := lookupKey(m, name); ok {
+		m[key] = value
+		return
+	}
+	m[name] = value
+}
+
+func deleteKey(m map[string]interface{}, name string) {
+	if key, ok := lookupKey(m, name); ok {
+		delete(m, key)
+	}
+}
@@ -0,0 +1,348 @@
+// Package scim implements the protocol side of SCIM 2.0 (RFC 7643 and
+// RFC 7644): resource representations, filters, PATCH operations and the
+// discovery documents. It knows nothing about how resources are stored.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Schema URNs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+)
+
+// Error types from RFC 7644 section 3.12, carried in the scimType field
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrTooMany       = "tooMany"
+	ErrUniqueness    = "uniqueness"
+	ErrMutability    = "mutability"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrNoTarget      = "noTarget"
+	ErrInvalidValue  = "invalidValue"
+)
+
+// Paging limits for list responses
+const (
+	DefaultCount = 100
+	MaxCount     = 1000
+)
+
+// Error is a SCIM error response. It is also returned by this package's
+// parsers so callers can send it as is.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// NewError creates an Error with the given HTTP status
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+// badRequest creates a 400 Error
+func badRequest(scimType, format string, args ...interface{}) *Error {
+	return NewError(http.StatusBadRequest, scimType, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	if e.ScimType != "" {
+		return fmt.Sprintf("scim %s (%s): %s", e.Status, e.ScimType, e.Detail)
+	}
+	return fmt.Sprintf("scim %s: %s", e.Status, e.Detail)
+}
+
+// StatusCode returns the HTTP status of the error
+func (e *Error) StatusCode() int {
+	status, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+// Meta is the read-only resource metadata
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// Name is the components of a user's name
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+// MultiValue is an entry of a multi-valued attribute such as emails
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User is the core User resource. Password is write-only.
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      *Bool        `json:"active,omitempty"`
+	Password    string       `json:"password,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// FullName picks the best available display name for the user
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name != nil {
+		if u.Name.Formatted != "" {
+			return u.Name.Formatted
+		}
+		if full := strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName); full != "" {
+			return full
+		}
+	}
+	return u.UserName
+}
+
+// Group is the core Group resource
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+// ListResponse is a page of query results. Resources are kept generic so
+// attributes can be projected per request.
+type ListResponse struct {
+	Schemas      []string                 `json:"schemas"`
+	TotalResults int                      `json:"totalResults"`
+	StartIndex   int                      `json:"startIndex"`
+	ItemsPerPage int                      `json:"itemsPerPage"`
+	Resources    []map[string]interface{} `json:"Resources"`
+}
+
+// PatchRequest is the body of a PATCH request
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is one operation of a PATCH request. Op is matched
+// case-insensitively since some clients send "Replace".
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Bool is a boolean that also accepts the strings "true" and "false" in any
+// case, which some identity providers send
+type Bool bool
+
+// UnmarshalJSON implements json.Unmarshaler
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = Bool(v)
+		return nil
+	case string:
+		parsed, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*b = Bool(parsed)
+		return nil
+	}
+	return fmt.Errorf("invalid boolean %s", data)
+}
+
+// BoolPtr returns a pointer to a Bool holding v
+func BoolPtr(v bool) *Bool {
+	b := Bool(v)
+	return &b
+}
+
+// Query holds the list parameters of RFC 7644 section 3.4.2. A nil
+// Filter matches everything.
+type Query struct {
+	Filter     Filter
+	StartIndex int
+	Count      int
+}
+
+// ParseQuery reads filter, startIndex and count from URL query values
+func ParseQuery(filter, startIndex, count string) (Query, error) {
+	query := Query{StartIndex: 1, Count: DefaultCount}
+
+	if filter != "" {
+		parsed, err := ParseFilter(filter)
+		if err != nil {
+			return query, err
+		}
+		query.Filter = parsed
+	}
+
+	if startIndex != "" {
+		n, err := strconv.Atoi(startIndex)
+		if err != nil {
+			return query, badRequest(ErrInvalidValue, "startIndex must be an integer")
+		}
+		// Values below one are interpreted as one
+		query.StartIndex = max(n, 1)
+	}
+
+	if count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return query, badRequest(ErrInvalidValue, "count must be an integer")
+		}
+		// Negative values are interpreted as zero
+		query.Count = min(max(n, 0), MaxCount)
+	}
+
+	return query, nil
+}
+
+// ToMap converts a resource to its generic JSON form, the form filters,
+// PATCH operations and attribute projection work on
+func ToMap(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FromMap converts the generic JSON form back into a resource
+func FromMap(m map[string]interface{}, resource interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, resource); err != nil {
+		return badRequest(ErrInvalidValue, "%s", err.Error())
+	}
+	return nil
+}
+
+// Project applies the attributes and excludedAttributes parameters to a
+// resource. id, schemas and meta are always returned unless excluded, and
+// id and schemas cannot be excluded.
+func Project(resource map[string]interface{}, attributes, excluded []string) map[string]interface{} {
+	always := map[string]bool{"id": true, "schemas": true}
+
+	if len(attributes) > 0 {
+		keep := map[string]bool{"meta": true}
+		for _, attr := range attributes {
+			keep[strings.ToLower(topLevel(attr))] = true
+		}
+		for key := range resource {
+			lower := strings.ToLower(key)
+			if !always[lower] && !keep[lower] {
+				delete(resource, key)
+			}
+		}
+	}
+
+	for _, attr := range excluded {
+		name := strings.ToLower(topLevel(attr))
+		if always[name] {
+			continue
+		}
+		if key, ok := lookupKey(resource, name); ok {
+			delete(resource, key)
+		}
+	}
+	return resource
+}
+
+// SplitAttributes splits a comma-separated attributes parameter
+func SplitAttributes(value string) []string {
+	var attrs []string
+	for _, attr := range strings.Split(value, ",") {
+		if attr = strings.TrimSpace(attr); attr != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// topLevel returns the top-level attribute of a possibly dotted or
+// schema-qualified attribute path
+func topLevel(attr string) string {
+	attr = stripSchema(attr)
+	name, _, _ := strings.Cut(attr, ".")
+	return name
+}
+
+// stripSchema removes a core schema URN prefix from an attribute path
+func stripSchema(attr string) string {
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(attr) > len(schema) && strings.EqualFold(attr[:len(schema)], schema) && attr[len(schema)] == ':' {
+			return attr[len(schema)+1:]
+		}
+	}
+	return attr
+}
+
+// lookupKey finds a key of m case-insensitively, as SCIM attribute names
+// are case-insensitive
+func lookupKey(m map[string]interface{}, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for key := range m {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/validation"
+)
+
+// API keys look like idk_<12 hex prefix>_<secret>. The prefix is stored in
+// clear to find the key and to let users recognise it; only a hash of the
+// whole key is kept.
+const (
+	apiKeyScheme       = "idk_"
+	apiKeyPrefixBytes  = 6
+	apiKeySecretBytes  = 32
+	apiKeyPrefixLength = apiKeyPrefixBytes * 2
+)
+
+// apiKeyService implements the APIKeyService interface
+type apiKeyService struct {
+	keys      repository.APIKeyRepository
+	roles     repository.RoleRepository
+	authz     AuthorizationService
+	lockout   LockoutService
+	validator *validation.Validator
+	audit     AuditService
+	config    *config.APIKeyConfig
+}
+
+// NewAPIKeyService creates a new APIKeyService instance
+func NewAPIKeyService(
+	keys repository.APIKeyRepository,
+	roles repository.RoleRepository,
+	authz AuthorizationService,
+	lockout LockoutService,
+	validator *validation.Validator,
+	audit AuditService,
+	cfg *config.APIKeyConfig,
+) APIKeyService {
+	return &apiKeyService{
+		keys:      keys,
+		roles:     roles,
+		authz:     authz,
+		lockout:   lockout,
+		validator: validator,
+		audit:     audit,
+		config:    cfg,
+	}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, principal *auth.Principal, name string, scopes []string, expiresAt *time.Time) (*models.CreatedAPIKey, error) {
+	if err := s.validator.ValidateName(name); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+	if len(scopes) == 0 {
+		return nil, apperrors.NewBadRequestError("at least one scope is required", nil)
+	}
+
+	// A key can never grant more than its creator holds right now
+	for _, scope := range scopes {
+		allowed, err := s.authz.Can(ctx, principal, auth.Permission(scope), nil)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, apperrors.NewForbiddenError(fmt.Sprintf("scope %q exceeds your permissions", scope))
+		}
+	}
+
+	now := time.Now().UTC()
+	expiry := now.Add(s.config.DefaultTTL)
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+	}
+	if !expiry.After(now) {
+		return nil, apperrors.NewBadRequestError("expires_at must be in the future", nil)
+	}
+	if expiry.After(now.Add(s.config.MaxTTL)) {
+		return nil, apperrors.NewBadRequestError(fmt.Sprintf("expires_at must be within %s", s.config.MaxTTL), nil)
+	}
+
+	prefixBytes := make([]byte, apiKeyPrefixBytes)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate api key", err)
+	}
+	secret, err := auth.GenerateToken(apiKeySecretBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate api key", err)
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	rawKey := apiKeyScheme + prefix + "_" + secret
+
+	key := &models.APIKey{
+		UserID:    principal.UserID,
+		Name:      name,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		ExpiresAt: expiry,
+		CreatedAt: now,
+	}
+	if principal.OrganizationID != 0 {
+		orgID := principal.OrganizationID
+		key.OrganizationID = &orgID
+	}
+
+	created, err := s.keys.Create(ctx, key, auth.HashToken(rawKey))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create api key", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditAPIKeyCreated,
+		TargetType: "api_key",
+		TargetID:   strconv.Itoa(created.ID),
+		Details: map[string]interface{}{
+			"name":       created.Name,
+			"prefix":     created.Prefix,
+			"scopes":     created.Scopes,
+			"expires_at": created.ExpiresAt,
+		},
+	})
+
+	return &models.CreatedAPIKey{APIKey: *created, Key: rawKey}, nil
+}
+
+func (s *apiKeyService) GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	keys, err := s.keys.GetForUser(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve api keys", err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id int) error {
+	revoked, err := s.keys.Revoke(ctx, userID, id, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke api key", err)
+	}
+	if !revoked {
+		return apperrors.NewNotFoundError("api key not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditAPIKeyRevoked,
+		TargetType: "api_key",
+		TargetID:   strconv.Itoa(id),
+	})
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey, ip string) (*auth.Principal, error) {
+	rest, ok := strings.CutPrefix(rawKey, apiKeyScheme)
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		return nil, err
+	}
+	if len(rest) <= apiKeyPrefixLength || rest[apiKeyPrefixLength] != '_' {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, "", "malformed")
+	}
+	prefix := rest[:apiKeyPrefixLength]
+
+	cred, err := s.keys.GetByPrefix(ctx, prefix)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, prefix, "unknown")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+
+	// A disabled account looks exactly like a wrong key to the caller. The
+	// password lock does not apply: anyone who knows the email can trigger
+	// it, and keys do not involve the password.
+	now := time.Now().UTC()
+	if cred.UserDisabled {
+		return nil, s.authFailed(ctx, prefix, "disabled")
+	}
+
+	// A wrong secret only proves the caller knows a key's public prefix,
+	// so it counts against the address and never locks the owner out
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(rawKey)), []byte(cred.KeyHash)) != 1 {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, prefix, "mismatch")
+	}
+	if cred.RevokedAt != nil {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, prefix, "revoked")
+	}
+	if !cred.ExpiresAt.After(now) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, prefix, "expired")
+	}
+
+	if err := s.keys.RecordUsage(ctx, cred.ID, ip, now); err != nil {
+		return nil, fmt.Errorf("failed to record api key usage: %w", err)
+	}
+
+	principal := &auth.Principal{
+		UserID:   cred.UserID,
+		Email:    cred.UserEmail,
+		APIKeyID: cred.ID,
+		// A nil slice would mean unrestricted; keys are always scoped
+		Scopes: append([]string{}, cred.Scopes...),
+	}
+	if cred.OrganizationID != nil {
+		principal.OrganizationID = *cred.OrganizationID
+	}
+
+	roles, err := s.roles.GetRoleNamesForUser(ctx, principal.OrganizationID, principal.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	principal.Roles = roles
+
+	return principal, nil
+}
+
+// authFailed audits a rejected key and returns the error to report.
+// Only the public prefix is recorded, never the secret.
+func (s *apiKeyService) authFailed(ctx context.Context, prefix, reason string) error {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "api_key",
+		TargetID:   prefix,
+		Details:    map[string]interface{}{"method": "api_key", "reason": reason},
+	})
+	return auth.ErrInvalidCredentials
+}
+
+// Ensure apiKeyService implements APIKeyService interface
+var _ APIKeyService = (*apiKeyService)(nil)
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/validation"
+)
+
+// attributeService implements the AttributeService interface
+type attributeService struct {
+	repo      repository.AttributeRepository
+	validator *validation.Validator
+	audit     AuditService
+}
+
+// NewAttributeService creates a new AttributeService instance
+func NewAttributeService(repo repository.AttributeRepository, validator *validation.Validator, audit AuditService) AttributeService {
+	return &attributeService{
+		repo:      repo,
+		validator: validator,
+		audit:     audit,
+	}
+}
+
+func (s *attributeService) GetDefinitions(ctx context.Context, orgID int) ([]models.AttributeDefinition, error) {
+	defs, err := s.repo.GetDefinitions(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve user attributes", err)
+	}
+	return defs, nil
+}
+
+func (s *attributeService) CreateDefinition(ctx context.Context, orgID int, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	def := attributeDefinitionFromRequest(req)
+	def.OrganizationID = orgID
+	if err := s.validator.ValidateAttributeDefinition(def); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	def.CreatedAt = time.Now().UTC()
+	created, err := s.repo.CreateDefinition(ctx, def)
+	if stderrors.Is(err, repository.ErrConflict) {
+		return nil, apperrors.NewConflictError("user attribute with this name already exists", err)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create user attribute", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditAttributeCreated,
+		OrganizationID: &orgID,
+		TargetType:     "user_attribute",
+		TargetID:       strconv.Itoa(created.ID),
+		Details:        map[string]interface{}{"name": created.Name, "type": created.Type},
+	})
+
+	return created, nil
+}
+
+// UpdateDefinition replaces a definition. Values already stored are not
+// revalidated against the new rules.
+func (s *attributeService) UpdateDefinition(ctx context.Context, orgID, id int, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error) {
+	def := attributeDefinitionFromRequest(req)
+	def.ID = id
+	def.OrganizationID = orgID
+	if err := s.validator.ValidateAttributeDefinition(def); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	def.UpdatedAt = time.Now().UTC()
+	updated, err := s.repo.UpdateDefinition(ctx, def)
+	if stderrors.Is(err, repository.ErrConflict) {
+		return nil, apperrors.NewConflictError("user attribute with this name already exists", err)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to update user attribute", err)
+	}
+	if !updated {
+		return nil, apperrors.NewNotFoundError("user attribute not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditAttributeUpdated,
+		OrganizationID: &orgID,
+		TargetType:     "user_attribute",
+		TargetID:       strconv.Itoa(id),
+		Details:        map[string]interface{}{"name": def.Name, "type": def.Type},
+	})
+
+	return def, nil
+}
+
+// DeleteDefinition removes a definition and every member's value for it
+func (s *attributeService) DeleteDefinition(ctx context.Context, orgID, id int) error {
+	def, err := s.repo.DeleteDefinition(ctx, orgID, id)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return apperrors.NewNotFoundError("user attribute not found")
+	}
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to delete user attribute", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditAttributeDeleted,
+		OrganizationID: &orgID,
+		TargetType:     "user_attribute",
+		TargetID:       strconv.Itoa(id),
+		Details:        map[string]interface{}{"name": def.Name},
+	})
+	return nil
+}
+
+func (s *attributeService) GetAttributes(ctx context.Context, orgID, userID int) (map[string]interface{}, error) {
+	attrs, err := s.repo.GetAttributes(ctx, orgID, userID)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve user attributes", err)
+	}
+	return attrs, nil
+}
+
+// SetAttributes replaces all of a member's attributes after validating
+// them against the organization's schema. Null values are dropped.
+func (s *attributeService) SetAttributes(ctx context.Context, orgID, userID int, attrs map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(attrs))
+	for name, value := range attrs {
+		if value != nil {
+			values[name] = value
+		}
+	}
+
+	defs, err := s.GetDefinitions(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validator.ValidateAttributes(defs, values); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	var unique []string
+	for _, def := range defs {
+		if def.Unique {
+			unique = append(unique, def.Name)
+		}
+	}
+
+	updated, err := s.repo.SetAttributes(ctx, orgID, userID, values, unique, time.Now().UTC())
+	if stderrors.Is(err, repository.ErrConflict) {
+		return nil, apperrors.NewConflictError("attribute value already in use", err)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to update user attributes", err)
+	}
+	if !updated {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditAttributesUpdated,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(userID),
+		Details:        map[string]interface{}{"attributes": names},
+	})
+
+	return values, nil
+}
+
+// GetClaims returns the member's attributes that the schema marks for
+// disclosure in tokens, keyed by attribute name
+func (s *attributeService) GetClaims(ctx context.Context, orgID, userID int) (map[string]interface{}, error) {
+	defs, err := s.GetDefinitions(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.GetAttributes(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	for _, def := range defs {
+		if value, ok := attrs[def.Name]; ok && def.Claim {
+			claims[def.Name] = value
+		}
+	}
+	return claims, nil
+}
+
+func attributeDefinitionFromRequest(req models.AttributeDefinitionRequest) *models.AttributeDefinition {
+	return &models.AttributeDefinition{
+		Name:        strings.TrimSpace(req.Name),
+		Type:        req.Type,
+		Description: strings.TrimSpace(req.Description),
+		Required:    req.Required,
+		Unique:      req.Unique,
+		Pattern:     req.Pattern,
+		Enum:        req.Enum,
+		Claim:       req.Claim,
+	}
+}
+
+// Ensure attributeService implements AttributeService interface
+var _ AttributeService = (*attributeService)(nil)
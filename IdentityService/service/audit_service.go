@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/requestinfo"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+	auditVerifyBatchSize = 1000
+	maxUserAgentLength   = 512
+)
+
+// auditVerifyMargin is kept free before the caller's deadline to answer
+// with a checkpoint instead of timing out
+const auditVerifyMargin = time.Second
+
+// auditService implements the AuditService interface
+type auditService struct {
+	repo repository.AuditRepository
+	log  *slog.Logger
+}
+
+// NewAuditService creates a new AuditService instance
+func NewAuditService(repo repository.AuditRepository, log *slog.Logger) AuditService {
+	return &auditService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+type auditReasonKey struct{}
+
+// WithAuditReason returns a copy of ctx whose audit events record why the
+// action was taken, such as the privacy request an administrator fulfils
+func WithAuditReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, auditReasonKey{}, reason)
+}
+
+// Record appends an event, filling in the actor, tenant and request details
+// from ctx when the caller has not set them. Audit failures are logged
+// rather than failing the operation that has already happened.
+func (s *auditService) Record(ctx context.Context, event models.AuditEvent) {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		// Whoever impersonates the principal is the one acting
+		if principal.Impersonated() {
+			if event.ActorUserID == nil {
+				event.ActorUserID = &principal.ActorUserID
+			}
+			event.Details = withDetail(event.Details, "impersonated_user_id", principal.UserID)
+		}
+		if event.ActorUserID == nil && principal.UserID != 0 {
+			event.ActorUserID = &principal.UserID
+		}
+		if event.ActorAPIKeyID == nil && principal.APIKeyID != 0 {
+			event.ActorAPIKeyID = &principal.APIKeyID
+		}
+		if event.OrganizationID == nil && principal.OrganizationID != 0 {
+			event.OrganizationID = &principal.OrganizationID
+		}
+		// Provisioning tokens have no column of their own
+		if principal.SCIMTokenID != 0 {
+			event.Details = withDetail(event.Details, "scim_token_id", principal.SCIMTokenID)
+		}
+	}
+	if reason, ok := ctx.Value(auditReasonKey{}).(string); ok && reason != "" {
+		event.Details = withDetail(event.Details, "reason", reason)
+	}
+
+	info := requestinfo.From(ctx)
+	event.IP = info.IP
+	event.UserAgent = truncate(info.UserAgent, maxUserAgentLength)
+	event.RequestID = info.ID
+
+	// Postgres stores microseconds; hash exactly what will be read back
+	event.OccurredAt = time.Now().UTC().Truncate(time.Microsecond)
+	if event.Outcome == "" {
+		event.Outcome = models.AuditSuccess
+	}
+
+	details, err := normalizeDetails(event.Details)
+	if err == nil {
+		event.Details = details
+		err = s.repo.Append(ctx, &event, sealAuditEvent)
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to record audit event",
+			slog.String("action", event.Action),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *auditService) Query(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPageSize
+	}
+	if filter.Limit > maxAuditPageSize {
+		filter.Limit = maxAuditPageSize
+	}
+
+	events, err := s.repo.Query(ctx, filter)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve audit events", err)
+	}
+
+	page := &models.AuditPage{Events: events}
+	if len(events) == filter.Limit {
+		cursor := events[len(events)-1].ID
+		page.NextCursor = &cursor
+	}
+
+	return page, nil
+}
+
+// Verify walks the hash chain from the event after afterID, or from the
+// start when it is zero, until it ends or ctx's deadline draws near
+func (s *auditService) Verify(ctx context.Context, afterID int64) (*models.AuditVerification, error) {
+	result := &models.AuditVerification{Valid: true, LastHash: repository.AuditGenesisHash}
+	if afterID > 0 {
+		hash, err := s.repo.GetHash(ctx, afterID)
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.NewBadRequestError("invalid cursor", err)
+		}
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to read audit events", err)
+		}
+		result.LastHash = hash
+	}
+
+	stopAt, hasDeadline := ctx.Deadline()
+	stopAt = stopAt.Add(-auditVerifyMargin)
+	for {
+		if hasDeadline && time.Now().After(stopAt) {
+			cursor := afterID
+			result.NextCursor = &cursor
+			return result, nil
+		}
+
+		events, err := s.repo.GetAfter(ctx, afterID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to read audit events", err)
+		}
+
+		for i := range events {
+			event := &events[i]
+			hash, err := sealAuditEvent(event)
+			if err != nil {
+				return nil, apperrors.NewInternalServerError("failed to hash audit event", err)
+			}
+
+			if event.PrevHash != result.LastHash || event.Hash != hash {
+				result.Valid = false
+				result.BrokenAtID = &event.ID
+				return result, nil
+			}
+
+			result.LastHash = event.Hash
+			result.EventsChecked++
+			afterID = event.ID
+		}
+
+		if len(events) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// sealAuditEvent hashes an event together with its predecessor's hash
+func sealAuditEvent(event *models.AuditEvent) (string, error) {
+	payload, err := json.Marshal(struct {
+		PrevHash       string                 `json:"prev_hash"`
+		OccurredAt     string                 `json:"occurred_at"`
+		Action         string                 `json:"action"`
+		Outcome        string                 `json:"outcome"`
+		ActorUserID    *int                   `json:"actor_user_id"`
+		ActorAPIKeyID  *int                   `json:"actor_api_key_id"`
+		OrganizationID *int                   `json:"organization_id"`
+		TargetType     string                 `json:"target_type"`
+		TargetID       string                 `json:"target_id"`
+		IP             string                 `json:"ip"`
+		UserAgent      string                 `json:"user_agent"`
+		RequestID      string                 `json:"request_id"`
+		Details        map[string]interface{} `json:"details"`
+	}{
+		PrevHash:       event.PrevHash,
+		OccurredAt:     event.OccurredAt.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		Action:         event.Action,
+		Outcome:        event.Outcome,
+		ActorUserID:    event.ActorUserID,
+		ActorAPIKeyID:  event.ActorAPIKeyID,
+		OrganizationID: event.OrganizationID,
+		TargetType:     event.TargetType,
+		TargetID:       event.TargetID,
+		IP:             event.IP,
+		UserAgent:      event.UserAgent,
+		RequestID:      event.RequestID,
+		Details:        event.Details,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// withDetail returns a copy of details with one more entry, leaving the
+// caller's map untouched
+func withDetail(details map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(details)+1)
+	for k, v := range details {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// normalizeDetails round-trips details through JSON so the hashed form
+// matches what is decoded from the database later
+func normalizeDetails(details map[string]interface{}) (map[string]interface{}, error) {
+	normalized := map[string]interface{}{}
+	if details == nil {
+		return normalized, nil
+	}
+
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// truncate shortens s to at most max bytes without splitting a character
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return strings.ToValidUTF8(s[:max], "")
+}
+
+// Ensure auditService implements AuditService interface
+var _ AuditService = (*auditService)(nil)
@@ -0,0 +1,1349 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/mail"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/requestinfo"
+)
+
+// Session tokens look like ids_<secret>; only a hash is stored
+const (
+	sessionTokenScheme = "ids_"
+	sessionTokenBytes  = 32
+)
+
+// Sign-in links carry one secret in the emailed URL and another kept by
+// the device that asked for it; only hashes of both are stored
+const (
+	magicLinkTokenBytes  = 32
+	magicLinkDeviceBytes = 32
+)
+
+// Reasons a correct password does not yet produce a session
+const (
+	ReasonPasswordChangeRequired = "password_change_required"
+	ReasonPasswordExpired        = "password_expired"
+)
+
+// ReasonRiskBlocked marks sign-ins refused for scoring too risky
+const ReasonRiskBlocked = "risk_blocked"
+
+// ReasonImpersonation marks operations refused to impersonation sessions
+const ReasonImpersonation = "impersonation"
+
+// authService implements the AuthService interface
+type authService struct {
+	creds     repository.CredentialRepository
+	sessions  repository.SessionRepository
+	roles     repository.RoleRepository
+	users     repository.UserRepository
+	links     repository.MagicLinkRepository
+	mfa       repository.MFARepository
+	devices   repository.TrustedDeviceRepository
+	passwords PasswordService
+	otp       OTPService
+	risk      RiskService
+	lockout   LockoutService
+	hasher    *auth.PasswordHasher
+	signer    *auth.DeviceTokenSigner
+	mailer    mail.Sender
+	audit     AuditService
+	config    *config.SessionConfig
+	log       *slog.Logger
+	// dummyHash is verified against when there is no real hash, so an
+	// unknown email takes as long to reject as a wrong password
+	dummyHash func() string
+}
+
+// NewAuthService creates a new AuthService instance
+func NewAuthService(
+	creds repository.CredentialRepository,
+	sessions repository.SessionRepository,
+	roles repository.RoleRepository,
+	users repository.UserRepository,
+	links repository.MagicLinkRepository,
+	mfa repository.MFARepository,
+	devices repository.TrustedDeviceRepository,
+	passwords PasswordService,
+	otp OTPService,
+	risk RiskService,
+	lockout LockoutService,
+	hasher *auth.PasswordHasher,
+	signer *auth.DeviceTokenSigner,
+	mailer mail.Sender,
+	audit AuditService,
+	cfg *config.SessionConfig,
+	log *slog.Logger,
+) AuthService {
+	return &authService{
+		creds:     creds,
+		sessions:  sessions,
+		roles:     roles,
+		users:     users,
+		links:     links,
+		mfa:       mfa,
+		devices:   devices,
+		passwords: passwords,
+		otp:       otp,
+		risk:      risk,
+		lockout:   lockout,
+		hasher:    hasher,
+		signer:    signer,
+		mailer:    mailer,
+		audit:     audit,
+		config:    cfg,
+		log:       log,
+		dummyHash: sync.OnceValue(func() string {
+			hash, _ := hasher.Hash("identity-service-timing-equaliser")
+			return hash
+		}),
+	}
+}
+
+// SignIn verifies an email and password and starts a session. A correct
+// password that must be changed first is refused with a distinct reason
+// so the client can switch to ChangeExpiredPassword.
+func (s *authService) SignIn(ctx context.Context, email, password string) (*models.SignInResponse, error) {
+	cred, err := s.verifyPassword(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkPasswordDue(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	return s.completeSignIn(ctx, cred, "password", "")
+}
+
+// checkPasswordDue refuses a sign-in by any method while the account's
+// password must be changed or has expired, with the reasons that send the
+// client to ChangeExpiredPassword. Accounts without a password have
+// nothing to change.
+func (s *authService) checkPasswordDue(ctx context.Context, cred *models.PasswordCredential) error {
+	if cred.PasswordHash == "" {
+		return nil
+	}
+	if cred.PasswordChangeRequired {
+		return apperrors.NewForbiddenError("password change required").WithReason(ReasonPasswordChangeRequired)
+	}
+	expired, err := s.passwords.PasswordExpired(ctx, cred)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return apperrors.NewForbiddenError("password has expired").WithReason(ReasonPasswordExpired)
+	}
+	return nil
+}
+
+// ChangeExpiredPassword replaces the password of a user who cannot sign in
+// until they do, proving the current password again, and starts a session.
+// The password must be expired or flagged for a change, and when a second
+// factor is required the new password is stored only once it is passed.
+func (s *authService) ChangeExpiredPassword(ctx context.Context, email, currentPassword, newPassword string) (*models.SignInResponse, error) {
+	cred, err := s.verifyPassword(ctx, email, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if !cred.PasswordChangeRequired {
+		expired, err := s.passwords.PasswordExpired(ctx, cred)
+		if err != nil {
+			return nil, err
+		}
+		if !expired {
+			return nil, apperrors.NewForbiddenError("password change is not required")
+		}
+	}
+
+	hash, err := s.passwords.HashPasswordChange(ctx, cred.UserID, newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeSignIn(ctx, cred, "password", hash)
+}
+
+// ChangePassword replaces the caller's password. The current password is
+// required whenever one is set, and wrong guesses count towards lockout
+// so a stolen session cannot be used to find it.
+func (s *authService) ChangePassword(ctx context.Context, principal *auth.Principal, currentPassword, newPassword string) error {
+	if err := forbidImpersonation(principal); err != nil {
+		return err
+	}
+
+	cred, err := s.creds.GetByUserID(ctx, principal.UserID)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+
+	if cred.PasswordHash != "" {
+		if err := s.passwords.Reverify(ctx, cred, currentPassword, "password_change"); err != nil {
+			return err
+		}
+	}
+
+	return s.passwords.SetPassword(ctx, principal.UserID, newPassword)
+}
+
+func (s *authService) SignOut(ctx context.Context, principal *auth.Principal) error {
+	if principal.SessionID == 0 {
+		return apperrors.NewBadRequestError("credentials are not a session", nil)
+	}
+
+	if _, err := s.sessions.Revoke(ctx, principal.UserID, principal.SessionID, time.Now().UTC()); err != nil {
+		return apperrors.NewInternalServerError("failed to sign out", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditSignOut,
+		TargetType: "session",
+		TargetID:   strconv.FormatInt(principal.SessionID, 10),
+	})
+	return nil
+}
+
+func (s *authService) Authenticate(ctx context.Context, token, ip string) (*auth.Principal, error) {
+	if !strings.HasPrefix(token, sessionTokenScheme) {
+		return nil, auth.ErrNoCredentials
+	}
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		return nil, err
+	}
+
+	cred, err := s.sessions.GetByTokenHash(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if cred.RevokedAt != nil || cred.UserDisabled || !cred.ExpiresAt.After(now) {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	if err := s.sessions.RecordUsage(ctx, cred.ID, ip, now); err != nil {
+		return nil, fmt.Errorf("failed to record session usage: %w", err)
+	}
+
+	roles, err := s.roles.GetRoleNamesForUser(ctx, 0, cred.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+
+	principal := &auth.Principal{
+		UserID:    cred.UserID,
+		Email:     cred.UserEmail,
+		Roles:     roles,
+		SessionID: cred.ID,
+		AMR:       cred.AMR,
+		AuthTime:  cred.AuthTime,
+	}
+	if cred.OrganizationID != nil {
+		principal.OrganizationID = *cred.OrganizationID
+	}
+	if cred.ActorUserID != nil {
+		principal.ActorUserID = *cred.ActorUserID
+	}
+	return principal, nil
+}
+
+// Impersonate starts a short-lived session in which the principal acts as
+// another member of the active organization. The session never grants a
+// permission the principal lacks there, and is refused sensitive account
+// changes such as the password. It carries only the impersonation method
+// and the weakest authentication class, whatever the principal proved.
+func (s *authService) Impersonate(ctx context.Context, principal *auth.Principal, userID int, reason string) (*models.ImpersonationResponse, error) {
+	ctx, err := withActionReason(ctx, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+	if principal.UserID == 0 || principal.APIKeyID != 0 {
+		return nil, apperrors.NewForbiddenError("impersonation requires a signed-in user")
+	}
+	if userID == principal.UserID {
+		return nil, apperrors.NewBadRequestError("cannot impersonate yourself", nil)
+	}
+
+	orgID := principal.OrganizationID
+	target, err := s.users.GetByID(ctx, orgID, userID)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
+	if !target.Active || target.DisabledAt != nil {
+		return nil, apperrors.NewBadRequestError("user is deactivated or disabled", nil)
+	}
+
+	escalates, err := s.grantsMore(ctx, orgID, userID, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if escalates {
+		return nil, apperrors.NewForbiddenError("user has permissions you do not have")
+	}
+
+	secret, err := auth.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate session token", err)
+	}
+	token := sessionTokenScheme + secret
+
+	now := time.Now().UTC()
+	info := requestinfo.From(ctx)
+	session, err := s.sessions.Create(ctx, &models.Session{
+		UserID:         userID,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.config.ImpersonationTTL),
+		IP:             info.IP,
+		UserAgent:      truncate(info.UserAgent, maxUserAgentLength),
+		OrganizationID: &orgID,
+		ActorUserID:    &principal.UserID,
+		AMR:            []string{auth.AMRImpersonation},
+		AuthTime:       now,
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create session", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditImpersonationStarted,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(userID),
+		Details:        map[string]interface{}{"session_id": session.ID, "expires_at": session.ExpiresAt},
+	})
+
+	return &models.ImpersonationResponse{
+		Token:          token,
+		ExpiresAt:      session.ExpiresAt,
+		Subject:        strconv.Itoa(userID),
+		OrganizationID: orgID,
+		Act:            models.Actor{Subject: strconv.Itoa(principal.UserID)},
+	}, nil
+}
+
+// StepUp re-authenticates the caller's session for operations that need a
+// recent or stronger sign-in. The password comes first when the account
+// has one, then a code for any verified factor; an account with neither
+// proves its email address instead.
+func (s *authService) StepUp(ctx context.Context, principal *auth.Principal, password string) (*models.StepUpResponse, error) {
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+	if principal.SessionID == 0 {
+		return nil, apperrors.NewBadRequestError("credentials are not a session", nil)
+	}
+
+	cred, err := s.creds.GetByUserID(ctx, principal.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+
+	first := ""
+	if cred.PasswordHash != "" {
+		if password == "" {
+			return nil, apperrors.NewBadRequestError("password is required", nil)
+		}
+		if err := s.passwords.Reverify(ctx, cred, password, "step_up"); err != nil {
+			return nil, err
+		}
+		first = "password"
+	}
+
+	options, err := s.factorOptions(ctx, cred, first)
+	if err != nil {
+		return nil, err
+	}
+	if first != "" && len(options) == 0 {
+		return s.stepUpSession(ctx, principal, first)
+	}
+
+	challenge, token, err := s.otp.Begin(ctx, &models.OTPChallenge{
+		UserID:      cred.UserID,
+		Purpose:     models.OTPPurposeStepUp,
+		FirstFactor: first,
+	})
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to start step-up", err)
+	}
+	if len(options) == 0 {
+		sent, err := s.otp.Send(ctx, challenge, token, nil)
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to send code", err)
+		}
+		if !sent {
+			return nil, apperrors.NewTooManyRequestsError("wait before requesting another code")
+		}
+	}
+
+	return &models.StepUpResponse{
+		CodeRequired: true,
+		OTPToken:     token,
+		ExpiresAt:    &challenge.ExpiresAt,
+		Factors:      options,
+	}, nil
+}
+
+// VerifyStepUp completes a step-up of the caller's session with the code
+// for its challenge
+func (s *authService) VerifyStepUp(ctx context.Context, principal *auth.Principal, token, code string) (*models.StepUpResponse, error) {
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+	if principal.SessionID == 0 {
+		return nil, apperrors.NewBadRequestError("credentials are not a session", nil)
+	}
+	if token == "" || code == "" {
+		return nil, apperrors.NewBadRequestError("otp_token and code are required", nil)
+	}
+
+	ip := requestinfo.From(ctx).IP
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		if stderrors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, apperrors.NewTooManyRequestsError("too many failed attempts")
+		}
+		return nil, apperrors.NewInternalServerError("failed to check lockout", err)
+	}
+
+	challenge, err := s.mfa.GetChallenge(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.codeFailed(ctx, principal.UserID, models.OTPPurposeStepUp, "invalid")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve challenge", err)
+	}
+	if challenge.Purpose != models.OTPPurposeStepUp || challenge.UserID != principal.UserID {
+		return nil, s.codeFailed(ctx, principal.UserID, challenge.Purpose, "wrong_purpose")
+	}
+
+	ok, err := s.otp.Verify(ctx, challenge, token, code)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to verify code", err)
+	}
+	if !ok {
+		s.lockout.RecordFailure(ctx, principal.UserID, ip)
+		return nil, s.codeFailed(ctx, principal.UserID, challenge.Purpose, "mismatch")
+	}
+
+	if challenge.FactorID != nil {
+		if err := s.mfa.RecordFactorUsage(ctx, *challenge.FactorID, time.Now().UTC()); err != nil {
+			return nil, apperrors.NewInternalServerError("failed to record factor usage", err)
+		}
+	}
+
+	method := challenge.Channel + "_otp"
+	if challenge.FirstFactor != "" {
+		method = challenge.FirstFactor + "+" + method
+	}
+	return s.stepUpSession(ctx, principal, method)
+}
+
+// CheckAuthentication reports whether the principal's session meets the
+// requirement. An account is never asked for a stronger sign-in than its
+// password and factors allow, only for a recent one.
+func (s *authService) CheckAuthentication(ctx context.Context, principal *auth.Principal, req auth.Requirement) (bool, error) {
+	if principal.SessionID == 0 {
+		return false, nil
+	}
+	if req.MaxAge > 0 && principal.AuthTime.Before(time.Now().UTC().Add(-req.MaxAge)) {
+		return false, nil
+	}
+	if auth.ACRSatisfies(principal.ACR(), req.ACR) {
+		return true, nil
+	}
+
+	cred, err := s.creds.GetByUserID(ctx, principal.UserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	attainable := auth.ACRSingleFactor
+	if cred.PasswordHash != "" {
+		factor, err := s.verifiedFactor(ctx, principal.UserID, "", 0)
+		if err != nil {
+			return false, err
+		}
+		if factor != nil {
+			attainable = auth.ACRMultiFactor
+		}
+	}
+	return !auth.ACRSatisfies(attainable, req.ACR) && auth.ACRSatisfies(principal.ACR(), attainable), nil
+}
+
+// stepUpSession records a re-authentication of the principal's session
+func (s *authService) stepUpSession(ctx context.Context, principal *auth.Principal, method string) (*models.StepUpResponse, error) {
+	amr := methodAMR(method)
+	now := time.Now().UTC()
+	updated, err := s.sessions.StepUp(ctx, principal.SessionID, amr, now)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to update session", err)
+	}
+	if !updated {
+		return nil, apperrors.NewUnauthorizedError("session has ended")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditStepUp,
+		TargetType: "session",
+		TargetID:   strconv.FormatInt(principal.SessionID, 10),
+		Details:    map[string]interface{}{"method": method, "amr": amr},
+	})
+
+	return &models.StepUpResponse{ACR: auth.ACR(amr), AMR: amr, AuthTime: &now}, nil
+}
+
+// grantsMore reports whether userID holds any permission in the
+// organization, or platform-wide, that actorID does not hold there too.
+// The same permission names are granted at both levels, so they are
+// compared level by level.
+func (s *authService) grantsMore(ctx context.Context, orgID, userID, actorID int) (bool, error) {
+	for _, scope := range []int{orgID, 0} {
+		held, err := s.roles.GetPermissionsForUser(ctx, scope, actorID)
+		if err != nil {
+			return false, apperrors.NewInternalServerError("failed to load permissions", err)
+		}
+		wanted, err := s.roles.GetPermissionsForUser(ctx, scope, userID)
+		if err != nil {
+			return false, apperrors.NewInternalServerError("failed to load permissions", err)
+		}
+
+		granted := make(map[string]bool, len(held))
+		for _, p := range held {
+			granted[p] = true
+		}
+		for _, p := range wanted {
+			if !granted[p] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// verifyPassword checks an email and password, applying lockout. Unknown
+// emails, locked or disabled accounts and wrong passwords are
+// indistinguishable.
+func (s *authService) verifyPassword(ctx context.Context, email, password string) (*models.PasswordCredential, error) {
+	ip := requestinfo.From(ctx).IP
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		if stderrors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, apperrors.NewTooManyRequestsError("too many failed attempts")
+		}
+		return nil, apperrors.NewInternalServerError("failed to check lockout", err)
+	}
+
+	cred, err := s.creds.GetByEmail(ctx, email)
+	if err != nil && !stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+
+	switch {
+	case cred == nil:
+		_, _ = s.hasher.Verify(password, s.dummyHash())
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.signInFailed(ctx, 0, email, "unknown")
+	case cred.LockedUntil != nil && cred.LockedUntil.After(time.Now().UTC()):
+		// Attempts during a lock are not counted so it cannot be extended
+		_, _ = s.hasher.Verify(password, s.dummyHash())
+		return nil, s.signInFailed(ctx, cred.UserID, email, "locked")
+	case cred.DisabledAt != nil:
+		_, _ = s.hasher.Verify(password, s.dummyHash())
+		return nil, s.signInFailed(ctx, cred.UserID, email, "disabled")
+	case cred.PasswordHash == "":
+		_, _ = s.hasher.Verify(password, s.dummyHash())
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.signInFailed(ctx, cred.UserID, email, "no_password")
+	}
+
+	ok, err := s.hasher.Verify(password, cred.PasswordHash)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to verify password", err)
+	}
+	if !ok {
+		s.lockout.RecordFailure(ctx, cred.UserID, ip)
+		return nil, s.signInFailed(ctx, cred.UserID, email, "mismatch")
+	}
+
+	s.lockout.RecordSuccess(ctx, cred.UserID)
+	if s.hasher.NeedsRehash(cred.PasswordHash) {
+		s.rehash(ctx, cred, password)
+	}
+	return cred, nil
+}
+
+// rehash replaces a verified hash made with another algorithm or older
+// parameters. Failures are logged only; the old hash still works and the
+// upgrade is retried on the next sign-in.
+func (s *authService) rehash(ctx context.Context, cred *models.PasswordCredential, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		s.logRehashFailure(ctx, cred.UserID, err)
+		return
+	}
+	updated, err := s.creds.UpdatePasswordHash(ctx, cred.UserID, cred.PasswordHash, hash)
+	if err != nil {
+		s.logRehashFailure(ctx, cred.UserID, err)
+		return
+	}
+	if !updated {
+		// Changed concurrently; the newer password wins
+		return
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:      models.AuditPasswordRehashed,
+		ActorUserID: &cred.UserID,
+		TargetType:  "user",
+		TargetID:    strconv.Itoa(cred.UserID),
+		Details: map[string]interface{}{
+			"from": auth.HashAlgorithm(cred.PasswordHash),
+			"to":   auth.HashArgon2id,
+		},
+	})
+	cred.PasswordHash = hash
+}
+
+func (s *authService) logRehashFailure(ctx context.Context, userID int, err error) {
+	s.log.ErrorContext(ctx, "failed to upgrade password hash",
+		slog.Int("user_id", userID),
+		slog.String("error", err.Error()),
+	)
+}
+
+// RequestMagicLink emails a sign-in link to the address if it belongs to
+// an active account. The response is the same either way and is given
+// before the account is even looked up, with the link created and sent in
+// the background, so neither it nor its timing can be used to discover
+// accounts. The link only works together with the returned device token.
+func (s *authService) RequestMagicLink(ctx context.Context, email string) (*models.MagicLinkResponse, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, apperrors.NewBadRequestError("email is required", nil)
+	}
+
+	deviceToken, err := auth.GenerateToken(magicLinkDeviceBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate device token", err)
+	}
+	now := time.Now().UTC()
+	response := &models.MagicLinkResponse{DeviceToken: deviceToken, ExpiresAt: now.Add(s.config.MagicLinkTTL)}
+
+	go s.issueMagicLink(context.WithoutCancel(ctx), email, deviceToken, now, response.ExpiresAt)
+
+	return response, nil
+}
+
+// issueMagicLink creates and sends the link RequestMagicLink promised when
+// the address belongs to an active account, logging rather than returning
+// failures since the request has already been answered
+func (s *authService) issueMagicLink(ctx context.Context, email, deviceToken string, now, expiresAt time.Time) {
+	cred, err := s.creds.GetByEmail(ctx, email)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to retrieve credentials for sign-in link", slog.String("error", err.Error()))
+		return
+	}
+	if cred.DisabledAt != nil {
+		return
+	}
+
+	token, err := auth.GenerateToken(magicLinkTokenBytes)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to generate sign-in link", slog.String("error", err.Error()))
+		return
+	}
+	link, err := s.links.Create(ctx, &models.MagicLink{
+		UserID:    cred.UserID,
+		Email:     cred.Email,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	}, auth.HashToken(token), auth.HashToken(deviceToken))
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to create sign-in link",
+			slog.Int("user_id", cred.UserID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditMagicLinkSent,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(cred.UserID),
+		Details:    map[string]interface{}{"expires_at": link.ExpiresAt},
+	})
+
+	s.sendMagicLink(ctx, link, token)
+}
+
+// RedeemMagicLink signs in with an emailed link. Opening the link only
+// shows a page asking to confirm, which then posts here, so scanners that
+// prefetch links do not use it up; they also lack the device token. A
+// locked account, or one whose password must be changed first, is refused
+// as SignIn refuses it.
+func (s *authService) RedeemMagicLink(ctx context.Context, token, deviceToken string) (*models.SignInResponse, error) {
+	if token == "" || deviceToken == "" {
+		return nil, apperrors.NewBadRequestError("token and device_token are required", nil)
+	}
+
+	ip := requestinfo.From(ctx).IP
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		if stderrors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, apperrors.NewTooManyRequestsError("too many failed attempts")
+		}
+		return nil, apperrors.NewInternalServerError("failed to check lockout", err)
+	}
+
+	link, err := s.links.Redeem(ctx, auth.HashToken(token), auth.HashToken(deviceToken), time.Now().UTC())
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.magicLinkFailed(ctx, 0, "invalid")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to redeem sign-in link", err)
+	}
+
+	cred, err := s.creds.GetByUserID(ctx, link.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+	// The link is used up either way; a locked account is not counted
+	// again so the lock cannot be extended
+	switch {
+	case cred.DisabledAt != nil:
+		return nil, s.magicLinkFailed(ctx, cred.UserID, "disabled")
+	case cred.LockedUntil != nil && cred.LockedUntil.After(time.Now().UTC()):
+		return nil, s.magicLinkFailed(ctx, cred.UserID, "locked")
+	}
+	if err := s.checkPasswordDue(ctx, cred); err != nil {
+		return nil, err
+	}
+
+	return s.completeSignIn(ctx, cred, "magic_link", "")
+}
+
+// sendMagicLink delivers a sign-in link, logging rather than returning
+// failures since the request has already been answered
+func (s *authService) sendMagicLink(ctx context.Context, link *models.MagicLink, token string) {
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      link.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Use this link to sign in on the device where you asked for it:\n%s?token=%s\n\nThis link works once and expires on %s. If you did not ask to sign in, ignore this email.",
+			s.config.MagicLinkURL, url.QueryEscape(token), link.ExpiresAt.Format(time.RFC1123),
+		),
+	}); err != nil {
+		s.log.ErrorContext(ctx, "failed to send sign-in link",
+			slog.Int("user_id", link.UserID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// magicLinkFailed audits a rejected sign-in link and returns the error to report
+func (s *authService) magicLinkFailed(ctx context.Context, userID int, reason string) error {
+	event := models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "user",
+		Details:    map[string]interface{}{"method": "magic_link", "reason": reason},
+	}
+	if userID != 0 {
+		event.TargetID = strconv.Itoa(userID)
+	}
+	s.audit.Record(ctx, event)
+
+	return apperrors.NewUnauthorizedError("invalid or expired sign-in link")
+}
+
+// RequestSignInCode sends a one-time passcode for passwordless sign-in to
+// the account's email address or verified SMS factor. Like
+// RequestMagicLink it answers the same whether or not a code is sent, and
+// before the account is looked up; the challenge is started under the
+// returned token in the background.
+func (s *authService) RequestSignInCode(ctx context.Context, email, channel string) (*models.OTPResponse, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, apperrors.NewBadRequestError("email is required", nil)
+	}
+	switch channel {
+	case "":
+		channel = models.FactorEmail
+	case models.FactorEmail, models.FactorSMS:
+	default:
+		return nil, apperrors.NewBadRequestError("channel must be email or sms", nil)
+	}
+
+	response, err := s.otp.Decoy()
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to start sign-in", err)
+	}
+
+	go s.issueSignInCode(context.WithoutCancel(ctx), email, channel, response)
+
+	return response, nil
+}
+
+// issueSignInCode starts and sends the challenge RequestSignInCode
+// answered for when the address belongs to an active account with the
+// channel, logging rather than returning failures since the request has
+// already been answered
+func (s *authService) issueSignInCode(ctx context.Context, email, channel string, response *models.OTPResponse) {
+	cred, err := s.creds.GetByEmail(ctx, email)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to retrieve credentials for sign-in code", slog.String("error", err.Error()))
+		return
+	}
+	if cred.DisabledAt != nil {
+		return
+	}
+
+	var factor *models.MFAFactor
+	if channel == models.FactorSMS {
+		if factor, err = s.verifiedFactor(ctx, cred.UserID, models.FactorSMS, 0); err != nil {
+			s.log.ErrorContext(ctx, "failed to retrieve factors for sign-in code",
+				slog.Int("user_id", cred.UserID),
+				slog.String("error", err.Error()),
+			)
+			return
+		}
+		if factor == nil {
+			return
+		}
+	}
+
+	challenge, err := s.otp.Adopt(ctx, &models.OTPChallenge{
+		UserID:  cred.UserID,
+		Purpose: models.OTPPurposeSignIn,
+	}, response)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to start sign-in code",
+			slog.Int("user_id", cred.UserID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	s.sendCode(ctx, challenge, response.OTPToken, factor)
+}
+
+// SendCode sends a new code for a challenge. An MFA challenge needs the
+// factor to send to, chosen from those offered at sign-in, unless its code
+// went to the account's email for want of one. Passwordless
+// challenges are resent in the background to where the first code went,
+// and unknown tokens are accepted silently, so neither reveals accounts.
+func (s *authService) SendCode(ctx context.Context, token string, factorID int) error {
+	if token == "" {
+		return apperrors.NewBadRequestError("otp_token is required", nil)
+	}
+
+	challenge, err := s.mfa.GetChallenge(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to retrieve challenge", err)
+	}
+	if !challenge.ExpiresAt.After(time.Now().UTC()) {
+		return nil
+	}
+
+	var factor *models.MFAFactor
+	switch challenge.Purpose {
+	case models.OTPPurposeSignIn:
+		if challenge.FactorID != nil {
+			if factor, err = s.verifiedFactor(ctx, challenge.UserID, models.FactorSMS, *challenge.FactorID); err != nil {
+				return err
+			}
+			if factor == nil {
+				return nil
+			}
+		}
+		go s.sendCode(context.WithoutCancel(ctx), challenge, token, factor)
+		return nil
+	case models.OTPPurposeMFA, models.OTPPurposeStepUp:
+		if factorID <= 0 && challenge.FactorID == nil && challenge.Channel == models.FactorEmail {
+			// Resent to the account's address, like the first code
+			break
+		}
+		if factorID <= 0 {
+			return apperrors.NewBadRequestError("factor_id is required", nil)
+		}
+		if factor, err = s.verifiedFactor(ctx, challenge.UserID, "", factorID); err != nil {
+			return err
+		}
+		if factor == nil || factor.Type == provenChannel(challenge.FirstFactor) {
+			return apperrors.NewBadRequestError("factor is not available", nil)
+		}
+	case models.OTPPurposeEnroll:
+		factors, err := s.mfa.GetFactors(ctx, challenge.UserID)
+		if err != nil {
+			return apperrors.NewInternalServerError("failed to retrieve factors", err)
+		}
+		for i := range factors {
+			if challenge.FactorID != nil && factors[i].ID == *challenge.FactorID {
+				factor = &factors[i]
+			}
+		}
+		if factor == nil {
+			return nil
+		}
+	}
+
+	sent, err := s.otp.Send(ctx, challenge, token, factor)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to send code", err)
+	}
+	if !sent {
+		return apperrors.NewTooManyRequestsError("wait before requesting another code")
+	}
+	return nil
+}
+
+// VerifyCode completes a passwordless or MFA challenge with its code. A
+// passwordless sign-in may still need a second factor; an MFA challenge
+// starts the session, and trusts the device when rememberDevice is set.
+// Codes are not checked while the account is locked, and a passwordless
+// sign-in is refused as SignIn would be while the password is due.
+func (s *authService) VerifyCode(ctx context.Context, token, code string, rememberDevice bool) (*models.SignInResponse, error) {
+	if token == "" || code == "" {
+		return nil, apperrors.NewBadRequestError("otp_token and code are required", nil)
+	}
+
+	ip := requestinfo.From(ctx).IP
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		if stderrors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, apperrors.NewTooManyRequestsError("too many failed attempts")
+		}
+		return nil, apperrors.NewInternalServerError("failed to check lockout", err)
+	}
+
+	challenge, err := s.mfa.GetChallenge(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.codeFailed(ctx, 0, "", "invalid")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve challenge", err)
+	}
+	if challenge.Purpose != models.OTPPurposeSignIn && challenge.Purpose != models.OTPPurposeMFA {
+		return nil, s.codeFailed(ctx, challenge.UserID, challenge.Purpose, "wrong_purpose")
+	}
+
+	cred, err := s.creds.GetByUserID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+	// Checked before the code so a lock stops guessing; attempts during
+	// it are not counted so it cannot be extended
+	switch {
+	case cred.DisabledAt != nil:
+		return nil, s.codeFailed(ctx, cred.UserID, challenge.Purpose, "disabled")
+	case cred.LockedUntil != nil && cred.LockedUntil.After(time.Now().UTC()):
+		return nil, s.codeFailed(ctx, cred.UserID, challenge.Purpose, "locked")
+	}
+
+	ok, err := s.otp.Verify(ctx, challenge, token, code)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to verify code", err)
+	}
+	if !ok {
+		s.lockout.RecordFailure(ctx, challenge.UserID, ip)
+		return nil, s.codeFailed(ctx, challenge.UserID, challenge.Purpose, "mismatch")
+	}
+	s.lockout.RecordSuccess(ctx, cred.UserID)
+
+	if challenge.FactorID != nil {
+		if err := s.mfa.RecordFactorUsage(ctx, *challenge.FactorID, time.Now().UTC()); err != nil {
+			return nil, apperrors.NewInternalServerError("failed to record factor usage", err)
+		}
+	}
+
+	method := challenge.Channel + "_otp"
+	if challenge.Purpose != models.OTPPurposeMFA {
+		// A second factor's first was already checked, but a passwordless
+		// sign-in must not get around a due password change
+		if err := s.checkPasswordDue(ctx, cred); err != nil {
+			return nil, err
+		}
+		return s.completeSignIn(ctx, cred, method, "")
+	}
+
+	session, err := s.finishSignIn(ctx, cred, challenge.FirstFactor+"+"+method, challenge.PendingPasswordHash)
+	if err != nil || !rememberDevice {
+		return session, err
+	}
+	return s.trustDevice(ctx, cred, session)
+}
+
+// completeSignIn starts a session once the first factor is proven and the
+// sign-in's risk score does not block it. Unless the device is trusted, a
+// user with a verified factor other than the one just used is always
+// challenged for it. A user without one is only challenged when the score
+// is past the MFA threshold, with a code sent to the account's email,
+// unless the first factor already proved the email. A non-empty
+// newPasswordHash replaces the password before the session starts, after
+// the challenge when there is one.
+func (s *authService) completeSignIn(ctx context.Context, cred *models.PasswordCredential, method, newPasswordHash string) (*models.SignInResponse, error) {
+	assessment, err := s.risk.Assess(ctx, cred)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to assess sign-in", err)
+	}
+	if assessment.Decision == models.RiskBlock {
+		return nil, s.riskBlocked(ctx, cred, method, assessment)
+	}
+
+	trusted, err := s.deviceTrusted(ctx, cred)
+	if err != nil {
+		return nil, err
+	}
+	if trusted {
+		return s.finishSignIn(ctx, cred, method, newPasswordHash)
+	}
+
+	options, err := s.factorOptions(ctx, cred, method)
+	if err != nil {
+		return nil, err
+	}
+	if len(options) == 0 && (assessment.Decision == models.RiskAllow || provenChannel(method) == models.FactorEmail) {
+		return s.finishSignIn(ctx, cred, method, newPasswordHash)
+	}
+
+	challenge, token, err := s.otp.Begin(ctx, &models.OTPChallenge{
+		UserID:              cred.UserID,
+		Purpose:             models.OTPPurposeMFA,
+		FirstFactor:         method,
+		PendingPasswordHash: newPasswordHash,
+	})
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to start second factor", err)
+	}
+	if len(options) == 0 {
+		sent, err := s.otp.Send(ctx, challenge, token, nil)
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to send code", err)
+		}
+		if !sent {
+			return nil, apperrors.NewTooManyRequestsError("wait before requesting another code")
+		}
+	}
+
+	return &models.SignInResponse{
+		ExpiresAt:   challenge.ExpiresAt,
+		MFARequired: true,
+		OTPToken:    token,
+		Factors:     options,
+	}, nil
+}
+
+// finishSignIn stores a pending password change, if any, and starts the
+// session
+func (s *authService) finishSignIn(ctx context.Context, cred *models.PasswordCredential, method, newPasswordHash string) (*models.SignInResponse, error) {
+	if newPasswordHash != "" {
+		if err := s.passwords.ApplyPasswordChange(ctx, cred.UserID, newPasswordHash); err != nil {
+			return nil, err
+		}
+	}
+	return s.startSession(ctx, cred, method)
+}
+
+// riskBlocked audits a sign-in refused for its risk score and returns the
+// error to report
+func (s *authService) riskBlocked(ctx context.Context, cred *models.PasswordCredential, method string, assessment *models.RiskAssessment) error {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(cred.UserID),
+		Details: map[string]interface{}{
+			"method":  method,
+			"reason":  "risk",
+			"score":   assessment.Score,
+			"signals": assessment.Signals,
+		},
+	})
+
+	return apperrors.NewForbiddenError("sign-in was blocked because it looks unusual").WithReason(ReasonRiskBlocked)
+}
+
+// deviceTrusted reports whether the request carries a trusted device token
+// for the user that is still valid. Changing the password forgets devices
+// trusted before it.
+func (s *authService) deviceTrusted(ctx context.Context, cred *models.PasswordCredential) (bool, error) {
+	info := requestinfo.From(ctx)
+	now := time.Now().UTC()
+	if info.TrustedDevice == "" || s.config.TrustedDeviceTTL <= 0 || !s.signer.Verify(info.TrustedDevice, cred.UserID, now) {
+		return false, nil
+	}
+
+	var since time.Time
+	if cred.PasswordChangedAt != nil {
+		since = *cred.PasswordChangedAt
+	}
+	_, err := s.devices.Use(ctx, cred.UserID, auth.HashToken(info.TrustedDevice), info.IP, since, now)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperrors.NewInternalServerError("failed to check trusted device", err)
+	}
+	return true, nil
+}
+
+// trustDevice adds a trusted device token to a session that has just
+// passed a second factor
+func (s *authService) trustDevice(ctx context.Context, cred *models.PasswordCredential, session *models.SignInResponse) (*models.SignInResponse, error) {
+	if s.config.TrustedDeviceTTL <= 0 {
+		return session, nil
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.config.TrustedDeviceTTL)
+	token, err := s.signer.Sign(cred.UserID, expiresAt)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate trusted device token", err)
+	}
+
+	info := requestinfo.From(ctx)
+	device, err := s.devices.Create(ctx, &models.TrustedDevice{
+		UserID:    cred.UserID,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+		IP:        info.IP,
+		UserAgent: truncate(info.UserAgent, maxUserAgentLength),
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to trust device", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:      models.AuditDeviceTrusted,
+		ActorUserID: &cred.UserID,
+		TargetType:  "trusted_device",
+		TargetID:    strconv.FormatInt(device.ID, 10),
+		Details:     map[string]interface{}{"expires_at": device.ExpiresAt},
+	})
+
+	session.TrustedDeviceToken = token
+	session.TrustedDeviceExpiresAt = &device.ExpiresAt
+	return session, nil
+}
+
+// factorOptions returns the user's verified factors that can follow the
+// sign-in method, with their destinations masked
+func (s *authService) factorOptions(ctx context.Context, cred *models.PasswordCredential, method string) ([]models.MFAFactorOption, error) {
+	factors, err := s.mfa.GetFactors(ctx, cred.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve factors", err)
+	}
+
+	options := []models.MFAFactorOption{}
+	for _, factor := range factors {
+		if factor.VerifiedAt == nil || factor.Type == provenChannel(method) {
+			continue
+		}
+		hint := maskPhone(factor.Destination)
+		if factor.Type == models.FactorEmail {
+			hint = maskEmail(cred.Email)
+		}
+		options = append(options, models.MFAFactorOption{ID: factor.ID, Type: factor.Type, Hint: hint})
+	}
+	return options, nil
+}
+
+// verifiedFactor returns the user's verified factor with the id, or the
+// first of the type when id is 0, or nil when there is none
+func (s *authService) verifiedFactor(ctx context.Context, userID int, factorType string, id int) (*models.MFAFactor, error) {
+	factors, err := s.mfa.GetFactors(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve factors", err)
+	}
+	for i := range factors {
+		f := &factors[i]
+		if f.VerifiedAt == nil || (id != 0 && f.ID != id) || (factorType != "" && f.Type != factorType) {
+			continue
+		}
+		return f, nil
+	}
+	return nil, nil
+}
+
+// sendCode delivers a passwordless sign-in code, logging rather than
+// returning failures since the request has already been answered
+func (s *authService) sendCode(ctx context.Context, challenge *models.OTPChallenge, token string, factor *models.MFAFactor) {
+	if _, err := s.otp.Send(ctx, challenge, token, factor); err != nil {
+		s.log.ErrorContext(ctx, "failed to send sign-in code",
+			slog.Int("user_id", challenge.UserID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// codeFailed audits a rejected one-time passcode and returns the error to report
+func (s *authService) codeFailed(ctx context.Context, userID int, purpose, reason string) error {
+	event := models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "user",
+		Details:    map[string]interface{}{"method": "otp", "purpose": purpose, "reason": reason},
+	}
+	if userID != 0 {
+		event.TargetID = strconv.Itoa(userID)
+	}
+	s.audit.Record(ctx, event)
+
+	return apperrors.NewUnauthorizedError("invalid or expired code")
+}
+
+// provenChannel returns the factor type a sign-in method already proved
+// control of, which is not offered again as the second factor
+func provenChannel(method string) string {
+	switch method {
+	case "magic_link", "email_otp":
+		return models.FactorEmail
+	case "sms_otp":
+		return models.FactorSMS
+	}
+	return ""
+}
+
+// methodAMR returns the RFC 8176 references for a sign-in method, in which
+// "+" joins the factors of a multi-factor sign-in
+func methodAMR(method string) []string {
+	factors := strings.Split(method, "+")
+	amr := []string{}
+	for _, factor := range factors {
+		var refs []string
+		switch factor {
+		case "password":
+			refs = []string{auth.AMRPassword}
+		case "magic_link", "email_otp":
+			refs = []string{auth.AMROTP}
+		case "sms_otp":
+			refs = []string{auth.AMROTP, auth.AMRSMS}
+		}
+		for _, ref := range refs {
+			if !slices.Contains(amr, ref) {
+				amr = append(amr, ref)
+			}
+		}
+	}
+	if len(factors) > 1 {
+		amr = append(amr, auth.AMRMultiFactor)
+	}
+	return amr
+}
+
+// maskEmail keeps enough of an address to recognise it
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "***"
+	}
+	return local[:1] + "***@" + domain
+}
+
+// maskPhone keeps the last two digits of a phone number
+func maskPhone(phone string) string {
+	if len(phone) <= 2 {
+		return "***"
+	}
+	return strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
+
+// startSession signs the user in, recording the sign-in method
+func (s *authService) startSession(ctx context.Context, cred *models.PasswordCredential, method string) (*models.SignInResponse, error) {
+	secret, err := auth.GenerateToken(sessionTokenBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate session token", err)
+	}
+	token := sessionTokenScheme + secret
+
+	now := time.Now().UTC()
+	info := requestinfo.From(ctx)
+	session, err := s.sessions.Create(ctx, &models.Session{
+		UserID:    cred.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.TTL),
+		IP:        info.IP,
+		UserAgent: truncate(info.UserAgent, maxUserAgentLength),
+		AMR:       methodAMR(method),
+		AuthTime:  now,
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create session", err)
+	}
+	s.risk.RecordSignIn(ctx, cred, session.ID)
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:      models.AuditSignIn,
+		ActorUserID: &cred.UserID,
+		TargetType:  "session",
+		TargetID:    strconv.FormatInt(session.ID, 10),
+		Details:     map[string]interface{}{"method": method, "amr": session.AMR},
+	})
+
+	return &models.SignInResponse{
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+		ACR:       auth.ACR(session.AMR),
+		AMR:       session.AMR,
+	}, nil
+}
+
+// signInFailed audits a rejected sign-in and returns the error to report
+func (s *authService) signInFailed(ctx context.Context, userID int, email, reason string) error {
+	event := models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "user",
+		Details:    map[string]interface{}{"method": "password", "reason": reason, "email": strings.ToLower(email)},
+	}
+	if userID != 0 {
+		event.TargetID = strconv.Itoa(userID)
+	}
+	s.audit.Record(ctx, event)
+
+	return apperrors.NewUnauthorizedError("invalid email or password")
+}
+
+// forbidImpersonation refuses account changes that only the account owner
+// may make
+func forbidImpersonation(principal *auth.Principal) error {
+	if principal.Impersonated() {
+		return apperrors.NewForbiddenError("not allowed while impersonating").WithReason(ReasonImpersonation)
+	}
+	return nil
+}
+
+// Ensure authService implements AuthService interface
+var _ AuthService = (*authService)(nil)
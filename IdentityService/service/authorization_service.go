@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+)
+
+// selfScopedPermissions only apply to resources owned by the principal
+var selfScopedPermissions = map[auth.Permission]bool{
+	auth.PermProfileRead:  true,
+	auth.PermProfileWrite: true,
+}
+
+// impersonationDeniedPermissions are never granted to impersonation
+// sessions, whatever the impersonated user holds; they create credentials,
+// delegate access or change other accounts, all of which would outlive
+// the session or extend it
+var impersonationDeniedPermissions = map[auth.Permission]bool{
+	auth.PermAPIKeysManage:     true,
+	auth.PermUsersImpersonate:  true,
+	auth.PermOAuthClients:      true,
+	auth.PermSCIMManage:        true,
+	auth.PermRolesAssign:       true,
+	auth.PermWebhooksManage:    true,
+	auth.PermUsersManage:       true,
+	auth.PermUsersDisable:      true,
+	auth.PermUsersCreate:       true,
+	auth.PermUsersImport:       true,
+	auth.PermInvitationsManage: true,
+
+	// Every platform permission, which must never be reached by
+	// impersonating a platform administrator
+	auth.PermAuditVerify: true,
+}
+
+// platformPermissions only count when granted platform-wide; holding the
+// same role in an organization is not enough
+var platformPermissions = map[auth.Permission]bool{
+	auth.PermAuditVerify:  true,
+	auth.PermUsersDisable: true,
+}
+
+// authorizationService implements the AuthorizationService interface
+type authorizationService struct {
+	roles repository.RoleRepository
+	users repository.UserRepository
+	audit AuditService
+}
+
+// NewAuthorizationService creates a new AuthorizationService instance
+func NewAuthorizationService(roles repository.RoleRepository, users repository.UserRepository, audit AuditService) AuthorizationService {
+	return &authorizationService{
+		roles: roles,
+		users: users,
+		audit: audit,
+	}
+}
+
+func (s *authorizationService) Can(ctx context.Context, principal *auth.Principal, permission auth.Permission, resource *auth.Resource) (bool, error) {
+	if principal == nil {
+		return false, nil
+	}
+
+	if selfScopedPermissions[permission] && resource != nil && resource.OwnerID != principal.UserID {
+		return false, nil
+	}
+
+	if !principal.AllowsScope(permission) {
+		return false, nil
+	}
+	if principal.Impersonated() && impersonationDeniedPermissions[permission] {
+		return false, nil
+	}
+
+	// Platform-wide grants always apply; org grants only in the active org
+	orgID := principal.OrganizationID
+	if platformPermissions[permission] {
+		orgID = 0
+	}
+	permissions, err := s.roles.GetPermissionsForUser(ctx, orgID, principal.UserID)
+	if err != nil {
+		return false, apperrors.NewInternalServerError("failed to load permissions", err)
+	}
+
+	for _, p := range permissions {
+		if auth.Permission(p) == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *authorizationService) GetAllRoles(ctx context.Context) ([]models.Role, error) {
+	roles, err := s.roles.GetAll(ctx)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve roles", err)
+	}
+	return roles, nil
+}
+
+func (s *authorizationService) GetUserRoles(ctx context.Context, orgID, userID int) ([]string, error) {
+	roles, err := s.roles.GetRoleNamesForUser(ctx, orgID, userID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve user roles", err)
+	}
+	return roles, nil
+}
+
+func (s *authorizationService) AssignRole(ctx context.Context, orgID, userID int, role string) error {
+	if err := s.ensureUserAndRole(ctx, orgID, userID, role); err != nil {
+		return err
+	}
+
+	if _, err := s.roles.AssignRole(ctx, orgID, userID, role); err != nil {
+		return apperrors.NewInternalServerError("failed to assign role", err)
+	}
+
+	s.recordRoleChange(ctx, models.AuditRoleAssigned, orgID, userID, role)
+	return nil
+}
+
+func (s *authorizationService) RemoveRole(ctx context.Context, orgID, userID int, role string) error {
+	if err := s.ensureUserAndRole(ctx, orgID, userID, role); err != nil {
+		return err
+	}
+
+	removed, err := s.roles.RemoveRole(ctx, orgID, userID, role)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to remove role", err)
+	}
+	if !removed {
+		return apperrors.NewNotFoundError("user does not have this role")
+	}
+
+	s.recordRoleChange(ctx, models.AuditRoleRemoved, orgID, userID, role)
+	return nil
+}
+
+func (s *authorizationService) recordRoleChange(ctx context.Context, action string, orgID, userID int, role string) {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         action,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(userID),
+		Details:        map[string]interface{}{"role": role},
+	})
+}
+
+// ensureUserAndRole checks the user belongs to the organization and the role exists
+func (s *authorizationService) ensureUserAndRole(ctx context.Context, orgID, userID int, role string) error {
+	if _, err := s.users.GetByID(ctx, orgID, userID); err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return apperrors.NewNotFoundError("user not found")
+		}
+		return apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
+
+	exists, err := s.roles.RoleExists(ctx, role)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to check role existence", err)
+	}
+	if !exists {
+		return apperrors.NewNotFoundError("role not found")
+	}
+
+	return nil
+}
+
+// Ensure authorizationService implements AuthorizationService interface
+var _ AuthorizationService = (*authorizationService)(nil)
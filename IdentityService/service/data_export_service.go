@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"time"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+)
+
+// maxDataRequestReasonLength matches the data_exports.reason column
+const maxDataRequestReasonLength = 500
+
+// dataExportService implements the DataExportService interface
+type dataExportService struct {
+	repo  repository.DataExportRepository
+	users UserService
+	audit AuditService
+}
+
+// NewDataExportService creates a new DataExportService instance
+func NewDataExportService(repo repository.DataExportRepository, users UserService, audit AuditService) DataExportService {
+	return &dataExportService{
+		repo:  repo,
+		users: users,
+		audit: audit,
+	}
+}
+
+// ExportOwnData builds the caller's complete archive across every
+// organization they belong to
+func (s *dataExportService) ExportOwnData(ctx context.Context, principal *auth.Principal) (*models.DataExport, error) {
+	if principal.UserID == 0 {
+		return nil, apperrors.NewForbiddenError("only users can export their own data")
+	}
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+
+	return s.export(ctx, principal, principal.UserID, 0, "")
+}
+
+// ExportUserData builds a user's archive on their behalf. Within an
+// organization the archive is limited to that organization and the user
+// must be one of its members; platform administrators get everything.
+func (s *dataExportService) ExportUserData(ctx context.Context, principal *auth.Principal, userID int, reason string) (*models.DataExport, error) {
+	if len(reason) > maxDataRequestReasonLength {
+		return nil, apperrors.NewBadRequestError("reason is too long", nil)
+	}
+
+	return s.export(ctx, principal, userID, principal.OrganizationID, reason)
+}
+
+func (s *dataExportService) GetExportRecords(ctx context.Context, orgID, userID int) ([]models.DataExportRecord, error) {
+	records, err := s.repo.GetRecords(ctx, userID, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve data exports", err)
+	}
+	return records, nil
+}
+
+// RectifyUser corrects a member's name and email on their behalf, noting
+// the reason in the audit log. Both are shared by every organization the
+// user belongs to, so only a user who belongs to no other can be corrected.
+func (s *dataExportService) RectifyUser(ctx context.Context, orgID, userID int, name, email, reason string) (*models.User, error) {
+	if len(reason) > maxDataRequestReasonLength {
+		return nil, apperrors.NewBadRequestError("reason is too long", nil)
+	}
+
+	elsewhere, err := s.users.BelongsElsewhere(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if elsewhere {
+		return nil, apperrors.NewForbiddenError("a user who belongs to other organizations cannot be rectified here")
+	}
+
+	return s.users.UpdateUser(WithAuditReason(ctx, reason), orgID, userID, name, email)
+}
+
+func (s *dataExportService) export(ctx context.Context, principal *auth.Principal, userID, orgID int, reason string) (*models.DataExport, error) {
+	export, err := s.repo.Collect(ctx, userID, orgID)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.NewNotFoundError("user not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to collect user data", err)
+	}
+
+	now := time.Now().UTC()
+	record := &models.DataExportRecord{
+		UserID:    userID,
+		Reason:    reason,
+		CreatedAt: now,
+	}
+	if orgID != 0 {
+		record.OrganizationID = &orgID
+	}
+	if principal.UserID != 0 {
+		record.RequestedBy = &principal.UserID
+	}
+
+	// An archive is only handed out once its production is on record
+	record, err = s.repo.Record(ctx, record)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to record data export", err)
+	}
+	export.GeneratedAt = now
+	export.Exports = append([]models.DataExportRecord{*record}, export.Exports...)
+
+	scope := "full"
+	if orgID != 0 {
+		scope = "organization"
+	}
+	s.audit.Record(WithAuditReason(ctx, reason), models.AuditEvent{
+		Action:     models.AuditUserDataExported,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Details:    map[string]interface{}{"scope": scope, "export_id": record.ID},
+	})
+
+	return export, nil
+}
+
+// Ensure dataExportService implements DataExportService interface
+var _ DataExportService = (*dataExportService)(nil)
@@ -2,11 +2,215 @@ package service
 
 import (
 	"context"
+	"identity-service/auth"
 	"identity-service/models"
+	"identity-service/scim"
+	"io"
+	"time"
 )
 
 // UserService defines the business logic interface for user operations
 type UserService interface {
-	GetAllUsers(ctx context.Context) ([]models.User, error)
-	CreateUser(ctx context.Context, name, email string) (*models.User, error)
+	GetAllUsers(ctx context.Context, orgID int, filter models.UserFilter) ([]models.User, error)
+	CreateUser(ctx context.Context, orgID int, name, email, password, passwordHash string) (*models.User, error)
+	UpdateUser(ctx context.Context, orgID, id int, name, email string) (*models.User, error)
+	BelongsElsewhere(ctx context.Context, orgID, id int) (bool, error)
+	SetUserActive(ctx context.Context, orgID, id int, active bool) error
+	RemoveUser(ctx context.Context, orgID, id int) error
+	UnlockUser(ctx context.Context, orgID, id int) error
+	DisableUser(ctx context.Context, orgID, id int, reason string) error
+	EnableUser(ctx context.Context, orgID, id int, reason string) error
+	SignOutUser(ctx context.Context, orgID, id int, reason string) (int, error)
+	RequirePasswordChange(ctx context.Context, orgID, id int, reason string) error
+	ResendVerification(ctx context.Context, orgID, id int, reason string) error
+}
+
+// ProfileService defines the business logic interface for self-service
+// profile management, email changes and email verification
+type ProfileService interface {
+	GetProfile(ctx context.Context, userID int) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, userID int, req models.UpdateProfileRequest) (*models.Profile, error)
+	RequestEmailChange(ctx context.Context, principal *auth.Principal, newEmail, currentPassword string) (*models.EmailChange, error)
+	ConfirmEmailChange(ctx context.Context, token string) error
+	CancelEmailChange(ctx context.Context, userID int) error
+	SendVerification(ctx context.Context, userID int) (*models.EmailVerification, error)
+	VerifyEmail(ctx context.Context, token string) error
+	DeleteAccount(ctx context.Context, principal *auth.Principal) error
+}
+
+// AuthorizationService decides what an authenticated principal may do
+type AuthorizationService interface {
+	Can(ctx context.Context, principal *auth.Principal, permission auth.Permission, resource *auth.Resource) (bool, error)
+	GetAllRoles(ctx context.Context) ([]models.Role, error)
+	GetUserRoles(ctx context.Context, orgID, userID int) ([]string, error)
+	AssignRole(ctx context.Context, orgID, userID int, role string) error
+	RemoveRole(ctx context.Context, orgID, userID int, role string) error
+}
+
+// OrganizationService defines the business logic interface for organizations and invitations
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, ownerID int, name, slug string) (*models.Organization, error)
+	GetOrganizationsForUser(ctx context.Context, userID int) ([]models.Organization, error)
+	IsMember(ctx context.Context, orgID, userID int) (bool, error)
+	InviteMember(ctx context.Context, orgID, invitedBy int, email, role string) (*models.Invitation, error)
+	GetInvitations(ctx context.Context, orgID int) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, orgID, invitationID int) error
+	AcceptInvitation(ctx context.Context, principal *auth.Principal, token string) (*models.Invitation, error)
+	DeclineInvitation(ctx context.Context, token string) error
+	GetPasswordPolicy(ctx context.Context, orgID int) (*models.PasswordPolicy, error)
+	UpdatePasswordPolicy(ctx context.Context, orgID int, policy models.PasswordPolicy) (*models.PasswordPolicy, error)
+}
+
+// APIKeyService defines the business logic interface for personal API keys
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, principal *auth.Principal, name string, scopes []string, expiresAt *time.Time) (*models.CreatedAPIKey, error)
+	GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id int) error
+	Authenticate(ctx context.Context, rawKey, ip string) (*auth.Principal, error)
+}
+
+// AuditService defines the interface for the security audit log
+type AuditService interface {
+	Record(ctx context.Context, event models.AuditEvent)
+	Query(ctx context.Context, filter models.AuditFilter) (*models.AuditPage, error)
+	Verify(ctx context.Context, afterID int64) (*models.AuditVerification, error)
+}
+
+// WebhookService defines the business logic interface for webhook endpoints and deliveries
+type WebhookService interface {
+	CreateEndpoint(ctx context.Context, orgID int, url string, eventTypes []string) (*models.CreatedWebhookEndpoint, error)
+	GetEndpoints(ctx context.Context, orgID int) ([]models.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, orgID, id int) error
+	GetDeliveries(ctx context.Context, orgID int, status string) ([]models.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, orgID int, id int64) error
+}
+
+// LockoutService defines the business logic interface for failed sign-in tracking and account lockout
+type LockoutService interface {
+	CheckIP(ctx context.Context, ip string) error
+	RecordFailure(ctx context.Context, userID int, ip string)
+	RecordSuccess(ctx context.Context, userID int)
+	Unlock(ctx context.Context, userID int) (bool, error)
+}
+
+// PasswordService defines the business logic interface for password policy, history and expiry
+type PasswordService interface {
+	SetPassword(ctx context.Context, userID int, password string) error
+	HashPasswordChange(ctx context.Context, userID int, password string) (string, error)
+	ApplyPasswordChange(ctx context.Context, userID int, hash string) error
+	HashNewPassword(password, name, email string) (string, error)
+	ImportPasswordHash(ctx context.Context, userID int, hash string) error
+	PasswordExpired(ctx context.Context, cred *models.PasswordCredential) (bool, error)
+	Reverify(ctx context.Context, cred *models.PasswordCredential, password, purpose string) error
+}
+
+// UserImportService defines the business logic interface for bulk user import and export
+type UserImportService interface {
+	Import(ctx context.Context, orgID int, src io.Reader, opts models.UserImportOptions, progress func(models.UserImportProgress)) (*models.UserImportResult, error)
+	Export(ctx context.Context, orgID int, dst io.Writer, opts models.UserExportOptions) (int, error)
+}
+
+// DataExportService defines the business logic interface for data subject
+// access and rectification requests
+type DataExportService interface {
+	ExportOwnData(ctx context.Context, principal *auth.Principal) (*models.DataExport, error)
+	ExportUserData(ctx context.Context, principal *auth.Principal, userID int, reason string) (*models.DataExport, error)
+	GetExportRecords(ctx context.Context, orgID, userID int) ([]models.DataExportRecord, error)
+	RectifyUser(ctx context.Context, orgID, userID int, name, email, reason string) (*models.User, error)
+}
+
+// AuthService defines the business logic interface for password and
+// passwordless sign-in and sessions
+type AuthService interface {
+	SignIn(ctx context.Context, email, password string) (*models.SignInResponse, error)
+	ChangeExpiredPassword(ctx context.Context, email, currentPassword, newPassword string) (*models.SignInResponse, error)
+	RequestMagicLink(ctx context.Context, email string) (*models.MagicLinkResponse, error)
+	RedeemMagicLink(ctx context.Context, token, deviceToken string) (*models.SignInResponse, error)
+	RequestSignInCode(ctx context.Context, email, channel string) (*models.OTPResponse, error)
+	SendCode(ctx context.Context, token string, factorID int) error
+	VerifyCode(ctx context.Context, token, code string, rememberDevice bool) (*models.SignInResponse, error)
+	StepUp(ctx context.Context, principal *auth.Principal, password string) (*models.StepUpResponse, error)
+	VerifyStepUp(ctx context.Context, principal *auth.Principal, token, code string) (*models.StepUpResponse, error)
+	CheckAuthentication(ctx context.Context, principal *auth.Principal, req auth.Requirement) (bool, error)
+	ChangePassword(ctx context.Context, principal *auth.Principal, currentPassword, newPassword string) error
+	SignOut(ctx context.Context, principal *auth.Principal) error
+	Authenticate(ctx context.Context, token, ip string) (*auth.Principal, error)
+	Impersonate(ctx context.Context, principal *auth.Principal, userID int, reason string) (*models.ImpersonationResponse, error)
+}
+
+// SessionService defines the business logic interface for the caller's
+// sessions and trusted devices
+type SessionService interface {
+	GetSessions(ctx context.Context, principal *auth.Principal) (*models.SessionList, error)
+	RevokeSession(ctx context.Context, principal *auth.Principal, id int64) error
+	RevokeTrustedDevice(ctx context.Context, principal *auth.Principal, id int64) error
+}
+
+// OTPService defines the business logic interface for issuing, sending
+// and checking one-time passcodes
+type OTPService interface {
+	Begin(ctx context.Context, challenge *models.OTPChallenge) (*models.OTPChallenge, string, error)
+	Adopt(ctx context.Context, challenge *models.OTPChallenge, response *models.OTPResponse) (*models.OTPChallenge, error)
+	Decoy() (*models.OTPResponse, error)
+	Send(ctx context.Context, challenge *models.OTPChallenge, token string, factor *models.MFAFactor) (bool, error)
+	Verify(ctx context.Context, challenge *models.OTPChallenge, token, code string) (bool, error)
+}
+
+// RiskService defines the business logic interface for scoring sign-ins
+// against the user's sign-in history
+type RiskService interface {
+	Assess(ctx context.Context, cred *models.PasswordCredential) (*models.RiskAssessment, error)
+	RecordSignIn(ctx context.Context, cred *models.PasswordCredential, sessionID int64)
+}
+
+// MFAService defines the business logic interface for the caller's
+// one-time passcode factors
+type MFAService interface {
+	GetFactors(ctx context.Context, principal *auth.Principal) ([]models.MFAFactor, error)
+	EnrollFactor(ctx context.Context, principal *auth.Principal, req models.EnrollFactorRequest) (*models.FactorEnrollment, error)
+	ConfirmFactor(ctx context.Context, principal *auth.Principal, token, code string) (*models.MFAFactor, error)
+	DeleteFactor(ctx context.Context, principal *auth.Principal, id int) error
+}
+
+// SCIMService defines the business logic interface for SCIM provisioning
+// tokens and the users and groups they provision
+type SCIMService interface {
+	CreateToken(ctx context.Context, orgID, createdBy int, name string) (*models.CreatedSCIMToken, error)
+	GetTokens(ctx context.Context, orgID int) ([]models.SCIMToken, error)
+	RevokeToken(ctx context.Context, orgID, id int) error
+	Authenticate(ctx context.Context, rawToken, ip string) (*auth.Principal, error)
+	ListUsers(ctx context.Context, orgID int, query scim.Query) ([]*scim.User, int, error)
+	GetUser(ctx context.Context, orgID, id int) (*scim.User, error)
+	CreateUser(ctx context.Context, orgID int, user *scim.User) (*scim.User, error)
+	ReplaceUser(ctx context.Context, orgID, id int, user *scim.User) (*scim.User, error)
+	PatchUser(ctx context.Context, orgID, id int, ops []scim.PatchOperation) (*scim.User, error)
+	DeleteUser(ctx context.Context, orgID, id int) error
+	ListGroups(ctx context.Context, orgID int, query scim.Query) ([]*scim.Group, int, error)
+	GetGroup(ctx context.Context, orgID, id int) (*scim.Group, error)
+	CreateGroup(ctx context.Context, orgID int, group *scim.Group) (*scim.Group, error)
+	ReplaceGroup(ctx context.Context, orgID, id int, group *scim.Group) (*scim.Group, error)
+	PatchGroup(ctx context.Context, orgID, id int, ops []scim.PatchOperation) (*scim.Group, error)
+	DeleteGroup(ctx context.Context, orgID, id int) error
+}
+
+// OAuthService defines the business logic interface for OAuth clients,
+// RFC 8693 token exchange and RFC 7662 introspection of exchanged tokens
+type OAuthService interface {
+	CreateClient(ctx context.Context, principal *auth.Principal, req models.CreateOAuthClientRequest) (*models.CreatedOAuthClient, error)
+	GetClients(ctx context.Context, orgID int) ([]models.OAuthClient, error)
+	RevokeClient(ctx context.Context, orgID, id int) error
+	ExchangeToken(ctx context.Context, clientID, clientSecret string, req models.TokenExchangeRequest) (*models.TokenResponse, error)
+	Introspect(ctx context.Context, clientID, clientSecret, token string) (*models.IntrospectionResponse, error)
+}
+
+// AttributeService defines the business logic interface for custom user
+// attributes and their per-organization schema
+type AttributeService interface {
+	GetDefinitions(ctx context.Context, orgID int) ([]models.AttributeDefinition, error)
+	CreateDefinition(ctx context.Context, orgID int, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error)
+	UpdateDefinition(ctx context.Context, orgID, id int, req models.AttributeDefinitionRequest) (*models.AttributeDefinition, error)
+	DeleteDefinition(ctx context.Context, orgID, id int) error
+	GetAttributes(ctx context.Context, orgID, userID int) (map[string]interface{}, error)
+	SetAttributes(ctx context.Context, orgID, userID int, attrs map[string]interface{}) (map[string]interface{}, error)
+	GetClaims(ctx context.Context, orgID, userID int) (map[string]interface{}, error)
 }
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	"identity-service/mail"
+	"identity-service/models"
+	"identity-service/repository"
+)
+
+// ipPruneInterval is how often expired IP counters are deleted
+const ipPruneInterval = 5 * time.Minute
+
+// lockoutService implements the LockoutService interface
+type lockoutService struct {
+	repo   repository.LockoutRepository
+	mailer mail.Sender
+	audit  AuditService
+	config *config.LockoutConfig
+	log    *slog.Logger
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewLockoutService creates a new LockoutService instance
+func NewLockoutService(repo repository.LockoutRepository, mailer mail.Sender, audit AuditService, cfg *config.LockoutConfig, log *slog.Logger) LockoutService {
+	return &lockoutService{
+		repo:   repo,
+		mailer: mailer,
+		audit:  audit,
+		config: cfg,
+		log:    log,
+	}
+}
+
+// CheckIP rejects attempts from an address that has failed too often.
+// The response is the same whether or not any account exists.
+func (s *lockoutService) CheckIP(ctx context.Context, ip string) error {
+	lockedUntil, err := s.repo.IPLockedUntil(ctx, ip)
+	if err != nil {
+		return fmt.Errorf("failed to check ip lockout: %w", err)
+	}
+	if lockedUntil != nil && lockedUntil.After(time.Now().UTC()) {
+		return auth.ErrTooManyAttempts
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt against ip and, when userID is not
+// zero, against the account. Errors are logged so that bookkeeping never
+// changes the response the caller sees.
+func (s *lockoutService) RecordFailure(ctx context.Context, userID int, ip string) {
+	now := time.Now().UTC()
+	s.recordIPFailure(ctx, ip, now)
+	if userID == 0 {
+		return
+	}
+
+	failures, email, err := s.repo.RecordUserFailure(ctx, userID, now, s.config.FailureWindow)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to record sign-in failure",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	var until time.Time
+	switch {
+	case failures >= s.config.Threshold:
+		until = now.Add(s.config.Duration)
+	case failures >= s.config.DelayAfter:
+		until = now.Add(s.delay(failures))
+	default:
+		return
+	}
+
+	if err := s.repo.LockUser(ctx, userID, until); err != nil {
+		s.log.ErrorContext(ctx, "failed to lock account",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	// Short progressive delays are routine; only full lockouts are reported
+	if failures < s.config.Threshold {
+		return
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditUserLocked,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Details:    map[string]interface{}{"failures": failures, "locked_until": until},
+	})
+
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Your account has been temporarily locked",
+		Body: fmt.Sprintf(
+			"We locked your account after %d failed sign-in attempts.\n\nYou can sign in again after %s. If these attempts were not you, contact your administrator.",
+			failures, until.Format(time.RFC1123),
+		),
+	}); err != nil {
+		s.log.ErrorContext(ctx, "failed to send lockout notification",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// RecordSuccess clears the account's failure count
+func (s *lockoutService) RecordSuccess(ctx context.Context, userID int) {
+	if _, err := s.repo.ResetUser(ctx, userID); err != nil {
+		s.log.ErrorContext(ctx, "failed to reset sign-in failures",
+			slog.Int("user_id", userID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Unlock lifts an account lock and clears its failure count
+func (s *lockoutService) Unlock(ctx context.Context, userID int) (bool, error) {
+	unlocked, err := s.repo.ResetUser(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if unlocked {
+		s.audit.Record(ctx, models.AuditEvent{
+			Action:     models.AuditUserUnlocked,
+			TargetType: "user",
+			TargetID:   strconv.Itoa(userID),
+		})
+	}
+	return unlocked, nil
+}
+
+func (s *lockoutService) recordIPFailure(ctx context.Context, ip string, now time.Time) {
+	if ip == "" {
+		return
+	}
+	s.pruneIPFailures(ctx, now)
+
+	failures, err := s.repo.RecordIPFailure(ctx, ip, now, s.config.IPWindow)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to record ip sign-in failure",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	if failures < s.config.IPThreshold {
+		return
+	}
+
+	if err := s.repo.LockIP(ctx, ip, now.Add(s.config.IPDuration)); err != nil {
+		s.log.ErrorContext(ctx, "failed to lock ip",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	s.log.WarnContext(ctx, "ip locked after repeated sign-in failures",
+		slog.String("ip", ip),
+		slog.Int("failures", failures),
+	)
+}
+
+// pruneIPFailures deletes stale counters at most once per interval
+func (s *lockoutService) pruneIPFailures(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastPrune) < ipPruneInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.mu.Unlock()
+
+	cutoff := now.Add(-max(s.config.IPWindow, s.config.IPDuration))
+	if err := s.repo.PruneIPFailures(ctx, cutoff); err != nil {
+		s.log.ErrorContext(ctx, "failed to prune ip sign-in failures",
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// delay doubles the lock for each failure past DelayAfter, up to MaxDelay
+func (s *lockoutService) delay(failures int) time.Duration {
+	delay := s.config.BaseDelay
+	for i := s.config.DelayAfter; i < failures && delay < s.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.config.MaxDelay)
+}
+
+// Ensure lockoutService implements LockoutService interface
+var _ LockoutService = (*lockoutService)(nil)
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/validation"
+)
+
+// mfaService implements the MFAService interface. A factor is only offered
+// at sign-in once the code sent to it during enrollment has been entered.
+type mfaService struct {
+	repo      repository.MFARepository
+	otp       OTPService
+	validator *validation.Validator
+	audit     AuditService
+}
+
+// NewMFAService creates a new MFAService instance
+func NewMFAService(repo repository.MFARepository, otp OTPService, validator *validation.Validator, audit AuditService) MFAService {
+	return &mfaService{
+		repo:      repo,
+		otp:       otp,
+		validator: validator,
+		audit:     audit,
+	}
+}
+
+func (s *mfaService) GetFactors(ctx context.Context, principal *auth.Principal) ([]models.MFAFactor, error) {
+	factors, err := s.repo.GetFactors(ctx, principal.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve factors", err)
+	}
+	return factors, nil
+}
+
+// EnrollFactor adds an unverified factor and sends a code to it. An email
+// factor always uses the account's current address.
+func (s *mfaService) EnrollFactor(ctx context.Context, principal *auth.Principal, req models.EnrollFactorRequest) (*models.FactorEnrollment, error) {
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+
+	factor := &models.MFAFactor{
+		UserID:    principal.UserID,
+		Type:      strings.ToLower(strings.TrimSpace(req.Type)),
+		CreatedAt: time.Now().UTC(),
+	}
+	switch factor.Type {
+	case models.FactorEmail:
+	case models.FactorSMS:
+		factor.Destination = strings.TrimSpace(req.Phone)
+		if err := s.validator.ValidatePhoneNumber(factor.Destination); err != nil {
+			return nil, apperrors.NewBadRequestError("validation failed", err)
+		}
+	default:
+		return nil, apperrors.NewBadRequestError("type must be email or sms", nil)
+	}
+
+	factor, err := s.repo.CreateFactor(ctx, factor)
+	if stderrors.Is(err, repository.ErrConflict) {
+		return nil, apperrors.NewConflictError("factor is already enrolled", err)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create factor", err)
+	}
+
+	challenge, token, err := s.otp.Begin(ctx, &models.OTPChallenge{
+		UserID:   principal.UserID,
+		Purpose:  models.OTPPurposeEnroll,
+		FactorID: &factor.ID,
+	})
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to start enrollment", err)
+	}
+	sent, err := s.otp.Send(ctx, challenge, token, factor)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to send code", err)
+	}
+	if !sent {
+		return nil, apperrors.NewTooManyRequestsError("wait before requesting another code")
+	}
+
+	return &models.FactorEnrollment{Factor: *factor, OTPToken: token, ExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// ConfirmFactor verifies an enrolled factor with the code sent to it
+func (s *mfaService) ConfirmFactor(ctx context.Context, principal *auth.Principal, token, code string) (*models.MFAFactor, error) {
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+	if token == "" || code == "" {
+		return nil, apperrors.NewBadRequestError("otp_token and code are required", nil)
+	}
+
+	challenge, err := s.repo.GetChallenge(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewBadRequestError("invalid or expired code", nil)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve challenge", err)
+	}
+	if challenge.Purpose != models.OTPPurposeEnroll || challenge.UserID != principal.UserID || challenge.FactorID == nil {
+		return nil, apperrors.NewBadRequestError("invalid or expired code", nil)
+	}
+
+	ok, err := s.otp.Verify(ctx, challenge, token, code)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to verify code", err)
+	}
+	if !ok {
+		return nil, apperrors.NewBadRequestError("invalid or expired code", nil)
+	}
+
+	factor, err := s.repo.VerifyFactor(ctx, principal.UserID, *challenge.FactorID, time.Now().UTC())
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("factor not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to verify factor", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditMFAFactorAdded,
+		TargetType: "mfa_factor",
+		TargetID:   strconv.Itoa(factor.ID),
+		Details:    map[string]interface{}{"type": factor.Type},
+	})
+	return factor, nil
+}
+
+func (s *mfaService) DeleteFactor(ctx context.Context, principal *auth.Principal, id int) error {
+	if err := forbidImpersonation(principal); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteFactor(ctx, principal.UserID, id)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to delete factor", err)
+	}
+	if !deleted {
+		return apperrors.NewNotFoundError("factor not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditMFAFactorRemoved,
+		TargetType: "mfa_factor",
+		TargetID:   strconv.Itoa(id),
+	})
+	return nil
+}
+
+// Ensure mfaService implements MFAService interface
+var _ MFAService = (*mfaService)(nil)
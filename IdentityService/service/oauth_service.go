@@ -0,0 +1,589 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	stderrors "errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/requestinfo"
+	"identity-service/validation"
+)
+
+// OAuth client IDs look like idc_<random> and exchanged tokens like
+// idt_<secret>; only hashes of secrets and tokens are stored. The
+// identity API itself never accepts exchanged tokens.
+const (
+	oauthClientIDScheme    = "idc_"
+	oauthClientIDBytes     = 12
+	oauthClientSecretBytes = 32
+	exchangedTokenScheme   = "idt_"
+	exchangedTokenBytes    = 32
+)
+
+// Limits on client policies and token exchange requests
+const (
+	maxOAuthAudienceLength = 255
+	maxTokenExchangeScopes = 50
+)
+
+// tokenTypeBearer is the RFC 6750 token_type of exchanged tokens
+const tokenTypeBearer = "Bearer"
+
+// RFC 6749 and RFC 8693 error codes, reported as the reason of the error
+const (
+	OAuthInvalidRequest       = "invalid_request"
+	OAuthInvalidClient        = "invalid_client"
+	OAuthInvalidGrant         = "invalid_grant"
+	OAuthInvalidScope         = "invalid_scope"
+	OAuthInvalidTarget        = "invalid_target"
+	OAuthUnsupportedGrantType = "unsupported_grant_type"
+	OAuthUnsupportedTokenType = "unsupported_token_type"
+)
+
+// oauthService implements the OAuthService interface
+type oauthService struct {
+	oauth      repository.OAuthRepository
+	sessions   repository.SessionRepository
+	orgs       repository.OrganizationRepository
+	roles      repository.RoleRepository
+	authz      AuthorizationService
+	attributes AttributeService
+	lockout    LockoutService
+	validator  *validation.Validator
+	audit      AuditService
+	config     *config.OAuthConfig
+}
+
+// NewOAuthService creates a new OAuthService instance
+func NewOAuthService(
+	oauth repository.OAuthRepository,
+	sessions repository.SessionRepository,
+	orgs repository.OrganizationRepository,
+	roles repository.RoleRepository,
+	authz AuthorizationService,
+	attributes AttributeService,
+	lockout LockoutService,
+	validator *validation.Validator,
+	audit AuditService,
+	cfg *config.OAuthConfig,
+) OAuthService {
+	return &oauthService{
+		oauth:      oauth,
+		sessions:   sessions,
+		orgs:       orgs,
+		roles:      roles,
+		authz:      authz,
+		attributes: attributes,
+		lockout:    lockout,
+		validator:  validator,
+		audit:      audit,
+		config:     cfg,
+	}
+}
+
+// exchangeSubject is a token presented to token exchange, resolved to
+// the user it speaks for. Scopes is nil when the token carries all of
+// the user's permissions; OrganizationID is zero when it is not bound to
+// an organization. Impersonated is set for impersonation sessions.
+type exchangeSubject struct {
+	UserID         int
+	SessionID      int64
+	OrganizationID int
+	Scopes         []string
+	Act            *models.Actor
+	Impersonated   bool
+	ExpiresAt      time.Time
+}
+
+// CreateClient registers a client of the principal's active organization.
+// Like an API key, a client can never be allowed a scope its creator
+// does not hold.
+func (s *oauthService) CreateClient(ctx context.Context, principal *auth.Principal, req models.CreateOAuthClientRequest) (*models.CreatedOAuthClient, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := s.validator.ValidateName(name); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	audiences := normalizeList(req.AllowedAudiences)
+	if len(audiences) == 0 {
+		return nil, apperrors.NewBadRequestError("at least one allowed audience is required", nil)
+	}
+	for _, audience := range audiences {
+		if len(audience) > maxOAuthAudienceLength {
+			return nil, apperrors.NewBadRequestError(fmt.Sprintf("audiences must be at most %d characters", maxOAuthAudienceLength), nil)
+		}
+	}
+
+	scopes := normalizeList(req.AllowedScopes)
+	if len(scopes) == 0 {
+		return nil, apperrors.NewBadRequestError("at least one allowed scope is required", nil)
+	}
+	for _, scope := range scopes {
+		allowed, err := s.authz.Can(ctx, principal, auth.Permission(scope), nil)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, apperrors.NewForbiddenError(fmt.Sprintf("scope %q exceeds your permissions", scope))
+		}
+	}
+
+	id, err := auth.GenerateToken(oauthClientIDBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate client id", err)
+	}
+	secret, err := auth.GenerateToken(oauthClientSecretBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate client secret", err)
+	}
+
+	orgID := principal.OrganizationID
+	client := &models.OAuthClient{
+		OrganizationID:     orgID,
+		ClientID:           oauthClientIDScheme + id,
+		Name:               name,
+		AllowedAudiences:   audiences,
+		AllowedScopes:      scopes,
+		AllowImpersonation: req.AllowImpersonation,
+		CreatedAt:          time.Now().UTC(),
+	}
+	if principal.UserID != 0 {
+		client.CreatedBy = &principal.UserID
+	}
+
+	created, err := s.oauth.CreateClient(ctx, client, auth.HashToken(secret))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create oauth client", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditOAuthClientCreated,
+		OrganizationID: &orgID,
+		TargetType:     "oauth_client",
+		TargetID:       strconv.Itoa(created.ID),
+		Details: map[string]interface{}{
+			"client_id":           created.ClientID,
+			"name":                created.Name,
+			"allowed_audiences":   created.AllowedAudiences,
+			"allowed_scopes":      created.AllowedScopes,
+			"allow_impersonation": created.AllowImpersonation,
+		},
+	})
+
+	return &models.CreatedOAuthClient{OAuthClient: *created, ClientSecret: secret}, nil
+}
+
+func (s *oauthService) GetClients(ctx context.Context, orgID int) ([]models.OAuthClient, error) {
+	clients, err := s.oauth.GetClients(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve oauth clients", err)
+	}
+	return clients, nil
+}
+
+// RevokeClient revokes a client together with every token it obtained
+func (s *oauthService) RevokeClient(ctx context.Context, orgID, id int) error {
+	revoked, err := s.oauth.RevokeClient(ctx, orgID, id, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke oauth client", err)
+	}
+	if !revoked {
+		return apperrors.NewNotFoundError("oauth client not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditOAuthClientRevoked,
+		OrganizationID: &orgID,
+		TargetType:     "oauth_client",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+// ExchangeToken implements the RFC 8693 token exchange grant. The subject
+// token, a session or an earlier exchanged token, is traded for a token
+// bound to the client's organization and one of its allowed audiences,
+// carrying no more scopes than the client is allowed and the subject
+// token holds. The new token records who acts for the subject: the actor
+// token's user (delegation), the client itself, or nobody new when the
+// client may impersonate. Actors of the subject token are kept nested.
+func (s *oauthService) ExchangeToken(ctx context.Context, clientID, clientSecret string, req models.TokenExchangeRequest) (*models.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SubjectToken == "" || req.SubjectTokenType == "" {
+		return nil, oauthError(OAuthInvalidRequest, "subject_token and subject_token_type are required")
+	}
+	if req.SubjectTokenType != models.TokenTypeAccessToken {
+		return nil, oauthError(OAuthUnsupportedTokenType, "subject_token_type is not supported")
+	}
+	if (req.ActorToken == "") != (req.ActorTokenType == "") {
+		return nil, oauthError(OAuthInvalidRequest, "actor_token and actor_token_type must be given together")
+	}
+	if req.ActorToken != "" && req.ActorTokenType != models.TokenTypeAccessToken {
+		return nil, oauthError(OAuthUnsupportedTokenType, "actor_token_type is not supported")
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != models.TokenTypeAccessToken {
+		return nil, oauthError(OAuthInvalidRequest, "requested_token_type is not supported")
+	}
+
+	audience := req.Audience
+	if audience == "" && len(client.AllowedAudiences) == 1 {
+		audience = client.AllowedAudiences[0]
+	}
+	if audience == "" {
+		return nil, oauthError(OAuthInvalidRequest, "audience is required")
+	}
+	if !slices.Contains(client.AllowedAudiences, audience) {
+		return nil, oauthError(OAuthInvalidTarget, "audience is not allowed for this client")
+	}
+
+	orgID := client.OrganizationID
+	subject, err := s.resolveSubject(ctx, req.SubjectToken, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if subject == nil {
+		return nil, oauthError(OAuthInvalidGrant, "subject_token is invalid or expired")
+	}
+
+	scopes, err := s.grantedScopes(ctx, client, subject, strings.Fields(req.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	act := subject.Act
+	if req.ActorToken != "" {
+		actor, err := s.resolveSubject(ctx, req.ActorToken, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if actor == nil {
+			return nil, oauthError(OAuthInvalidGrant, "actor_token is invalid or expired")
+		}
+		act = &models.Actor{Subject: strconv.Itoa(actor.UserID), Act: subject.Act}
+	} else if !client.AllowImpersonation {
+		act = &models.Actor{ClientID: client.ClientID, Act: subject.Act}
+	}
+
+	secret, err := auth.GenerateToken(exchangedTokenBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate token", err)
+	}
+	token := exchangedTokenScheme + secret
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.config.TokenTTL)
+	if subject.ExpiresAt.Before(expiresAt) {
+		expiresAt = subject.ExpiresAt
+	}
+
+	issued, err := s.oauth.CreateAccessToken(ctx, &models.AccessToken{
+		ClientID:       client.ID,
+		OrganizationID: orgID,
+		UserID:         subject.UserID,
+		SessionID:      subject.SessionID,
+		Audience:       audience,
+		Scopes:         scopes,
+		Act:            act,
+		IssuedAt:       now,
+		ExpiresAt:      expiresAt,
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to issue token", err)
+	}
+
+	details := map[string]interface{}{
+		"client_id": client.ClientID,
+		"audience":  audience,
+		"scopes":    scopes,
+		"token_id":  issued.ID,
+	}
+	if act != nil {
+		details["act"] = act
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditTokenExchanged,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(subject.UserID),
+		Details:        details,
+	})
+
+	return &models.TokenResponse{
+		AccessToken:     token,
+		IssuedTokenType: models.TokenTypeAccessToken,
+		TokenType:       tokenTypeBearer,
+		ExpiresIn:       int(expiresAt.Sub(now).Seconds()),
+		Scope:           strings.Join(scopes, " "),
+	}, nil
+}
+
+// Introspect implements RFC 7662 for exchanged tokens. A client may only
+// introspect tokens of its own organization; anything else, like an
+// unknown or expired token, is reported as merely inactive. An active
+// token's subject is described with their roles and attribute claims in
+// that organization.
+func (s *oauthService) Introspect(ctx context.Context, clientID, clientSecret, token string) (*models.IntrospectionResponse, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := &models.IntrospectionResponse{Active: false}
+	if !strings.HasPrefix(token, exchangedTokenScheme) {
+		return inactive, nil
+	}
+
+	cred, err := s.oauth.GetAccessTokenByHash(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return inactive, nil
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to look up token", err)
+	}
+	if cred.Revoked || !cred.ExpiresAt.After(time.Now().UTC()) || cred.OrganizationID != client.OrganizationID {
+		return inactive, nil
+	}
+
+	roles, err := s.roles.GetRoleNamesForUser(ctx, cred.OrganizationID, cred.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve roles", err)
+	}
+	claims, err := s.attributes.GetClaims(ctx, cred.OrganizationID, cred.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IntrospectionResponse{
+		Active:         true,
+		Scope:          strings.Join(cred.Scopes, " "),
+		ClientID:       cred.ClientPublicID,
+		Username:       cred.UserEmail,
+		TokenType:      tokenTypeBearer,
+		Exp:            cred.ExpiresAt.Unix(),
+		Iat:            cred.IssuedAt.Unix(),
+		Sub:            strconv.Itoa(cred.UserID),
+		Aud:            cred.Audience,
+		Act:            cred.Act,
+		ACR:            auth.ACR(cred.SessionAMR),
+		AMR:            cred.SessionAMR,
+		AuthTime:       cred.SessionAuthTime.Unix(),
+		OrganizationID: cred.OrganizationID,
+		Roles:          roles,
+		Attributes:     claims,
+	}, nil
+}
+
+// authenticateClient checks a client's credentials, applying the same
+// per-IP lockout as other machine credentials
+func (s *oauthService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClientCredential, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, oauthClientError()
+	}
+
+	ip := requestinfo.From(ctx).IP
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		if stderrors.Is(err, auth.ErrTooManyAttempts) {
+			return nil, apperrors.NewTooManyRequestsError("too many failed attempts")
+		}
+		return nil, apperrors.NewInternalServerError("failed to check lockout", err)
+	}
+
+	client, err := s.oauth.GetClientByClientID(ctx, clientID)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.clientAuthFailed(ctx, nil, "unknown")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to look up oauth client", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.clientAuthFailed(ctx, client, "invalid_secret")
+	}
+	if client.RevokedAt != nil {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.clientAuthFailed(ctx, client, "revoked")
+	}
+
+	if err := s.oauth.RecordClientUsage(ctx, client.ID, time.Now().UTC()); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to record oauth client usage", err)
+	}
+	return client, nil
+}
+
+// clientAuthFailed audits a rejected client and returns the error to report
+func (s *oauthService) clientAuthFailed(ctx context.Context, client *models.OAuthClientCredential, reason string) error {
+	event := models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "oauth_client",
+		Details:    map[string]interface{}{"method": "oauth_client", "reason": reason},
+	}
+	if client != nil {
+		event.OrganizationID = &client.OrganizationID
+		event.TargetID = strconv.Itoa(client.ID)
+	}
+	s.audit.Record(ctx, event)
+
+	return oauthClientError()
+}
+
+// resolveSubject resolves a session or exchanged token to the user it
+// speaks for within the organization. A nil subject means the token is
+// not usable there.
+func (s *oauthService) resolveSubject(ctx context.Context, token string, orgID int) (*exchangeSubject, error) {
+	now := time.Now().UTC()
+
+	var subject *exchangeSubject
+	switch {
+	case strings.HasPrefix(token, sessionTokenScheme):
+		cred, err := s.sessions.GetByTokenHash(ctx, auth.HashToken(token))
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to look up session", err)
+		}
+		if cred.RevokedAt != nil || cred.UserDisabled || !cred.ExpiresAt.After(now) {
+			return nil, nil
+		}
+
+		subject = &exchangeSubject{
+			UserID:    cred.UserID,
+			SessionID: cred.ID,
+			ExpiresAt: cred.ExpiresAt,
+		}
+		if cred.OrganizationID != nil {
+			subject.OrganizationID = *cred.OrganizationID
+		}
+		if cred.ActorUserID != nil {
+			subject.Act = &models.Actor{Subject: strconv.Itoa(*cred.ActorUserID)}
+			subject.Impersonated = true
+		}
+
+	case strings.HasPrefix(token, exchangedTokenScheme):
+		cred, err := s.oauth.GetAccessTokenByHash(ctx, auth.HashToken(token))
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to look up token", err)
+		}
+		if cred.Revoked || !cred.ExpiresAt.After(now) {
+			return nil, nil
+		}
+
+		subject = &exchangeSubject{
+			UserID:         cred.UserID,
+			SessionID:      cred.SessionID,
+			OrganizationID: cred.OrganizationID,
+			Scopes:         cred.Scopes,
+			Act:            cred.Act,
+			ExpiresAt:      cred.ExpiresAt,
+		}
+
+	default:
+		return nil, nil
+	}
+
+	if subject.OrganizationID != 0 && subject.OrganizationID != orgID {
+		return nil, nil
+	}
+	member, err := s.orgs.IsMember(ctx, orgID, subject.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to check membership", err)
+	}
+	if !member {
+		return nil, nil
+	}
+	return subject, nil
+}
+
+// grantedScopes returns the requested scopes, or when none are requested
+// every scope available, after checking each is allowed for the client
+// and held by the subject. Impersonation sessions never pass on what they
+// are denied themselves.
+func (s *oauthService) grantedScopes(ctx context.Context, client *models.OAuthClientCredential, subject *exchangeSubject, requested []string) ([]string, error) {
+	if len(requested) > maxTokenExchangeScopes {
+		return nil, oauthError(OAuthInvalidScope, fmt.Sprintf("at most %d scopes may be requested", maxTokenExchangeScopes))
+	}
+
+	held := subject.Scopes
+	if held == nil {
+		permissions, err := s.roles.GetPermissionsForUser(ctx, client.OrganizationID, subject.UserID)
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to load permissions", err)
+		}
+		held = permissions
+	}
+
+	available := make([]string, 0, len(client.AllowedScopes))
+	for _, scope := range client.AllowedScopes {
+		if !slices.Contains(held, scope) {
+			continue
+		}
+		if subject.Impersonated && impersonationDeniedPermissions[auth.Permission(scope)] {
+			continue
+		}
+		available = append(available, scope)
+	}
+
+	if len(requested) == 0 {
+		if len(available) == 0 {
+			return nil, oauthError(OAuthInvalidScope, "no allowed scope is held by the subject")
+		}
+		return available, nil
+	}
+
+	granted := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		if !slices.Contains(available, scope) {
+			return nil, oauthError(OAuthInvalidScope, fmt.Sprintf("scope %q is not allowed", scope))
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted, nil
+}
+
+// oauthError returns a bad request carrying an OAuth error code as its reason
+func oauthError(code, description string) error {
+	return apperrors.NewBadRequestError(description, nil).WithReason(code)
+}
+
+// oauthClientError is the single error reported for any client
+// authentication failure
+func oauthClientError() error {
+	return apperrors.NewUnauthorizedError("client authentication failed").WithReason(OAuthInvalidClient)
+}
+
+// normalizeList trims entries and drops blanks and duplicates
+func normalizeList(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" && !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// Ensure oauthService implements OAuthService interface
+var _ OAuthService = (*oauthService)(nil)
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	"identity-service/database"
+	apperrors "identity-service/errors"
+	"identity-service/mail"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/validation"
+)
+
+// invitationTokenBytes is the entropy of invitation tokens
+const invitationTokenBytes = 32
+
+// organizationService implements the OrganizationService interface
+type organizationService struct {
+	orgs        repository.OrganizationRepository
+	invitations repository.InvitationRepository
+	roles       repository.RoleRepository
+	mailer      mail.Sender
+	validator   *validation.Validator
+	audit       AuditService
+	config      *config.OrganizationConfig
+}
+
+// NewOrganizationService creates a new OrganizationService instance
+func NewOrganizationService(
+	orgs repository.OrganizationRepository,
+	invitations repository.InvitationRepository,
+	roles repository.RoleRepository,
+	mailer mail.Sender,
+	validator *validation.Validator,
+	audit AuditService,
+	cfg *config.OrganizationConfig,
+) OrganizationService {
+	return &organizationService{
+		orgs:        orgs,
+		invitations: invitations,
+		roles:       roles,
+		mailer:      mailer,
+		validator:   validator,
+		audit:       audit,
+		config:      cfg,
+	}
+}
+
+func (s *organizationService) CreateOrganization(ctx context.Context, ownerID int, name, slug string) (*models.Organization, error) {
+	if err := s.validator.ValidateCreateOrganizationRequest(name, slug); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	exists, err := s.orgs.SlugExists(ctx, slug)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to check slug existence", err)
+	}
+	if exists {
+		return nil, apperrors.NewConflictError("organization with this slug already exists", nil)
+	}
+
+	// The creator administers the new organization
+	org, err := s.orgs.Create(ctx, name, slug, ownerID, auth.RoleAdmin)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create organization", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditOrganizationCreated,
+		OrganizationID: &org.ID,
+		TargetType:     "organization",
+		TargetID:       strconv.Itoa(org.ID),
+		Details:        map[string]interface{}{"slug": org.Slug},
+	})
+
+	return org, nil
+}
+
+func (s *organizationService) GetOrganizationsForUser(ctx context.Context, userID int) ([]models.Organization, error) {
+	orgs, err := s.orgs.GetForUser(ctx, userID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve organizations", err)
+	}
+	return orgs, nil
+}
+
+func (s *organizationService) IsMember(ctx context.Context, orgID, userID int) (bool, error) {
+	member, err := s.orgs.IsMember(ctx, orgID, userID)
+	if err != nil {
+		return false, apperrors.NewInternalServerError("failed to check membership", err)
+	}
+	return member, nil
+}
+
+func (s *organizationService) InviteMember(ctx context.Context, orgID, invitedBy int, email, role string) (*models.Invitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if err := s.validator.ValidateEmail(email); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	exists, err := s.roles.RoleExists(ctx, role)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to check role existence", err)
+	}
+	if !exists {
+		return nil, apperrors.NewNotFoundError("role not found")
+	}
+
+	now := time.Now().UTC()
+	pending, err := s.invitations.PendingExists(ctx, orgID, email, now)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to check pending invitations", err)
+	}
+	if pending {
+		return nil, apperrors.NewConflictError("an invitation is already pending for this email", nil)
+	}
+
+	org, err := s.orgs.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve organization", err)
+	}
+
+	token, err := auth.GenerateToken(invitationTokenBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create invitation", err)
+	}
+
+	inv, err := s.invitations.Create(ctx, &models.Invitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      &invitedBy,
+		ExpiresAt:      now.Add(s.config.InvitationTTL),
+		CreatedAt:      now,
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create invitation", err)
+	}
+
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You have been invited to join %s", org.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s.\n\nAccept or decline the invitation here:\n%s?token=%s\n\nThis invitation expires on %s.",
+			org.Name, s.config.InvitationURL, url.QueryEscape(token), inv.ExpiresAt.Format(time.RFC1123),
+		),
+	}); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to send invitation email", err)
+	}
+
+	s.recordInvitation(ctx, models.AuditInvitationCreated, inv)
+	return inv, nil
+}
+
+func (s *organizationService) GetInvitations(ctx context.Context, orgID int) ([]models.Invitation, error) {
+	invitations, err := s.invitations.GetForOrganization(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve invitations", err)
+	}
+	return invitations, nil
+}
+
+func (s *organizationService) RevokeInvitation(ctx context.Context, orgID, invitationID int) error {
+	revoked, err := s.invitations.UpdateStatus(ctx, orgID, invitationID, models.InvitationRevoked, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke invitation", err)
+	}
+	if !revoked {
+		return apperrors.NewNotFoundError("pending invitation not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditInvitationRevoked,
+		OrganizationID: &orgID,
+		TargetType:     "invitation",
+		TargetID:       strconv.Itoa(invitationID),
+	})
+	return nil
+}
+
+func (s *organizationService) AcceptInvitation(ctx context.Context, principal *auth.Principal, token string) (*models.Invitation, error) {
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(inv.Email, principal.Email) {
+		return nil, apperrors.NewForbiddenError("invitation was issued to a different email address")
+	}
+
+	// The token proves access to exactly one tenant
+	ctx = database.WithTenant(ctx, inv.OrganizationID)
+
+	now := time.Now().UTC()
+	if err := s.invitations.Accept(ctx, inv.ID, principal.UserID, now); err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.NewNotFoundError("invitation not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to accept invitation", err)
+	}
+
+	inv.Status = models.InvitationAccepted
+	inv.RespondedAt = &now
+	s.recordInvitation(ctx, models.AuditInvitationAccepted, inv)
+	return inv, nil
+}
+
+func (s *organizationService) DeclineInvitation(ctx context.Context, token string) error {
+	inv, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	ctx = database.WithTenant(ctx, inv.OrganizationID)
+	declined, err := s.invitations.UpdateStatus(ctx, inv.OrganizationID, inv.ID, models.InvitationDeclined, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to decline invitation", err)
+	}
+	if !declined {
+		return apperrors.NewNotFoundError("invitation not found")
+	}
+
+	s.recordInvitation(ctx, models.AuditInvitationDeclined, inv)
+	return nil
+}
+
+func (s *organizationService) recordInvitation(ctx context.Context, action string, inv *models.Invitation) {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         action,
+		OrganizationID: &inv.OrganizationID,
+		TargetType:     "invitation",
+		TargetID:       strconv.Itoa(inv.ID),
+		Details:        map[string]interface{}{"email": inv.Email, "role": inv.Role},
+	})
+}
+
+// pendingInvitation resolves a token to an invitation that can still be answered
+func (s *organizationService) pendingInvitation(ctx context.Context, token string) (*models.Invitation, error) {
+	if token == "" {
+		return nil, apperrors.NewBadRequestError("token is required", nil)
+	}
+
+	inv, err := s.invitations.GetByTokenHash(ctx, auth.HashToken(token))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("invitation not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve invitation", err)
+	}
+
+	if inv.Status != models.InvitationPending {
+		return nil, apperrors.NewConflictError("invitation is no longer pending", nil)
+	}
+
+	now := time.Now().UTC()
+	if !inv.ExpiresAt.After(now) {
+		ctx = database.WithTenant(ctx, inv.OrganizationID)
+		if _, err := s.invitations.UpdateStatus(ctx, inv.OrganizationID, inv.ID, models.InvitationExpired, now); err != nil {
+			return nil, apperrors.NewInternalServerError("failed to expire invitation", err)
+		}
+		return nil, apperrors.NewBadRequestError("invitation has expired", nil)
+	}
+
+	return inv, nil
+}
+
+func (s *organizationService) GetPasswordPolicy(ctx context.Context, orgID int) (*models.PasswordPolicy, error) {
+	policy, err := s.orgs.GetPasswordPolicy(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve password policy", err)
+	}
+	return policy, nil
+}
+
+// UpdatePasswordPolicy replaces the organization's password policy
+// overrides. Nil fields restore the service defaults.
+func (s *organizationService) UpdatePasswordPolicy(ctx context.Context, orgID int, policy models.PasswordPolicy) (*models.PasswordPolicy, error) {
+	if policy.HistoryCount != nil && (*policy.HistoryCount < 0 || *policy.HistoryCount > MaxPasswordHistory) {
+		return nil, apperrors.NewBadRequestError(fmt.Sprintf("history_count must be between 0 and %d", MaxPasswordHistory), nil)
+	}
+	if policy.MaxAgeDays != nil && *policy.MaxAgeDays < 0 {
+		return nil, apperrors.NewBadRequestError("max_age_days must not be negative", nil)
+	}
+
+	if err := s.orgs.SetPasswordPolicy(ctx, orgID, policy); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to update password policy", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditPasswordPolicySet,
+		OrganizationID: &orgID,
+		TargetType:     "organization",
+		TargetID:       strconv.Itoa(orgID),
+		Details:        map[string]interface{}{"history_count": policy.HistoryCount, "max_age_days": policy.MaxAgeDays},
+	})
+
+	return &policy, nil
+}
+
+// Ensure organizationService implements OrganizationService interface
+var _ OrganizationService = (*organizationService)(nil)
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	"identity-service/mail"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/sms"
+)
+
+// otpTokenBytes is the entropy of the token a client holds for a challenge
+const otpTokenBytes = 32
+
+// otpService implements the OTPService interface. A code is stored only
+// as a hash together with the challenge token, so a leaked table does not
+// allow codes to be guessed offline.
+type otpService struct {
+	repo   repository.MFARepository
+	creds  repository.CredentialRepository
+	mailer mail.Sender
+	sms    sms.Sender
+	audit  AuditService
+	config *config.OTPConfig
+	log    *slog.Logger
+}
+
+// NewOTPService creates a new OTPService instance
+func NewOTPService(
+	repo repository.MFARepository,
+	creds repository.CredentialRepository,
+	mailer mail.Sender,
+	smsSender sms.Sender,
+	audit AuditService,
+	cfg *config.OTPConfig,
+	log *slog.Logger,
+) OTPService {
+	return &otpService{
+		repo:   repo,
+		creds:  creds,
+		mailer: mailer,
+		sms:    smsSender,
+		audit:  audit,
+		config: cfg,
+		log:    log,
+	}
+}
+
+// Begin starts the challenge described by its UserID, Purpose and
+// optional FirstFactor, FactorID and PendingPasswordHash, replacing any
+// earlier one for the same purpose, and returns it with the token the
+// client must present
+func (s *otpService) Begin(ctx context.Context, challenge *models.OTPChallenge) (*models.OTPChallenge, string, error) {
+	token, err := auth.GenerateToken(otpTokenBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+
+	pending := *challenge
+	pending.ExpiresAt = time.Now().UTC().Add(s.config.ChallengeTTL)
+	challenge, err = s.Adopt(ctx, &pending, &models.OTPResponse{OTPToken: token, ExpiresAt: pending.ExpiresAt})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return challenge, token, nil
+}
+
+// Adopt starts a challenge like Begin, but under the token and expiry of a
+// response from Decoy, so a request can be answered before it is known
+// whether there is an account to challenge
+func (s *otpService) Adopt(ctx context.Context, challenge *models.OTPChallenge, response *models.OTPResponse) (*models.OTPChallenge, error) {
+	pending := *challenge
+	pending.ExpiresAt = response.ExpiresAt
+	pending.CreatedAt = time.Now().UTC()
+	created, err := s.repo.CreateChallenge(ctx, &pending, auth.HashToken(response.OTPToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	return created, nil
+}
+
+// Decoy returns a response shaped like that of a real challenge, for
+// requests that must not reveal whether an account exists
+func (s *otpService) Decoy() (*models.OTPResponse, error) {
+	token, err := auth.GenerateToken(otpTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+	return &models.OTPResponse{OTPToken: token, ExpiresAt: time.Now().UTC().Add(s.config.ChallengeTTL)}, nil
+}
+
+// Send replaces the challenge's code and delivers it to the factor, or to
+// the account's address when factor is nil. It reports false without
+// sending while the resend cooldown lasts or once the send limit is hit.
+func (s *otpService) Send(ctx context.Context, challenge *models.OTPChallenge, token string, factor *models.MFAFactor) (bool, error) {
+	channel := models.FactorEmail
+	var factorID *int
+	if factor != nil {
+		channel = factor.Type
+		factorID = &factor.ID
+	}
+
+	code, err := generateCode(s.config.Length)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	stored, err := s.repo.SetCode(ctx, challenge.ID, factorID, channel, hashCode(token, code),
+		now, now.Add(-s.config.ResendCooldown), s.config.MaxSends)
+	if err != nil {
+		return false, fmt.Errorf("failed to store code: %w", err)
+	}
+	if !stored {
+		return false, nil
+	}
+
+	if channel == models.FactorSMS {
+		err = s.sms.Send(ctx, sms.Message{
+			To:   factor.Destination,
+			Body: fmt.Sprintf("%s is your verification code. It expires in %s.", code, time.Until(challenge.ExpiresAt).Round(time.Minute)),
+		})
+	} else {
+		var cred *models.PasswordCredential
+		if cred, err = s.creds.GetByUserID(ctx, challenge.UserID); err != nil {
+			return false, fmt.Errorf("failed to retrieve credentials: %w", err)
+		}
+		err = s.mailer.Send(ctx, mail.Message{
+			To:      cred.Email,
+			Subject: "Your verification code",
+			Body: fmt.Sprintf(
+				"Your verification code is %s\n\nIt expires on %s. If you did not ask for it, ignore this email and consider changing your password.",
+				code, challenge.ExpiresAt.Format(time.RFC1123),
+			),
+		})
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to deliver code: %w", err)
+	}
+
+	details := map[string]interface{}{"purpose": challenge.Purpose, "channel": channel}
+	if factorID != nil {
+		details["factor_id"] = *factorID
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditOTPSent,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(challenge.UserID),
+		Details:    details,
+	})
+	return true, nil
+}
+
+// Verify checks a code against the challenge and consumes the challenge
+// when it matches. A challenge is also dropped once its attempts run out,
+// so further guesses need a new one.
+func (s *otpService) Verify(ctx context.Context, challenge *models.OTPChallenge, token, code string) (bool, error) {
+	if challenge.CodeHash == "" || !challenge.ExpiresAt.After(time.Now().UTC()) {
+		return false, nil
+	}
+
+	allowed, err := s.repo.RecordAttempt(ctx, challenge.ID, s.config.MaxAttempts)
+	if err != nil {
+		return false, fmt.Errorf("failed to record attempt: %w", err)
+	}
+	if !allowed {
+		if _, err := s.repo.DeleteChallenge(ctx, challenge.ID); err != nil {
+			return false, fmt.Errorf("failed to delete challenge: %w", err)
+		}
+		return false, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashCode(token, code)), []byte(challenge.CodeHash)) != 1 {
+		return false, nil
+	}
+
+	consumed, err := s.repo.DeleteChallenge(ctx, challenge.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume challenge: %w", err)
+	}
+	return consumed, nil
+}
+
+// generateCode returns a uniformly random numeric code of the given length
+func generateCode(length int) (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+// hashCode binds a code to the challenge token it was issued for
+func hashCode(token, code string) string {
+	return auth.HashToken(token + ":" + code)
+}
+
+// Ensure otpService implements OTPService interface
+var _ OTPService = (*otpService)(nil)
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/requestinfo"
+	"identity-service/validation"
+)
+
+// MaxPasswordHistory is the most previous passwords any policy may forbid
+// reusing. This many hashes are kept so a policy can be tightened later.
+const MaxPasswordHistory = 24
+
+// passwordPolicy is the effective history and expiry policy for one user
+type passwordPolicy struct {
+	historyCount int
+	maxAgeDays   int
+}
+
+// passwordService implements the PasswordService interface
+type passwordService struct {
+	creds     repository.CredentialRepository
+	orgs      repository.OrganizationRepository
+	sessions  repository.SessionRepository
+	hasher    *auth.PasswordHasher
+	lockout   LockoutService
+	validator *validation.Validator
+	audit     AuditService
+	config    *config.PasswordConfig
+}
+
+// NewPasswordService creates a new PasswordService instance
+func NewPasswordService(
+	creds repository.CredentialRepository,
+	orgs repository.OrganizationRepository,
+	sessions repository.SessionRepository,
+	hasher *auth.PasswordHasher,
+	lockout LockoutService,
+	validator *validation.Validator,
+	audit AuditService,
+	cfg *config.PasswordConfig,
+) PasswordService {
+	return &passwordService{
+		creds:     creds,
+		orgs:      orgs,
+		sessions:  sessions,
+		hasher:    hasher,
+		lockout:   lockout,
+		validator: validator,
+		audit:     audit,
+		config:    cfg,
+	}
+}
+
+// SetPassword replaces a user's password after checking it against the
+// password policy and the user's recent passwords. Every other session of
+// the user is signed out; the caller's own session is kept.
+func (s *passwordService) SetPassword(ctx context.Context, userID int, password string) error {
+	hash, err := s.HashPasswordChange(ctx, userID, password)
+	if err != nil {
+		return err
+	}
+
+	return s.storeHash(ctx, userID, hash, map[string]interface{}{})
+}
+
+// HashPasswordChange checks a user's new password against the password
+// policy and their recent passwords and returns its hash, for a change
+// that ApplyPasswordChange makes later
+func (s *passwordService) HashPasswordChange(ctx context.Context, userID int, password string) (string, error) {
+	cred, err := s.creds.GetByUserID(ctx, userID)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return "", apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return "", apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+
+	// Policy violations are returned as-is so each one reaches the caller
+	if err := s.validator.ValidatePassword(password, cred.Name, cred.Email); err != nil {
+		var violations validation.ValidationErrors
+		if stderrors.As(err, &violations) {
+			return "", violations
+		}
+		return "", apperrors.NewInternalServerError("failed to check password", err)
+	}
+
+	policy, err := s.policyFor(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if policy.historyCount > 0 {
+		history, err := s.creds.GetHistory(ctx, userID, policy.historyCount)
+		if err != nil {
+			return "", apperrors.NewInternalServerError("failed to retrieve password history", err)
+		}
+		for _, previous := range history {
+			reused, err := s.hasher.Verify(password, previous)
+			if err != nil && !stderrors.Is(err, auth.ErrUnsupportedHash) {
+				return "", apperrors.NewInternalServerError("failed to check password history", err)
+			}
+			if reused {
+				return "", validation.ValidationErrors{{
+					Field:   "password",
+					Message: fmt.Sprintf("must not match any of your last %d passwords", policy.historyCount),
+				}}
+			}
+		}
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return "", apperrors.NewInternalServerError("failed to hash password", err)
+	}
+	return hash, nil
+}
+
+// ApplyPasswordChange stores a hash returned by HashPasswordChange the way
+// SetPassword does
+func (s *passwordService) ApplyPasswordChange(ctx context.Context, userID int, hash string) error {
+	return s.storeHash(ctx, userID, hash, map[string]interface{}{})
+}
+
+// HashNewPassword checks the first password of a user who is yet to be
+// created against the password policy and returns its hash
+func (s *passwordService) HashNewPassword(password, name, email string) (string, error) {
+	if err := s.validator.ValidatePassword(password, name, email); err != nil {
+		var violations validation.ValidationErrors
+		if stderrors.As(err, &violations) {
+			return "", violations
+		}
+		return "", apperrors.NewInternalServerError("failed to check password", err)
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return "", apperrors.NewInternalServerError("failed to hash password", err)
+	}
+	return hash, nil
+}
+
+// ImportPasswordHash sets a user's password from a hash produced by another
+// user store. Any supported format is accepted; it is replaced with the
+// current algorithm the next time the user signs in.
+func (s *passwordService) ImportPasswordHash(ctx context.Context, userID int, hash string) error {
+	if err := auth.ValidateHash(hash); err != nil {
+		return validation.ValidationErrors{{
+			Field:   "password_hash",
+			Message: "must be an argon2id, bcrypt, pbkdf2, scrypt or salted SHA hash",
+		}}
+	}
+
+	return s.storeHash(ctx, userID, hash, map[string]interface{}{
+		"imported":  true,
+		"algorithm": auth.HashAlgorithm(hash),
+	})
+}
+
+// storeHash saves a new password hash, signs out every other session of
+// the user and audits the change with the given details
+func (s *passwordService) storeHash(ctx context.Context, userID int, hash string, details map[string]interface{}) error {
+	now := time.Now().UTC()
+	err := s.creds.SetPassword(ctx, userID, hash, now, MaxPasswordHistory)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to set password", err)
+	}
+
+	var keep int64
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.UserID == userID {
+		keep = principal.SessionID
+	}
+	revoked, err := s.sessions.RevokeAllForUser(ctx, userID, keep, now)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to sign out other sessions", err)
+	}
+	details["sessions_revoked"] = revoked
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditPasswordChanged,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Details:    details,
+	})
+	return nil
+}
+
+// Reverify checks the password of a signed-in user, counting
+// failures towards lockout as sign-in does. purpose says what the
+// password was asked for in the audit log.
+func (s *passwordService) Reverify(ctx context.Context, cred *models.PasswordCredential, password, purpose string) error {
+	ip := requestinfo.From(ctx).IP
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		if stderrors.Is(err, auth.ErrTooManyAttempts) {
+			return apperrors.NewTooManyRequestsError("too many failed attempts")
+		}
+		return apperrors.NewInternalServerError("failed to check lockout", err)
+	}
+	if cred.LockedUntil != nil && cred.LockedUntil.After(time.Now().UTC()) {
+		return apperrors.NewTooManyRequestsError("too many failed attempts")
+	}
+
+	ok, err := s.hasher.Verify(password, cred.PasswordHash)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to verify password", err)
+	}
+	if !ok {
+		s.lockout.RecordFailure(ctx, cred.UserID, ip)
+		s.audit.Record(ctx, models.AuditEvent{
+			Action:     models.AuditAuthFailed,
+			Outcome:    models.AuditFailure,
+			TargetType: "user",
+			TargetID:   strconv.Itoa(cred.UserID),
+			Details:    map[string]interface{}{"method": "password", "reason": "mismatch", "purpose": purpose},
+		})
+		return apperrors.NewForbiddenError("password is incorrect")
+	}
+
+	s.lockout.RecordSuccess(ctx, cred.UserID)
+	return nil
+}
+
+// PasswordExpired reports whether the credential is older than the
+// maximum age that applies to its owner
+func (s *passwordService) PasswordExpired(ctx context.Context, cred *models.PasswordCredential) (bool, error) {
+	policy, err := s.policyFor(ctx, cred.UserID)
+	if err != nil {
+		return false, err
+	}
+	if policy.maxAgeDays <= 0 || cred.PasswordChangedAt == nil {
+		return false, nil
+	}
+
+	expiresAt := cred.PasswordChangedAt.AddDate(0, 0, policy.maxAgeDays)
+	return !time.Now().UTC().Before(expiresAt), nil
+}
+
+// policyFor combines the service defaults with the overrides of every
+// organization the user belongs to, keeping the strictest of each
+func (s *passwordService) policyFor(ctx context.Context, userID int) (passwordPolicy, error) {
+	policy := passwordPolicy{
+		historyCount: s.config.HistoryCount,
+		maxAgeDays:   s.config.MaxAgeDays,
+	}
+
+	overrides, err := s.orgs.GetPasswordPoliciesForUser(ctx, userID)
+	if err != nil {
+		return policy, apperrors.NewInternalServerError("failed to retrieve password policy", err)
+	}
+
+	for _, override := range overrides {
+		if override.HistoryCount != nil && *override.HistoryCount > policy.historyCount {
+			policy.historyCount = *override.HistoryCount
+		}
+		if override.MaxAgeDays != nil && *override.MaxAgeDays > 0 &&
+			(policy.maxAgeDays <= 0 || *override.MaxAgeDays < policy.maxAgeDays) {
+			policy.maxAgeDays = *override.MaxAgeDays
+		}
+	}
+
+	policy.historyCount = min(policy.historyCount, MaxPasswordHistory)
+	return policy, nil
+}
+
+// Ensure passwordService implements PasswordService interface
+var _ PasswordService = (*passwordService)(nil)
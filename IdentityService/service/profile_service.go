@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/mail"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/validation"
+)
+
+// emailChangeTokenBytes is the entropy of email change and verification tokens
+const emailChangeTokenBytes = 32
+
+// profileService implements the ProfileService interface
+type profileService struct {
+	repo      repository.ProfileRepository
+	users     repository.UserRepository
+	creds     repository.CredentialRepository
+	passwords PasswordService
+	mailer    mail.Sender
+	validator *validation.Validator
+	audit     AuditService
+	config    *config.ProfileConfig
+	log       *slog.Logger
+}
+
+// NewProfileService creates a new ProfileService instance
+func NewProfileService(
+	repo repository.ProfileRepository,
+	users repository.UserRepository,
+	creds repository.CredentialRepository,
+	passwords PasswordService,
+	mailer mail.Sender,
+	validator *validation.Validator,
+	audit AuditService,
+	cfg *config.ProfileConfig,
+	log *slog.Logger,
+) ProfileService {
+	return &profileService{
+		repo:      repo,
+		users:     users,
+		creds:     creds,
+		passwords: passwords,
+		mailer:    mailer,
+		validator: validator,
+		audit:     audit,
+		config:    cfg,
+		log:       log,
+	}
+}
+
+func (s *profileService) GetProfile(ctx context.Context, userID int) (*models.Profile, error) {
+	profile, err := s.repo.GetProfile(ctx, userID, time.Now().UTC())
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve profile", err)
+	}
+	return profile, nil
+}
+
+// UpdateProfile applies the fields present in the request. The email
+// address is changed through RequestEmailChange instead.
+func (s *profileService) UpdateProfile(ctx context.Context, userID int, req models.UpdateProfileRequest) (*models.Profile, error) {
+	current, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	var changed []string
+	apply := func(field string, value *string, target *string) {
+		if value == nil {
+			return
+		}
+		if v := strings.TrimSpace(*value); v != *target {
+			*target = v
+			changed = append(changed, field)
+		}
+	}
+	apply("name", req.Name, &updated.Name)
+	apply("display_name", req.DisplayName, &updated.DisplayName)
+	apply("avatar_url", req.AvatarURL, &updated.AvatarURL)
+	apply("locale", req.Locale, &updated.Locale)
+	apply("time_zone", req.TimeZone, &updated.TimeZone)
+	if len(changed) == 0 {
+		return current, nil
+	}
+
+	var errors validation.ValidationErrors
+	if err := s.validator.ValidateName(updated.Name); err != nil {
+		if validationErr, ok := err.(validation.ValidationError); ok {
+			errors = append(errors, validationErr)
+		}
+	}
+	if err := s.validator.ValidateProfile(updated.DisplayName, updated.AvatarURL, updated.Locale, updated.TimeZone); err != nil {
+		if validationErrs, ok := err.(validation.ValidationErrors); ok {
+			errors = append(errors, validationErrs...)
+		}
+	}
+	if len(errors) > 0 {
+		return nil, apperrors.NewBadRequestError("validation failed", errors)
+	}
+
+	now := time.Now().UTC()
+	ok, err := s.repo.UpdateProfile(ctx, &updated, now)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to update profile", err)
+	}
+	if !ok {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditProfileUpdated,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Details:    map[string]interface{}{"fields": changed},
+	})
+
+	updated.UpdatedAt = &now
+	return &updated, nil
+}
+
+// RequestEmailChange sends a verification link to the new address. The
+// address only changes once the link is followed, see ConfirmEmailChange.
+func (s *profileService) RequestEmailChange(ctx context.Context, principal *auth.Principal, newEmail, currentPassword string) (*models.EmailChange, error) {
+	if err := forbidImpersonation(principal); err != nil {
+		return nil, err
+	}
+
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+	if err := s.validator.ValidateEmail(newEmail); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	cred, err := s.creds.GetByUserID(ctx, principal.UserID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve credentials", err)
+	}
+	if strings.EqualFold(cred.Email, newEmail) {
+		return nil, apperrors.NewBadRequestError("new email is the same as the current one", nil)
+	}
+
+	// A stolen session alone must not be enough to take over the account,
+	// so wrong guesses count towards lockout
+	if cred.PasswordHash != "" {
+		if err := s.passwords.Reverify(ctx, cred, currentPassword, "email_change"); err != nil {
+			return nil, err
+		}
+	}
+
+	exists, err := s.users.EmailExists(ctx, newEmail)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to check email existence", err)
+	}
+	if exists {
+		return nil, apperrors.NewConflictError("user with this email already exists", nil)
+	}
+
+	token, err := auth.GenerateToken(emailChangeTokenBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create email change", err)
+	}
+
+	now := time.Now().UTC()
+	change, err := s.repo.CreateEmailChange(ctx, &models.EmailChange{
+		UserID:    principal.UserID,
+		NewEmail:  newEmail,
+		ExpiresAt: now.Add(s.config.EmailChangeTTL),
+		CreatedAt: now,
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create email change", err)
+	}
+
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Confirm that you want to use this address for your account:\n%s?token=%s\n\nThis link expires on %s. If you did not ask for this change, ignore this email.",
+			s.config.EmailChangeURL, url.QueryEscape(token), change.ExpiresAt.Format(time.RFC1123),
+		),
+	}); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to send verification email", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditEmailChangeStarted,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(principal.UserID),
+		Details:    map[string]interface{}{"new_email": newEmail},
+	})
+
+	return change, nil
+}
+
+// ConfirmEmailChange switches the account to the verified address and
+// tells the previous address about it. The token alone proves control of
+// the new mailbox, so no session is required.
+func (s *profileService) ConfirmEmailChange(ctx context.Context, token string) error {
+	if token == "" {
+		return apperrors.NewBadRequestError("token is required", nil)
+	}
+
+	change, previous, err := s.repo.CompleteEmailChange(ctx, auth.HashToken(token), time.Now().UTC())
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return apperrors.NewNotFoundError("email change not found or expired")
+	}
+	if stderrors.Is(err, repository.ErrConflict) {
+		return apperrors.NewConflictError("user with this email already exists", err)
+	}
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to change email", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:      models.AuditEmailChanged,
+		ActorUserID: &change.UserID,
+		TargetType:  "user",
+		TargetID:    strconv.Itoa(change.UserID),
+		Details:     map[string]interface{}{"email": change.NewEmail, "previous_email": previous},
+	})
+
+	// The change has happened; a failed notice must not report otherwise
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      previous,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"The email address of your account was changed to %s.\n\nIf you did not make this change, contact support immediately.",
+			change.NewEmail,
+		),
+	}); err != nil {
+		s.log.ErrorContext(ctx, "failed to notify previous email address",
+			slog.Int("user_id", change.UserID),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return nil
+}
+
+func (s *profileService) CancelEmailChange(ctx context.Context, userID int) error {
+	deleted, err := s.repo.DeleteEmailChange(ctx, userID)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to cancel email change", err)
+	}
+	if !deleted {
+		return apperrors.NewNotFoundError("no email change is pending")
+	}
+	return nil
+}
+
+// DeleteAccount deletes the caller's account everywhere. The route demands
+// a fresh step-up, and neither API keys nor impersonation sessions may
+// use it.
+func (s *profileService) DeleteAccount(ctx context.Context, principal *auth.Principal) error {
+	if err := forbidImpersonation(principal); err != nil {
+		return err
+	}
+	if principal.UserID == 0 || principal.APIKeyID != 0 {
+		return apperrors.NewForbiddenError("account deletion requires a signed-in user")
+	}
+
+	deleted, err := s.repo.DeleteAccount(ctx, principal.UserID)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to delete account", err)
+	}
+	if !deleted {
+		return apperrors.NewNotFoundError("user not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditAccountDeleted,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(principal.UserID),
+	})
+	return nil
+}
+
+// SendVerification mails a link to the user's current address which
+// marks it verified when followed, replacing any earlier link
+func (s *profileService) SendVerification(ctx context.Context, userID int) (*models.EmailVerification, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.EmailVerified {
+		return nil, apperrors.NewConflictError("email address is already verified", nil)
+	}
+
+	token, err := auth.GenerateToken(emailChangeTokenBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create email verification", err)
+	}
+
+	now := time.Now().UTC()
+	verification, err := s.repo.CreateEmailVerification(ctx, &models.EmailVerification{
+		UserID:    userID,
+		Email:     profile.Email,
+		ExpiresAt: now.Add(s.config.VerificationTTL),
+		CreatedAt: now,
+	}, auth.HashToken(token))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create email verification", err)
+	}
+
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      verification.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Confirm that this address belongs to your account:\n%s?token=%s\n\nThis link expires on %s.",
+			s.config.VerificationURL, url.QueryEscape(token), verification.ExpiresAt.Format(time.RFC1123),
+		),
+	}); err != nil {
+		return nil, apperrors.NewInternalServerError("failed to send verification email", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditVerificationSent,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Details:    map[string]interface{}{"email": verification.Email},
+	})
+
+	return verification, nil
+}
+
+// VerifyEmail marks the address a verification link was sent to as
+// verified. Like ConfirmEmailChange it needs no session.
+func (s *profileService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return apperrors.NewBadRequestError("token is required", nil)
+	}
+
+	verification, err := s.repo.CompleteEmailVerification(ctx, auth.HashToken(token), time.Now().UTC())
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return apperrors.NewNotFoundError("email verification not found or expired")
+	}
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to verify email", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:      models.AuditEmailVerified,
+		ActorUserID: &verification.UserID,
+		TargetType:  "user",
+		TargetID:    strconv.Itoa(verification.UserID),
+		Details:     map[string]interface{}{"email": verification.Email},
+	})
+	return nil
+}
+
+// Ensure profileService implements ProfileService interface
+var _ ProfileService = (*profileService)(nil)
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"identity-service/config"
+	"identity-service/geoip"
+	"identity-service/mail"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/requestinfo"
+)
+
+// How much each signal adds to a sign-in's risk score. Repeated failures
+// add riskPerFailure each, up to riskMaxFailures.
+const (
+	riskNewUserAgent     = 20
+	riskNewIP            = 10
+	riskNewASN           = 25
+	riskImpossibleTravel = 60
+	riskPerFailure       = 5
+	riskMaxFailures      = 30
+)
+
+// minTravelDistance is how far apart, in kilometres, two sign-ins must be
+// before their speed is judged, since GeoIP locations are approximate
+const minTravelDistance = 500
+
+// earthRadius is the mean radius of the Earth in kilometres
+const earthRadius = 6371.0
+
+// riskService implements the RiskService interface
+type riskService struct {
+	repo   repository.RiskRepository
+	geoip  *geoip.Database
+	mailer mail.Sender
+	audit  AuditService
+	config *config.RiskConfig
+	log    *slog.Logger
+}
+
+// NewRiskService creates a new RiskService instance. geo may be nil, in
+// which case the network and travel signals are never raised.
+func NewRiskService(repo repository.RiskRepository, geo *geoip.Database, mailer mail.Sender, audit AuditService, cfg *config.RiskConfig, log *slog.Logger) RiskService {
+	return &riskService{
+		repo:   repo,
+		geoip:  geo,
+		mailer: mailer,
+		audit:  audit,
+		config: cfg,
+		log:    log,
+	}
+}
+
+// Assess scores a sign-in whose first factor has just been proven against
+// the user's earlier sign-ins. A user's first sign-in has nothing to be
+// new compared with, so it only scores failed attempts.
+func (s *riskService) Assess(ctx context.Context, cred *models.PasswordCredential) (*models.RiskAssessment, error) {
+	info := requestinfo.From(ctx)
+	now := time.Now().UTC()
+	location, located := s.geoip.Lookup(info.IP)
+
+	history, err := s.repo.GetHistory(ctx, cred.UserID, info.IP, truncate(info.UserAgent, maxUserAgentLength), location.ASN,
+		now.Add(-s.config.HistoryRetention), now.Add(-s.config.VelocityWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sign-in history: %w", err)
+	}
+
+	assessment := &models.RiskAssessment{Signals: []string{}}
+	raise := func(signal string, score int) {
+		assessment.Signals = append(assessment.Signals, signal)
+		assessment.Score += score
+	}
+
+	if history.SignIns > 0 {
+		if !history.KnownUserAgent {
+			raise(models.RiskSignalNewUserAgent, riskNewUserAgent)
+		}
+		if !history.KnownIP {
+			raise(models.RiskSignalNewIP, riskNewIP)
+		}
+		if location.ASN != 0 && !history.KnownASN {
+			raise(models.RiskSignalNewASN, riskNewASN)
+		}
+	}
+	if located && s.impossibleTravel(history.Last, location, now) {
+		raise(models.RiskSignalImpossibleTravel, riskImpossibleTravel)
+	}
+
+	failures := history.IPFailures
+	if cred.LastFailedLoginAt != nil && cred.LastFailedLoginAt.After(now.Add(-s.config.VelocityWindow)) {
+		failures += cred.FailedLoginCount
+	}
+	if failures > 0 {
+		raise(models.RiskSignalFailedAttempts, min(failures*riskPerFailure, riskMaxFailures))
+	}
+
+	switch {
+	case s.config.BlockThreshold > 0 && assessment.Score >= s.config.BlockThreshold:
+		assessment.Decision = models.RiskBlock
+	case assessment.Score >= s.config.MFAThreshold:
+		assessment.Decision = models.RiskMFA
+	default:
+		assessment.Decision = models.RiskAllow
+	}
+	return assessment, nil
+}
+
+// RecordSignIn adds a new session's sign-in to the user's history, pruning
+// entries past the retention period, and tells the user when it came from
+// a device they have not used before. Errors are logged so that
+// bookkeeping never fails a sign-in.
+func (s *riskService) RecordSignIn(ctx context.Context, cred *models.PasswordCredential, sessionID int64) {
+	info := requestinfo.From(ctx)
+	now := time.Now().UTC()
+	location, located := s.geoip.Lookup(info.IP)
+
+	event := &models.SignInEvent{
+		UserID:    cred.UserID,
+		SessionID: &sessionID,
+		IP:        info.IP,
+		UserAgent: truncate(info.UserAgent, maxUserAgentLength),
+		CreatedAt: now,
+	}
+	if located {
+		event.Country = location.Country
+		if location.ASN != 0 {
+			event.ASN = &location.ASN
+		}
+		if location.HasCoordinates {
+			event.Latitude, event.Longitude = &location.Latitude, &location.Longitude
+		}
+	}
+
+	history, err := s.repo.GetHistory(ctx, cred.UserID, event.IP, event.UserAgent, location.ASN,
+		now.Add(-s.config.HistoryRetention), now)
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to load sign-in history",
+			slog.Int("user_id", cred.UserID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	if _, err := s.repo.RecordSignIn(ctx, event, now.Add(-s.config.HistoryRetention)); err != nil {
+		s.log.ErrorContext(ctx, "failed to record sign-in",
+			slog.Int("user_id", cred.UserID),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	if history.SignIns == 0 || history.KnownUserAgent {
+		return
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:      models.AuditNewDeviceSignIn,
+		ActorUserID: &cred.UserID,
+		TargetType:  "session",
+		TargetID:    strconv.FormatInt(sessionID, 10),
+		Details:     map[string]interface{}{"user_agent": event.UserAgent, "country": event.Country},
+	})
+	if s.config.NotifyNewDevice {
+		go s.notifyNewDevice(context.WithoutCancel(ctx), cred, event)
+	}
+}
+
+// impossibleTravel reports whether getting from the last located sign-in
+// to location by now would take travel faster than MaxTravelSpeed
+func (s *riskService) impossibleTravel(last *models.SignInEvent, location geoip.Location, now time.Time) bool {
+	if s.config.MaxTravelSpeed <= 0 || last == nil || last.Latitude == nil || last.Longitude == nil || !location.HasCoordinates {
+		return false
+	}
+
+	distance := haversine(*last.Latitude, *last.Longitude, location.Latitude, location.Longitude)
+	if distance < minTravelDistance {
+		return false
+	}
+	hours := now.Sub(last.CreatedAt).Hours()
+	return hours <= 0 || distance/hours > float64(s.config.MaxTravelSpeed)
+}
+
+// notifyNewDevice emails the user about a sign-in from an unfamiliar device
+func (s *riskService) notifyNewDevice(ctx context.Context, cred *models.PasswordCredential, event *models.SignInEvent) {
+	device := event.UserAgent
+	if device == "" {
+		device = "unknown"
+	}
+	where := event.IP
+	if event.Country != "" {
+		where += " (" + event.Country + ")"
+	}
+
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      cred.Email,
+		Subject: "New sign-in to your account",
+		Body: fmt.Sprintf(
+			"Your account was signed in to from a device it has not been used on before.\n\nWhen: %s\nDevice: %s\nIP address: %s\n\nIf this was you, there is nothing to do. If not, change your password and review your sign-in methods right away.",
+			event.CreatedAt.Format(time.RFC1123), device, where,
+		),
+	}); err != nil {
+		s.log.ErrorContext(ctx, "failed to send new device notification",
+			slog.Int("user_id", cred.UserID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// haversine returns the great-circle distance between two points in kilometres
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := toRad(lat2-lat1), toRad(lon2-lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// Ensure riskService implements RiskService interface
+var _ RiskService = (*riskService)(nil)
@@ -0,0 +1,661 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/scim"
+	"identity-service/validation"
+)
+
+// SCIM tokens look like scim_<secret>. Only a hash of the whole token is
+// kept; the scheme lets the authenticator skip tokens of other kinds.
+const (
+	scimTokenScheme      = "scim_"
+	scimTokenSecretBytes = 32
+)
+
+// scimService implements the SCIMService interface. Users are provisioned
+// through UserService so SCIM changes follow the same rules and audit
+// trail as changes made in the API.
+type scimService struct {
+	users     UserService
+	passwords PasswordService
+	scim      repository.SCIMRepository
+	groups    repository.GroupRepository
+	lockout   LockoutService
+	validator *validation.Validator
+	audit     AuditService
+}
+
+// NewSCIMService creates a new SCIMService instance
+func NewSCIMService(
+	users UserService,
+	passwords PasswordService,
+	scimRepo repository.SCIMRepository,
+	groups repository.GroupRepository,
+	lockout LockoutService,
+	validator *validation.Validator,
+	audit AuditService,
+) SCIMService {
+	return &scimService{
+		users:     users,
+		passwords: passwords,
+		scim:      scimRepo,
+		groups:    groups,
+		lockout:   lockout,
+		validator: validator,
+		audit:     audit,
+	}
+}
+
+func (s *scimService) CreateToken(ctx context.Context, orgID, createdBy int, name string) (*models.CreatedSCIMToken, error) {
+	if err := s.validator.ValidateName(name); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	secret, err := auth.GenerateToken(scimTokenSecretBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate scim token", err)
+	}
+	rawToken := scimTokenScheme + secret
+
+	token := &models.SCIMToken{
+		OrganizationID: orgID,
+		Name:           name,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if createdBy != 0 {
+		token.CreatedBy = &createdBy
+	}
+
+	created, err := s.scim.CreateToken(ctx, token, auth.HashToken(rawToken))
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create scim token", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditSCIMTokenCreated,
+		OrganizationID: &orgID,
+		TargetType:     "scim_token",
+		TargetID:       strconv.Itoa(created.ID),
+		Details:        map[string]interface{}{"name": created.Name},
+	})
+
+	return &models.CreatedSCIMToken{SCIMToken: *created, Token: rawToken}, nil
+}
+
+func (s *scimService) GetTokens(ctx context.Context, orgID int) ([]models.SCIMToken, error) {
+	tokens, err := s.scim.GetTokens(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve scim tokens", err)
+	}
+	return tokens, nil
+}
+
+func (s *scimService) RevokeToken(ctx context.Context, orgID, id int) error {
+	revoked, err := s.scim.RevokeToken(ctx, orgID, id, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke scim token", err)
+	}
+	if !revoked {
+		return apperrors.NewNotFoundError("scim token not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditSCIMTokenRevoked,
+		OrganizationID: &orgID,
+		TargetType:     "scim_token",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+// Authenticate resolves a SCIM token to a principal acting for its
+// organization. The principal has no user, roles or scopes; SCIM routes
+// accept nothing else and other routes accept nothing from it.
+func (s *scimService) Authenticate(ctx context.Context, rawToken, ip string) (*auth.Principal, error) {
+	if !strings.HasPrefix(rawToken, scimTokenScheme) {
+		return nil, auth.ErrNoCredentials
+	}
+	if err := s.lockout.CheckIP(ctx, ip); err != nil {
+		return nil, err
+	}
+
+	cred, err := s.scim.GetTokenByHash(ctx, auth.HashToken(rawToken))
+	if stderrors.Is(err, repository.ErrNotFound) {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, "", "unknown")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up scim token: %w", err)
+	}
+
+	if cred.RevokedAt != nil {
+		s.lockout.RecordFailure(ctx, 0, ip)
+		return nil, s.authFailed(ctx, strconv.Itoa(cred.ID), "revoked")
+	}
+
+	if err := s.scim.RecordTokenUsage(ctx, cred.ID, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to record scim token usage: %w", err)
+	}
+
+	return &auth.Principal{
+		OrganizationID: cred.OrganizationID,
+		SCIMTokenID:    cred.ID,
+		Scopes:         []string{},
+	}, nil
+}
+
+// authFailed audits a rejected token and returns the error to report
+func (s *scimService) authFailed(ctx context.Context, tokenID, reason string) error {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditAuthFailed,
+		Outcome:    models.AuditFailure,
+		TargetType: "scim_token",
+		TargetID:   tokenID,
+		Details:    map[string]interface{}{"method": "scim_token", "reason": reason},
+	})
+	return auth.ErrInvalidCredentials
+}
+
+// ListUsers returns a page of the members matching the query and the
+// number of matches. Single userName or externalId lookups, the common
+// case for identity providers, are narrowed in the database.
+func (s *scimService) ListUsers(ctx context.Context, orgID int, query scim.Query) ([]*scim.User, int, error) {
+	var match models.SCIMMemberMatch
+	if query.Filter != nil {
+		match.Email, _ = scim.EqualValue(query.Filter, "userName")
+		match.ExternalID, _ = scim.EqualValue(query.Filter, "externalId")
+	}
+
+	members, err := s.scim.GetMembers(ctx, orgID, match)
+	if err != nil {
+		return nil, 0, apperrors.NewInternalServerError("failed to retrieve users", err)
+	}
+
+	users := make([]*scim.User, 0, len(members))
+	for i := range members {
+		user := toSCIMUser(&members[i])
+		if matched, err := matchesFilter(query.Filter, user); err != nil || !matched {
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		users = append(users, user)
+	}
+
+	return page(users, query), len(users), nil
+}
+
+func (s *scimService) GetUser(ctx context.Context, orgID, id int) (*scim.User, error) {
+	member, err := s.getMember(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toSCIMUser(member), nil
+}
+
+// CreateUser provisions a user. userName is the user's email address.
+func (s *scimService) CreateUser(ctx context.Context, orgID int, user *scim.User) (*scim.User, error) {
+	email, err := scimUserName(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked up front so a taken externalId does not leave a user behind
+	if user.ExternalID != "" {
+		existing, err := s.scim.GetMembers(ctx, orgID, models.SCIMMemberMatch{ExternalID: user.ExternalID})
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to check external id", err)
+		}
+		if len(existing) > 0 {
+			return nil, apperrors.NewConflictError("a user with this externalId already exists", nil).WithReason(scim.ErrUniqueness)
+		}
+	}
+
+	created, err := s.users.CreateUser(ctx, orgID, user.FullName(), email, user.Password, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ExternalID != "" {
+		if err := s.setExternalID(ctx, orgID, created.ID, user.ExternalID); err != nil {
+			return nil, err
+		}
+	}
+	if user.Active != nil && !bool(*user.Active) {
+		if err := s.users.SetUserActive(ctx, orgID, created.ID, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUser(ctx, orgID, created.ID)
+}
+
+// ReplaceUser replaces a user's attributes. An omitted active attribute
+// leaves the user's status unchanged.
+func (s *scimService) ReplaceUser(ctx context.Context, orgID, id int, user *scim.User) (*scim.User, error) {
+	member, err := s.getMember(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateUser(ctx, orgID, member, user, user.FullName())
+}
+
+// PatchUser applies PATCH operations to a user
+func (s *scimService) PatchUser(ctx context.Context, orgID, id int, ops []scim.PatchOperation) (*scim.User, error) {
+	member, err := s.getMember(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := toSCIMUser(member)
+
+	resource, err := scim.ToMap(before)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to encode user", err)
+	}
+	if err := scim.Apply(resource, ops); err != nil {
+		return nil, err
+	}
+	var after scim.User
+	if err := scim.FromMap(resource, &after); err != nil {
+		return nil, err
+	}
+	if after.ID != before.ID {
+		return nil, apperrors.NewBadRequestError("id cannot be changed", nil).WithReason(scim.ErrMutability)
+	}
+
+	return s.updateUser(ctx, orgID, member, &after, patchedName(before, &after))
+}
+
+func (s *scimService) DeleteUser(ctx context.Context, orgID, id int) error {
+	return s.users.RemoveUser(ctx, orgID, id)
+}
+
+// updateUser writes the differences between a member and its new SCIM
+// representation. Only one name is stored, so the caller picks it.
+// emails are derived from userName and changes to them are ignored. The
+// name, email and password of a user who also belongs to another
+// organization are not the provisioning organization's to change: a new
+// name is ignored and the others are refused.
+func (s *scimService) updateUser(ctx context.Context, orgID int, member *models.SCIMMember, user *scim.User, name string) (*scim.User, error) {
+	email, err := scimUserName(user)
+	if err != nil {
+		return nil, err
+	}
+
+	emailChanged := !strings.EqualFold(email, member.Email)
+	if emailChanged || name != member.Name || user.Password != "" {
+		elsewhere, err := s.users.BelongsElsewhere(ctx, orgID, member.ID)
+		if err != nil {
+			return nil, err
+		}
+		if elsewhere && emailChanged {
+			return nil, apperrors.NewConflictError("userName of a user who belongs to other organizations cannot be changed", nil).WithReason(scim.ErrMutability)
+		}
+		if elsewhere && user.Password != "" {
+			return nil, apperrors.NewForbiddenError("password of a user who belongs to other organizations cannot be set")
+		}
+		if elsewhere {
+			name = member.Name
+		}
+	}
+
+	if _, err := s.users.UpdateUser(ctx, orgID, member.ID, name, email); err != nil {
+		return nil, err
+	}
+	if user.ExternalID != member.ExternalID {
+		if err := s.setExternalID(ctx, orgID, member.ID, user.ExternalID); err != nil {
+			return nil, err
+		}
+	}
+	if user.Active != nil && bool(*user.Active) != member.Active {
+		if err := s.users.SetUserActive(ctx, orgID, member.ID, bool(*user.Active)); err != nil {
+			return nil, err
+		}
+	}
+	if user.Password != "" {
+		if err := s.passwords.SetPassword(ctx, member.ID, user.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUser(ctx, orgID, member.ID)
+}
+
+func (s *scimService) getMember(ctx context.Context, orgID, id int) (*models.SCIMMember, error) {
+	member, err := s.scim.GetMember(ctx, orgID, id)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
+	return member, nil
+}
+
+func (s *scimService) setExternalID(ctx context.Context, orgID, id int, externalID string) error {
+	_, err := s.scim.SetExternalID(ctx, orgID, id, externalID, time.Now().UTC())
+	if stderrors.Is(err, repository.ErrConflict) {
+		return apperrors.NewConflictError("a user with this externalId already exists", err).WithReason(scim.ErrUniqueness)
+	}
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to update user", err)
+	}
+	return nil
+}
+
+func (s *scimService) ListGroups(ctx context.Context, orgID int, query scim.Query) ([]*scim.Group, int, error) {
+	var match models.GroupMatch
+	if query.Filter != nil {
+		match.DisplayName, _ = scim.EqualValue(query.Filter, "displayName")
+		match.ExternalID, _ = scim.EqualValue(query.Filter, "externalId")
+	}
+
+	groups, err := s.groups.GetAll(ctx, orgID, match)
+	if err != nil {
+		return nil, 0, apperrors.NewInternalServerError("failed to retrieve groups", err)
+	}
+
+	result := make([]*scim.Group, 0, len(groups))
+	for i := range groups {
+		group := toSCIMGroup(&groups[i])
+		if matched, err := matchesFilter(query.Filter, group); err != nil || !matched {
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		result = append(result, group)
+	}
+
+	return page(result, query), len(result), nil
+}
+
+func (s *scimService) GetGroup(ctx context.Context, orgID, id int) (*scim.Group, error) {
+	group, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toSCIMGroup(group), nil
+}
+
+func (s *scimService) CreateGroup(ctx context.Context, orgID int, group *scim.Group) (*scim.Group, error) {
+	model, err := fromSCIMGroup(orgID, group)
+	if err != nil {
+		return nil, err
+	}
+	model.CreatedAt = time.Now().UTC()
+	model.UpdatedAt = model.CreatedAt
+
+	created, err := s.groups.Create(ctx, model)
+	if err != nil {
+		return nil, groupWriteError(err, "failed to create group")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditGroupCreated,
+		OrganizationID: &orgID,
+		TargetType:     "group",
+		TargetID:       strconv.Itoa(created.ID),
+		Details: map[string]interface{}{
+			"display_name": created.DisplayName,
+			"members":      len(created.Members),
+		},
+	})
+
+	return toSCIMGroup(created), nil
+}
+
+func (s *scimService) ReplaceGroup(ctx context.Context, orgID, id int, group *scim.Group) (*scim.Group, error) {
+	current, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateGroup(ctx, orgID, current, group)
+}
+
+// PatchGroup applies PATCH operations to a group, typically adding or
+// removing members
+func (s *scimService) PatchGroup(ctx context.Context, orgID, id int, ops []scim.PatchOperation) (*scim.Group, error) {
+	current, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	before := toSCIMGroup(current)
+
+	resource, err := scim.ToMap(before)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to encode group", err)
+	}
+	if err := scim.Apply(resource, ops); err != nil {
+		return nil, err
+	}
+	var after scim.Group
+	if err := scim.FromMap(resource, &after); err != nil {
+		return nil, err
+	}
+	if after.ID != before.ID {
+		return nil, apperrors.NewBadRequestError("id cannot be changed", nil).WithReason(scim.ErrMutability)
+	}
+
+	return s.updateGroup(ctx, orgID, current, &after)
+}
+
+func (s *scimService) DeleteGroup(ctx context.Context, orgID, id int) error {
+	deleted, err := s.groups.Delete(ctx, orgID, id)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to delete group", err)
+	}
+	if !deleted {
+		return apperrors.NewNotFoundError("group not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditGroupDeleted,
+		OrganizationID: &orgID,
+		TargetType:     "group",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+func (s *scimService) updateGroup(ctx context.Context, orgID int, current *models.Group, group *scim.Group) (*scim.Group, error) {
+	model, err := fromSCIMGroup(orgID, group)
+	if err != nil {
+		return nil, err
+	}
+	model.ID = current.ID
+	model.UpdatedAt = time.Now().UTC()
+
+	updated, err := s.groups.Update(ctx, model)
+	if err != nil {
+		return nil, groupWriteError(err, "failed to update group")
+	}
+	if !updated {
+		return nil, apperrors.NewNotFoundError("group not found")
+	}
+
+	added, removed := diffMembers(current.Members, model.Members)
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditGroupUpdated,
+		OrganizationID: &orgID,
+		TargetType:     "group",
+		TargetID:       strconv.Itoa(current.ID),
+		Details: map[string]interface{}{
+			"display_name":    model.DisplayName,
+			"members_added":   added,
+			"members_removed": removed,
+		},
+	})
+
+	return s.GetGroup(ctx, orgID, current.ID)
+}
+
+func (s *scimService) getGroup(ctx context.Context, orgID, id int) (*models.Group, error) {
+	group, err := s.groups.GetByID(ctx, orgID, id)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("group not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve group", err)
+	}
+	return group, nil
+}
+
+// groupWriteError maps repository errors from writing a group
+func groupWriteError(err error, message string) error {
+	switch {
+	case stderrors.Is(err, repository.ErrConflict):
+		return apperrors.NewConflictError("a group with this externalId already exists", err).WithReason(scim.ErrUniqueness)
+	case stderrors.Is(err, repository.ErrInvalidReference):
+		return apperrors.NewBadRequestError("members must be users of the organization", err).WithReason(scim.ErrInvalidValue)
+	}
+	return apperrors.NewInternalServerError(message, err)
+}
+
+// scimUserName returns the email address a SCIM userName stands for
+func scimUserName(user *scim.User) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(user.UserName))
+	if email == "" {
+		return "", apperrors.NewBadRequestError("userName is required", nil).WithReason(scim.ErrInvalidValue)
+	}
+	return email, nil
+}
+
+// patchedName picks the name a PATCH meant to set: a changed displayName,
+// else a changed name, else the current one
+func patchedName(before, after *scim.User) string {
+	if after.DisplayName != before.DisplayName && after.DisplayName != "" {
+		return after.DisplayName
+	}
+	if after.Name != nil && !reflect.DeepEqual(after.Name, before.Name) {
+		name := *after.Name
+		// A formatted name left over from before is stale once its
+		// parts change
+		if before.Name != nil && name.Formatted == before.Name.Formatted {
+			name.Formatted = ""
+		}
+		if full := (&scim.User{Name: &name}).FullName(); full != "" {
+			return full
+		}
+	}
+	return before.DisplayName
+}
+
+func toSCIMUser(member *models.SCIMMember) *scim.User {
+	created := member.CreatedAt
+	modified := created
+	if member.UpdatedAt != nil {
+		modified = *member.UpdatedAt
+	}
+
+	return &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          strconv.Itoa(member.ID),
+		ExternalID:  member.ExternalID,
+		UserName:    member.Email,
+		Name:        &scim.Name{Formatted: member.Name},
+		DisplayName: member.Name,
+		Emails:      []scim.MultiValue{{Value: member.Email, Type: "work", Primary: true}},
+		Active:      scim.BoolPtr(member.Active),
+		Meta:        &scim.Meta{ResourceType: "User", Created: &created, LastModified: &modified},
+	}
+}
+
+func toSCIMGroup(group *models.Group) *scim.Group {
+	members := make([]scim.MultiValue, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = scim.MultiValue{Value: strconv.Itoa(member.UserID), Display: member.Name, Type: "User"}
+	}
+
+	created, modified := group.CreatedAt, group.UpdatedAt
+	return &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          strconv.Itoa(group.ID),
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     members,
+		Meta:        &scim.Meta{ResourceType: "Group", Created: &created, LastModified: &modified},
+	}
+}
+
+func fromSCIMGroup(orgID int, group *scim.Group) (*models.Group, error) {
+	name := strings.TrimSpace(group.DisplayName)
+	if name == "" {
+		return nil, apperrors.NewBadRequestError("displayName is required", nil).WithReason(scim.ErrInvalidValue)
+	}
+
+	model := &models.Group{
+		OrganizationID: orgID,
+		DisplayName:    name,
+		ExternalID:     group.ExternalID,
+		Members:        make([]models.GroupMember, 0, len(group.Members)),
+	}
+	for _, member := range group.Members {
+		userID, err := strconv.Atoi(member.Value)
+		if err != nil || userID <= 0 {
+			return nil, apperrors.NewBadRequestError(fmt.Sprintf("unknown member %q", member.Value), nil).WithReason(scim.ErrInvalidValue)
+		}
+		model.Members = append(model.Members, models.GroupMember{UserID: userID})
+	}
+	return model, nil
+}
+
+// diffMembers returns the user ids added to and removed from a group
+func diffMembers(before, after []models.GroupMember) (added, removed []int) {
+	had := make(map[int]bool, len(before))
+	for _, member := range before {
+		had[member.UserID] = true
+	}
+	has := make(map[int]bool, len(after))
+	for _, member := range after {
+		if !has[member.UserID] && !had[member.UserID] {
+			added = append(added, member.UserID)
+		}
+		has[member.UserID] = true
+	}
+	for _, member := range before {
+		if !has[member.UserID] {
+			removed = append(removed, member.UserID)
+		}
+	}
+	return added, removed
+}
+
+// matchesFilter evaluates a filter against a resource; a nil filter
+// matches everything
+func matchesFilter(filter scim.Filter, resource interface{}) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+	m, err := scim.ToMap(resource)
+	if err != nil {
+		return false, apperrors.NewInternalServerError("failed to encode resource", err)
+	}
+	return filter.Match(m), nil
+}
+
+// page returns the slice of results the query's startIndex and count select
+func page[T any](results []T, query scim.Query) []T {
+	start := query.StartIndex - 1
+	if start >= len(results) {
+		return results[:0]
+	}
+	end := min(start+query.Count, len(results))
+	return results[start:end]
+}
+
+// Ensure scimService implements SCIMService interface
+var _ SCIMService = (*scimService)(nil)
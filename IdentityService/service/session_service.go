@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"identity-service/auth"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+)
+
+// sessionService implements the SessionService interface
+type sessionService struct {
+	sessions repository.SessionRepository
+	devices  repository.TrustedDeviceRepository
+	audit    AuditService
+}
+
+// NewSessionService creates a new SessionService instance
+func NewSessionService(sessions repository.SessionRepository, devices repository.TrustedDeviceRepository, audit AuditService) SessionService {
+	return &sessionService{
+		sessions: sessions,
+		devices:  devices,
+		audit:    audit,
+	}
+}
+
+// GetSessions lists the caller's active sessions, including impersonation
+// sessions acting as them, and their trusted devices
+func (s *sessionService) GetSessions(ctx context.Context, principal *auth.Principal) (*models.SessionList, error) {
+	now := time.Now().UTC()
+	sessions, err := s.sessions.GetForUser(ctx, principal.UserID, now)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve sessions", err)
+	}
+	devices, err := s.devices.GetForUser(ctx, principal.UserID, now)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve trusted devices", err)
+	}
+
+	for i := range sessions {
+		sessions[i].Current = principal.SessionID != 0 && sessions[i].ID == principal.SessionID
+	}
+	return &models.SessionList{Sessions: sessions, TrustedDevices: devices}, nil
+}
+
+// RevokeSession signs out one of the caller's sessions, which may be the
+// current one
+func (s *sessionService) RevokeSession(ctx context.Context, principal *auth.Principal, id int64) error {
+	if err := forbidImpersonation(principal); err != nil {
+		return err
+	}
+
+	revoked, err := s.sessions.Revoke(ctx, principal.UserID, id, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke session", err)
+	}
+	if !revoked {
+		return apperrors.NewNotFoundError("session not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditSessionRevoked,
+		TargetType: "session",
+		TargetID:   strconv.FormatInt(id, 10),
+	})
+	return nil
+}
+
+// RevokeTrustedDevice makes one of the caller's trusted devices ask for the
+// second factor again. Sessions it already started are left alone.
+func (s *sessionService) RevokeTrustedDevice(ctx context.Context, principal *auth.Principal, id int64) error {
+	if err := forbidImpersonation(principal); err != nil {
+		return err
+	}
+
+	revoked, err := s.devices.Revoke(ctx, principal.UserID, id, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke trusted device", err)
+	}
+	if !revoked {
+		return apperrors.NewNotFoundError("trusted device not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditTrustedDeviceRevoked,
+		TargetType: "trusted_device",
+		TargetID:   strconv.FormatInt(id, 10),
+	})
+	return nil
+}
+
+// Ensure sessionService implements SessionService interface
+var _ SessionService = (*sessionService)(nil)
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "identity-service/errors"
+	"identity-service/models"
+)
+
+// maxJSONLineBytes bounds a single JSON Lines record
+const maxJSONLineBytes = 1 << 20
+
+// userImportRowError is a problem confined to one row of an import file.
+// The row is skipped and reading continues.
+type userImportRowError struct {
+	line    int
+	message string
+}
+
+func (e *userImportRowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.message)
+}
+
+// userImportReader reads rows from a CSV or JSON Lines import file
+type userImportReader struct {
+	csv     *csv.Reader
+	columns []string
+	lines   *bufio.Scanner
+	line    int
+}
+
+// newUserImportReader prepares to read src. A CSV file must start with a
+// header naming its columns: name and email, optionally password or
+// password_hash.
+func newUserImportReader(src io.Reader, format string) (*userImportReader, error) {
+	switch format {
+	case models.UserFileCSV:
+		r := csv.NewReader(src)
+		header, err := r.Read()
+		if err == io.EOF {
+			return nil, apperrors.NewBadRequestError("import file is empty", nil)
+		}
+		if err != nil {
+			return nil, apperrors.NewBadRequestError("invalid CSV header", err)
+		}
+
+		columns := make([]string, len(header))
+		found := make(map[string]bool, len(header))
+		for i, column := range header {
+			column = strings.ToLower(strings.TrimSpace(column))
+			if i == 0 {
+				column = strings.TrimPrefix(column, "\ufeff") // written by spreadsheet exports
+			}
+			switch column {
+			case "name", "email", "password", "password_hash":
+			default:
+				return nil, apperrors.NewBadRequestError(fmt.Sprintf("unknown CSV column %q", column), nil)
+			}
+			if found[column] {
+				return nil, apperrors.NewBadRequestError(fmt.Sprintf("CSV column %q appears twice", column), nil)
+			}
+			found[column] = true
+			columns[i] = column
+		}
+		if !found["name"] || !found["email"] {
+			return nil, apperrors.NewBadRequestError("CSV header must include name and email", nil)
+		}
+		return &userImportReader{csv: r, columns: columns}, nil
+
+	case models.UserFileJSONL:
+		lines := bufio.NewScanner(src)
+		lines.Buffer(make([]byte, 0, 64*1024), maxJSONLineBytes)
+		return &userImportReader{lines: lines}, nil
+	}
+
+	return nil, apperrors.NewBadRequestError(fmt.Sprintf("unsupported format %q; use csv or jsonl", format), nil)
+}
+
+// Next returns the next row, a *userImportRowError for a malformed row,
+// or io.EOF at the end of the file
+func (r *userImportReader) Next() (*models.UserImportRow, error) {
+	if r.csv != nil {
+		return r.nextCSV()
+	}
+	return r.nextJSON()
+}
+
+func (r *userImportReader) nextCSV() (*models.UserImportRow, error) {
+	record, err := r.csv.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if stderrors.As(err, &parseErr) && stderrors.Is(parseErr.Err, csv.ErrFieldCount) {
+		return nil, &userImportRowError{line: parseErr.StartLine, message: fmt.Sprintf("expected %d fields, found %d", len(r.columns), len(record))}
+	}
+	if err != nil {
+		return nil, apperrors.NewBadRequestError("invalid CSV", err)
+	}
+
+	line, _ := r.csv.FieldPos(0)
+	row := &models.UserImportRow{Line: line}
+	for i, value := range record {
+		switch r.columns[i] {
+		case "name":
+			row.Name = value
+		case "email":
+			row.Email = value
+		case "password":
+			row.Password = value
+		case "password_hash":
+			row.PasswordHash = value
+		}
+	}
+	return row, nil
+}
+
+func (r *userImportReader) nextJSON() (*models.UserImportRow, error) {
+	for r.lines.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.lines.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var row models.UserImportRow
+		if err := decoder.Decode(&row); err != nil {
+			return nil, &userImportRowError{line: r.line, message: "invalid JSON: " + err.Error()}
+		}
+		row.Line = r.line
+		return &row, nil
+	}
+
+	if err := r.lines.Err(); err != nil {
+		if stderrors.Is(err, bufio.ErrTooLong) {
+			return nil, apperrors.NewBadRequestError(fmt.Sprintf("line %d is longer than %d bytes", r.line+1, maxJSONLineBytes), err)
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// userExportWriter writes exported users with a fixed set of fields
+type userExportWriter struct {
+	fields []string
+	csv    *csv.Writer
+	json   *bufio.Writer
+}
+
+func newUserExportWriter(dst io.Writer, format string, fields []string) (*userExportWriter, error) {
+	switch format {
+	case models.UserFileCSV:
+		w := &userExportWriter{fields: fields, csv: csv.NewWriter(dst)}
+		return w, w.csv.Write(fields)
+	case models.UserFileJSONL:
+		return &userExportWriter{fields: fields, json: bufio.NewWriter(dst)}, nil
+	}
+
+	return nil, apperrors.NewBadRequestError(fmt.Sprintf("unsupported format %q; use csv or jsonl", format), nil)
+}
+
+func (w *userExportWriter) Write(record *models.UserExportRecord) error {
+	if w.csv != nil {
+		values := make([]string, len(w.fields))
+		for i, field := range w.fields {
+			values[i] = exportText(exportValue(record, field))
+		}
+		return w.csv.Write(values)
+	}
+
+	// Fields are written in the order they were selected
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range w.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		value, err := json.Marshal(exportValue(record, field))
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+	_, err := w.json.Write(buf.Bytes())
+	return err
+}
+
+func (w *userExportWriter) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return w.json.Flush()
+}
+
+func exportValue(record *models.UserExportRecord, field string) interface{} {
+	switch field {
+	case "id":
+		return record.ID
+	case "name":
+		return record.Name
+	case "email":
+		return record.Email
+	case "created_at":
+		return record.CreatedAt
+	case "locked_until":
+		return record.LockedUntil
+	case "password_changed_at":
+		return record.PasswordChangedAt
+	}
+	return nil
+}
+
+// exportText renders a value for CSV; missing times are left empty
+func exportText(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+	"identity-service/validation"
+)
+
+// userImportService implements the UserImportService interface
+type userImportService struct {
+	users     repository.UserRepository
+	hasher    *auth.PasswordHasher
+	validator *validation.Validator
+	audit     AuditService
+	config    *config.UserImportConfig
+}
+
+// NewUserImportService creates a new UserImportService instance
+func NewUserImportService(
+	users repository.UserRepository,
+	hasher *auth.PasswordHasher,
+	validator *validation.Validator,
+	audit AuditService,
+	cfg *config.UserImportConfig,
+) UserImportService {
+	return &userImportService{
+		users:     users,
+		hasher:    hasher,
+		validator: validator,
+		audit:     audit,
+		config:    cfg,
+	}
+}
+
+// Import reads users from a CSV or JSON Lines file into an organization.
+// Rows are matched to existing users by email; invalid rows are reported
+// and skipped without affecting the rest. Valid rows are written in
+// batches, each in its own transaction, and progress is reported after
+// every batch. If writing a batch fails the import stops, and batches
+// already written stay written.
+func (s *userImportService) Import(ctx context.Context, orgID int, src io.Reader, opts models.UserImportOptions, progress func(models.UserImportProgress)) (*models.UserImportResult, error) {
+	reader, err := newUserImportReader(src, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &userImport{
+		service: s,
+		orgID:   orgID,
+		dryRun:  opts.DryRun,
+		result:  &models.UserImportResult{DryRun: opts.DryRun, Errors: []models.UserImportError{}},
+		seen:    make(map[string]int),
+		now:     time.Now().UTC(),
+	}
+
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *userImportRowError
+		if stderrors.As(err, &rowErr) {
+			run.fail(models.UserImportError{Line: rowErr.line, Message: rowErr.message})
+			continue
+		}
+		if err != nil {
+			s.recordImport(ctx, run, opts.Format)
+			return nil, err
+		}
+
+		entry, ok := run.validate(row)
+		if !ok {
+			continue
+		}
+		run.batch = append(run.batch, entry)
+
+		if len(run.batch) >= s.config.BatchSize {
+			if err := run.flush(ctx); err != nil {
+				s.recordImport(ctx, run, opts.Format)
+				return nil, err
+			}
+			if progress != nil {
+				progress(run.result.UserImportProgress)
+			}
+		}
+	}
+
+	if err := run.flush(ctx); err != nil {
+		s.recordImport(ctx, run, opts.Format)
+		return nil, err
+	}
+	s.recordImport(ctx, run, opts.Format)
+
+	// Conflicts are only found when a batch is written, after later rows
+	// may already have failed validation
+	sort.SliceStable(run.result.Errors, func(i, j int) bool {
+		return run.result.Errors[i].Line < run.result.Errors[j].Line
+	})
+	return run.result, nil
+}
+
+// recordImport audits what an import wrote; dry runs write nothing
+func (s *userImportService) recordImport(ctx context.Context, run *userImport, format string) {
+	if run.dryRun || run.result.Processed == 0 {
+		return
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUsersImported,
+		OrganizationID: &run.orgID,
+		TargetType:     "organization",
+		TargetID:       strconv.Itoa(run.orgID),
+		Details: map[string]interface{}{
+			"format":    format,
+			"processed": run.result.Processed,
+			"created":   run.result.Created,
+			"updated":   run.result.Updated,
+			"failed":    run.result.Failed,
+		},
+	})
+}
+
+// userImport holds the state of one running import
+type userImport struct {
+	service *userImportService
+	orgID   int
+	dryRun  bool
+	result  *models.UserImportResult
+	batch   []userImportPending
+	// seen maps each email read so far to its line, to catch duplicates
+	seen map[string]int
+	now  time.Time
+}
+
+// userImportPending is a validated row whose password is not hashed yet
+type userImportPending struct {
+	entry    models.UserImportEntry
+	password string
+}
+
+// validate checks one row, recording why it is rejected if it is
+func (u *userImport) validate(row *models.UserImportRow) (userImportPending, bool) {
+	v := u.service.validator
+	name := strings.TrimSpace(row.Name)
+	email := strings.ToLower(strings.TrimSpace(row.Email))
+
+	var violations validation.ValidationErrors
+	if err := v.ValidateCreateUserRequest(name, email); err != nil {
+		stderrors.As(err, &violations)
+	}
+	if row.Password != "" && row.PasswordHash != "" {
+		violations = append(violations, validation.ValidationError{Field: "password_hash", Message: "must not be combined with password"})
+	}
+	if len(violations) == 0 && row.Password != "" {
+		if err := v.ValidatePassword(row.Password, name, email); err != nil {
+			var passwordViolations validation.ValidationErrors
+			if !stderrors.As(err, &passwordViolations) {
+				passwordViolations = validation.ValidationErrors{{Field: "password", Message: "could not be checked"}}
+			}
+			violations = append(violations, passwordViolations...)
+		}
+	}
+	if row.PasswordHash != "" {
+		if err := auth.ValidateHash(row.PasswordHash); err != nil {
+			violations = append(violations, validation.ValidationError{Field: "password_hash", Message: "is not a supported hash"})
+		}
+	}
+	if first, ok := u.seen[email]; ok && email != "" {
+		violations = append(violations, validation.ValidationError{Field: "email", Message: fmt.Sprintf("duplicates line %d", first)})
+	}
+
+	if len(violations) > 0 {
+		importErrs := make([]models.UserImportError, len(violations))
+		for i, violation := range violations {
+			importErrs[i] = models.UserImportError{Line: row.Line, Email: email, Field: violation.Field, Message: violation.Message}
+		}
+		u.fail(importErrs...)
+		return userImportPending{}, false
+	}
+
+	u.seen[email] = row.Line
+	return userImportPending{
+		entry: models.UserImportEntry{
+			Line:         row.Line,
+			Name:         name,
+			Email:        email,
+			PasswordHash: row.PasswordHash,
+		},
+		password: row.Password,
+	}, true
+}
+
+// fail records a rejected row with each of its problems
+func (u *userImport) fail(importErrs ...models.UserImportError) {
+	result := u.result
+	result.Processed++
+	result.Failed++
+
+	for _, importErr := range importErrs {
+		if len(result.Errors) >= u.service.config.MaxErrors {
+			result.ErrorsTruncated = true
+			return
+		}
+		result.Errors = append(result.Errors, importErr)
+	}
+}
+
+// flush hashes the pending passwords and writes the batch
+func (u *userImport) flush(ctx context.Context) error {
+	if len(u.batch) == 0 {
+		return nil
+	}
+
+	entries := make([]models.UserImportEntry, len(u.batch))
+	for i, pending := range u.batch {
+		entries[i] = pending.entry
+		if pending.password != "" && !u.dryRun {
+			hash, err := u.service.hasher.Hash(pending.password)
+			if err != nil {
+				return apperrors.NewInternalServerError("failed to hash password", err)
+			}
+			entries[i].PasswordHash = hash
+		}
+	}
+	u.batch = u.batch[:0]
+
+	outcomes, err := u.service.users.ImportBatch(ctx, u.orgID, entries, u.now, MaxPasswordHistory, u.dryRun)
+	if err != nil {
+		return apperrors.NewInternalServerError(fmt.Sprintf("failed to import users from line %d", entries[0].Line), err)
+	}
+
+	for _, outcome := range outcomes {
+		switch outcome.Action {
+		case models.UserImportCreated:
+			u.result.Processed++
+			u.result.Created++
+		case models.UserImportUpdated:
+			u.result.Processed++
+			u.result.Updated++
+		case models.UserImportConflict:
+			u.fail(models.UserImportError{
+				Line:    outcome.Line,
+				Email:   outcome.Email,
+				Field:   "email",
+				Message: "belongs to a user managed outside this organization",
+			})
+		}
+	}
+	return nil
+}
+
+// Export writes every member of an organization as CSV or JSON Lines with
+// the selected fields. Options are checked before anything is written.
+func (s *userImportService) Export(ctx context.Context, orgID int, dst io.Writer, opts models.UserExportOptions) (int, error) {
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = models.UserExportFields
+	}
+	if err := validateExportFields(fields); err != nil {
+		return 0, err
+	}
+
+	writer, err := newUserExportWriter(dst, opts.Format, fields)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = s.users.Export(ctx, orgID, func(record *models.UserExportRecord) error {
+		count++
+		return writer.Write(record)
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		return count, apperrors.NewInternalServerError("failed to export users", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUsersExported,
+		OrganizationID: &orgID,
+		TargetType:     "organization",
+		TargetID:       strconv.Itoa(orgID),
+		Details:        map[string]interface{}{"format": opts.Format, "fields": fields, "count": count},
+	})
+	return count, nil
+}
+
+func validateExportFields(fields []string) error {
+	known := make(map[string]bool, len(models.UserExportFields))
+	for _, field := range models.UserExportFields {
+		known[field] = true
+	}
+
+	selected := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if !known[field] {
+			return validation.ValidationErrors{{
+				Field:   "fields",
+				Message: fmt.Sprintf("unknown field %q; choose from %s", field, strings.Join(models.UserExportFields, ", ")),
+			}}
+		}
+		if selected[field] {
+			return validation.ValidationErrors{{Field: "fields", Message: fmt.Sprintf("%q is listed twice", field)}}
+		}
+		selected[field] = true
+	}
+	return nil
+}
+
+// Ensure userImportService implements UserImportService interface
+var _ UserImportService = (*userImportService)(nil)
@@ -2,7 +2,12 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"identity-service/auth"
 	"identity-service/models"
 	"identity-service/repository"
 	apperrors "identity-service/errors"
@@ -12,33 +17,103 @@ import (
 // ValidationErrors is exported for handlers to use
 type ValidationErrors = validation.ValidationErrors
 
+// maxActionReasonLength bounds the reason given for administrative actions
+const maxActionReasonLength = 500
+
 // userService implements the UserService interface
 type userService struct {
-	repo      repository.UserRepository
-	validator *validation.Validator
+	repo       repository.UserRepository
+	roles      repository.RoleRepository
+	attributes repository.AttributeRepository
+	sessions   repository.SessionRepository
+	profiles   ProfileService
+	lockout    LockoutService
+	passwords  PasswordService
+	validator  *validation.Validator
+	audit      AuditService
 }
 
 // NewUserService creates a new UserService instance
-func NewUserService(repo repository.UserRepository, validator *validation.Validator) UserService {
+func NewUserService(
+	repo repository.UserRepository,
+	roles repository.RoleRepository,
+	attributes repository.AttributeRepository,
+	sessions repository.SessionRepository,
+	profiles ProfileService,
+	lockout LockoutService,
+	passwords PasswordService,
+	validator *validation.Validator,
+	audit AuditService,
+) UserService {
 	return &userService{
-		repo:      repo,
-		validator: validator,
+		repo:       repo,
+		roles:      roles,
+		attributes: attributes,
+		sessions:   sessions,
+		profiles:   profiles,
+		lockout:    lockout,
+		passwords:  passwords,
+		validator:  validator,
+		audit:      audit,
 	}
 }
 
-func (s *userService) GetAllUsers(ctx context.Context) ([]models.User, error) {
-	users, err := s.repo.GetAll(ctx)
+// GetAllUsers lists the organization's members. Attribute filters are
+// matched exactly after converting them to the attribute's type.
+func (s *userService) GetAllUsers(ctx context.Context, orgID int, filter models.UserFilter) ([]models.User, error) {
+	var contains map[string]interface{}
+	if len(filter.Attributes) > 0 {
+		defs, err := s.attributes.GetDefinitions(ctx, orgID)
+		if err != nil {
+			return nil, apperrors.NewInternalServerError("failed to retrieve user attributes", err)
+		}
+		byName := make(map[string]*models.AttributeDefinition, len(defs))
+		for i := range defs {
+			byName[defs[i].Name] = &defs[i]
+		}
+
+		contains = make(map[string]interface{}, len(filter.Attributes))
+		for name, raw := range filter.Attributes {
+			def, ok := byName[name]
+			if !ok {
+				return nil, apperrors.NewBadRequestError("unknown attribute "+name, nil)
+			}
+			value, err := s.validator.ParseAttributeValue(def, raw)
+			if err != nil {
+				return nil, apperrors.NewBadRequestError("invalid value for attribute "+name, err)
+			}
+			contains[name] = value
+		}
+	}
+
+	users, err := s.repo.GetAll(ctx, orgID, contains)
 	if err != nil {
 		return nil, apperrors.NewInternalServerError("failed to retrieve users", err)
 	}
+
+	// Expired locks stay in the table until the next successful sign-in
+	now := time.Now().UTC()
+	for i := range users {
+		if users[i].LockedUntil != nil && !users[i].LockedUntil.After(now) {
+			users[i].LockedUntil = nil
+		}
+	}
 	return users, nil
 }
 
-func (s *userService) CreateUser(ctx context.Context, name, email string) (*models.User, error) {
+func (s *userService) CreateUser(ctx context.Context, orgID int, name, email, password, passwordHash string) (*models.User, error) {
 	// Validate input
 	if err := s.validator.ValidateCreateUserRequest(name, email); err != nil {
 		return nil, apperrors.NewBadRequestError("validation failed", err)
 	}
+	if password != "" && passwordHash != "" {
+		return nil, apperrors.NewBadRequestError("password and password_hash are mutually exclusive", nil)
+	}
+	if passwordHash != "" {
+		if err := auth.ValidateHash(passwordHash); err != nil {
+			return nil, apperrors.NewBadRequestError("unsupported password hash", err)
+		}
+	}
 
 	// Check if email already exists (business logic)
 	existingUser, err := s.repo.EmailExists(ctx, email)
@@ -49,14 +124,348 @@ func (s *userService) CreateUser(ctx context.Context, name, email string) (*mode
 		return nil, apperrors.NewConflictError("user with this email already exists", nil)
 	}
 
-	// Create user
-	user, err := s.repo.Create(ctx, name, email)
+	details := map[string]interface{}{"email": email}
+	hash := passwordHash
+	switch {
+	case password != "":
+		if hash, err = s.passwords.HashNewPassword(password, name, email); err != nil {
+			return nil, err
+		}
+		details["password_set"] = true
+	case passwordHash != "":
+		details["password_set"] = true
+		details["password_algorithm"] = auth.HashAlgorithm(passwordHash)
+	}
+
+	// Every new user starts with the platform-wide user role
+	user, err := s.repo.Create(ctx, orgID, name, email, hash, auth.RoleUser, time.Now().UTC())
 	if err != nil {
 		return nil, apperrors.NewInternalServerError("failed to create user", err)
 	}
 
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUserCreated,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(user.ID),
+		Details:        details,
+	})
+
+	return user, nil
+}
+
+// UpdateUser changes a member's name and email
+func (s *userService) UpdateUser(ctx context.Context, orgID, id int, name, email string) (*models.User, error) {
+	if err := s.validator.ValidateCreateUserRequest(name, email); err != nil {
+		return nil, apperrors.NewBadRequestError("validation failed", err)
+	}
+
+	current, err := s.repo.GetByID(ctx, orgID, id)
+	if err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.NewNotFoundError("user not found")
+		}
+		return nil, apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
+	if current.Name == name && strings.EqualFold(current.Email, email) {
+		return current, nil
+	}
+
+	updated, err := s.repo.Update(ctx, orgID, id, name, email, time.Now().UTC())
+	if stderrors.Is(err, repository.ErrConflict) {
+		return nil, apperrors.NewConflictError("user with this email already exists", err)
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to update user", err)
+	}
+	if !updated {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+
+	details := map[string]interface{}{}
+	if current.Name != name {
+		details["name"] = name
+	}
+	if !strings.EqualFold(current.Email, email) {
+		details["email"] = strings.ToLower(email)
+		details["previous_email"] = current.Email
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUserUpdated,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+		Details:        details,
+	})
+
+	current.Name = name
+	current.Email = strings.ToLower(email)
+	return current, nil
+}
+
+// BelongsElsewhere reports whether a member also belongs to another
+// organization. Their email and password are shared with it, so only
+// changes the organization makes to its own membership are allowed.
+func (s *userService) BelongsElsewhere(ctx context.Context, orgID, id int) (bool, error) {
+	elsewhere, err := s.repo.MemberElsewhere(ctx, orgID, id)
+	if err != nil {
+		return false, apperrors.NewInternalServerError("failed to check memberships", err)
+	}
+	return elsewhere, nil
+}
+
+// SetUserActive deactivates or reactivates a member. A deactivated member
+// keeps their roles and groups but cannot act in the organization.
+func (s *userService) SetUserActive(ctx context.Context, orgID, id int, active bool) error {
+	if _, err := s.repo.GetByID(ctx, orgID, id); err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return apperrors.NewNotFoundError("user not found")
+		}
+		return apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
+
+	changed, err := s.repo.SetActive(ctx, orgID, id, active, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to update user", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	action := models.AuditUserDeactivated
+	if active {
+		action = models.AuditUserReactivated
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         action,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+// RemoveUser removes a member from the organization. Users who belong to
+// no other organization are deleted.
+func (s *userService) RemoveUser(ctx context.Context, orgID, id int) error {
+	removed, err := s.repo.RemoveMember(ctx, orgID, id)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to remove user", err)
+	}
+	if !removed {
+		return apperrors.NewNotFoundError("user not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUserRemoved,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+// UnlockUser lifts a sign-in lockout on a member of the organization. The
+// lock covers every organization, so only a user who belongs to no other
+// can be unlocked.
+func (s *userService) UnlockUser(ctx context.Context, orgID, id int) error {
+	if _, err := s.repo.GetByID(ctx, orgID, id); err != nil {
+		if stderrors.Is(err, repository.ErrNotFound) {
+			return apperrors.NewNotFoundError("user not found")
+		}
+		return apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
+	if err := s.requireSoleMembership(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	if _, err := s.lockout.Unlock(ctx, id); err != nil {
+		return apperrors.NewInternalServerError("failed to unlock user", err)
+	}
+	return nil
+}
+
+// DisableUser blocks a member's account from signing in anywhere and ends
+// their sessions. API keys stop working while the account is disabled.
+// That reaches every organization the user belongs to, so it is left to
+// platform administrators; an organization deactivates its own membership
+// with SetUserActive, as SCIM does, instead.
+func (s *userService) DisableUser(ctx context.Context, orgID, id int, reason string) error {
+	ctx, err := withActionReason(ctx, reason)
+	if err != nil {
+		return err
+	}
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && principal.UserID == id {
+		return apperrors.NewBadRequestError("cannot disable your own account", nil)
+	}
+	if _, err := s.getMember(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	changed, err := s.repo.SetDisabled(ctx, orgID, id, true, now)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to disable user", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	revoked, err := s.sessions.RevokeAllForUser(ctx, id, 0, now)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to revoke sessions", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUserDisabled,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+		Details:        map[string]interface{}{"sessions_revoked": revoked},
+	})
+	return nil
+}
+
+// EnableUser lets a disabled account sign in again
+func (s *userService) EnableUser(ctx context.Context, orgID, id int, reason string) error {
+	ctx, err := withActionReason(ctx, reason)
+	if err != nil {
+		return err
+	}
+	if _, err := s.getMember(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	changed, err := s.repo.SetDisabled(ctx, orgID, id, false, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to enable user", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUserEnabled,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+// SignOutUser revokes every session of a member and reports how many were
+// active. API keys are left alone; they are revoked individually. Sessions
+// are not tied to one organization, so only a user who belongs to no
+// other can be signed out.
+func (s *userService) SignOutUser(ctx context.Context, orgID, id int, reason string) (int, error) {
+	ctx, err := withActionReason(ctx, reason)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.getMember(ctx, orgID, id); err != nil {
+		return 0, err
+	}
+	if err := s.requireSoleMembership(ctx, orgID, id); err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.sessions.RevokeAllForUser(ctx, id, 0, time.Now().UTC())
+	if err != nil {
+		return 0, apperrors.NewInternalServerError("failed to revoke sessions", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditUserSignedOut,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+		Details:        map[string]interface{}{"sessions_revoked": revoked},
+	})
+	return revoked, nil
+}
+
+// RequirePasswordChange makes a member choose a new password at their next
+// sign-in. Current sessions are not affected. The password is shared by
+// every organization, so only a user who belongs to no other is affected.
+func (s *userService) RequirePasswordChange(ctx context.Context, orgID, id int, reason string) error {
+	ctx, err := withActionReason(ctx, reason)
+	if err != nil {
+		return err
+	}
+	if _, err := s.getMember(ctx, orgID, id); err != nil {
+		return err
+	}
+	if err := s.requireSoleMembership(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	changed, err := s.repo.RequirePasswordChange(ctx, orgID, id)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to update user", err)
+	}
+	if !changed {
+		return nil
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditPasswordChangeRequired,
+		OrganizationID: &orgID,
+		TargetType:     "user",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+// ResendVerification mails a member a new link to verify their address
+func (s *userService) ResendVerification(ctx context.Context, orgID, id int, reason string) error {
+	ctx, err := withActionReason(ctx, reason)
+	if err != nil {
+		return err
+	}
+	if _, err := s.getMember(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	_, err = s.profiles.SendVerification(ctx, id)
+	return err
+}
+
+func (s *userService) getMember(ctx context.Context, orgID, id int) (*models.User, error) {
+	user, err := s.repo.GetByID(ctx, orgID, id)
+	if stderrors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.NewNotFoundError("user not found")
+	}
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve user", err)
+	}
 	return user, nil
 }
 
+// requireSoleMembership refuses account-wide actions on a member who also
+// belongs to another organization
+func (s *userService) requireSoleMembership(ctx context.Context, orgID, id int) error {
+	elsewhere, err := s.BelongsElsewhere(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+	if elsewhere {
+		return apperrors.NewForbiddenError("user belongs to other organizations")
+	}
+	return nil
+}
+
+// withActionReason requires the reason for an administrative action and
+// attaches it to the audit events recorded with the returned context
+func withActionReason(ctx context.Context, reason string) (context.Context, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return ctx, apperrors.NewBadRequestError("reason is required", nil)
+	}
+	if len(reason) > maxActionReasonLength {
+		return ctx, apperrors.NewBadRequestError("reason is too long", nil)
+	}
+	return WithAuditReason(ctx, reason), nil
+}
+
 // Ensure userService implements UserService interface
 var _ UserService = (*userService)(nil)
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"identity-service/config"
+	"identity-service/models"
+	"identity-service/repository"
+)
+
+// Headers sent with every webhook delivery. Receivers verify the request by
+// computing HMAC-SHA256(secret, "<t>.<body>") and comparing it with v1.
+const (
+	WebhookSignatureHeader = "Webhook-Signature"
+	WebhookIDHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+)
+
+const maxWebhookErrorLength = 500
+
+// errWebhookAddressNotPublic rejects deliveries to internal services
+var errWebhookAddressNotPublic = errors.New("webhook endpoint does not resolve to a public address")
+
+// sharedAddressSpace is carrier-grade NAT space, internal to the provider
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// WebhookDispatcher publishes outbox events to subscribed webhook endpoints
+type WebhookDispatcher struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	config *config.WebhookConfig
+	log    *slog.Logger
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher instance
+func NewWebhookDispatcher(repo repository.WebhookRepository, cfg *config.WebhookConfig, log *slog.Logger) *WebhookDispatcher {
+	// The address is checked after resolution, as it is dialled, so a host
+	// cannot pass a check and then resolve somewhere else. Proxies are not
+	// used because the check would only see the proxy's address.
+	dialer := &net.Dialer{Timeout: cfg.RequestTimeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = dialPublicOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		repo: repo,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.RequestTimeout,
+			// Never follow redirects to hosts the subscriber did not register
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: cfg,
+		log:    log,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *WebhookDispatcher) tick(ctx context.Context) {
+	now := time.Now().UTC()
+
+	if _, err := d.repo.FanOut(ctx, now, d.config.BatchSize); err != nil {
+		d.log.ErrorContext(ctx, "failed to fan out outbox events",
+			slog.String("error", err.Error()),
+		)
+	}
+
+	// Lease long enough to cover the HTTP timeout of every claimed job
+	lease := d.config.RequestTimeout*time.Duration(d.config.BatchSize) + time.Minute
+	jobs, err := d.repo.ClaimDue(ctx, now, lease, d.config.BatchSize)
+	if err != nil {
+		d.log.ErrorContext(ctx, "failed to claim webhook deliveries",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		d.deliver(ctx, job)
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, job models.WebhookJob) {
+	statusCode, err := d.send(ctx, job)
+	now := time.Now().UTC()
+
+	if err == nil {
+		if err := d.repo.MarkSucceeded(ctx, job.DeliveryID, statusCode, now); err != nil {
+			d.log.ErrorContext(ctx, "failed to record webhook delivery",
+				slog.Int64("delivery_id", job.DeliveryID),
+				slog.String("error", err.Error()),
+			)
+		}
+		return
+	}
+
+	attempts := job.Attempts + 1
+	var nextAttempt *time.Time
+	if attempts < d.config.MaxAttempts {
+		next := now.Add(d.backoff(attempts))
+		nextAttempt = &next
+	}
+
+	message := err.Error()
+	if len(message) > maxWebhookErrorLength {
+		message = message[:maxWebhookErrorLength]
+	}
+
+	d.log.WarnContext(ctx, "webhook delivery failed",
+		slog.Int64("delivery_id", job.DeliveryID),
+		slog.Int("attempts", attempts),
+		slog.Bool("dead", nextAttempt == nil),
+		slog.String("error", message),
+	)
+
+	if err := d.repo.MarkFailed(ctx, job.DeliveryID, statusCode, message, nextAttempt); err != nil {
+		d.log.ErrorContext(ctx, "failed to record webhook delivery",
+			slog.Int64("delivery_id", job.DeliveryID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// send posts the signed event and returns the response status code
+func (d *WebhookDispatcher) send(ctx context.Context, job models.WebhookJob) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"id":              strconv.FormatInt(job.EventID, 10),
+		"type":            job.EventType,
+		"organization_id": job.OrganizationID,
+		"created_at":      job.OccurredAt.UTC(),
+		"data":            job.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(job.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, strconv.FormatInt(job.EventID, 10))
+	req.Header.Set(WebhookEventHeader, job.EventType)
+	req.Header.Set(WebhookSignatureHeader, "t="+timestamp+",v1="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// dialPublicOnly refuses connections to addresses that are not public
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !publicAddress(addrPort.Addr()) {
+		return errWebhookAddressNotPublic
+	}
+	return nil
+}
+
+// publicAddress reports whether addr is routable on the internet, ruling
+// out loopback, private, link-local (cloud metadata), multicast and
+// unspecified addresses
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// backoff doubles the delay per attempt, capped, with up to 10% jitter
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	delay := d.config.InitialBackoff
+	for i := 1; i < attempts && delay < d.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.config.MaxBackoff {
+		delay = d.config.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int64N(int64(delay)/10+1))
+}
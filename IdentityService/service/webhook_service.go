@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"time"
+
+	"identity-service/auth"
+	"identity-service/config"
+	apperrors "identity-service/errors"
+	"identity-service/models"
+	"identity-service/repository"
+)
+
+const (
+	webhookSecretPrefix    = "whsec_"
+	webhookSecretBytes     = 32
+	webhookDeliveryPageMax = 100
+)
+
+// webhookEventTypes lists the events endpoints may subscribe to
+var webhookEventTypes = map[string]bool{
+	models.EventUserCreated:             true,
+	models.EventUserDeleted:             true,
+	models.EventUserEmailVerified:       true,
+	models.EventOrganizationMemberAdded: true,
+	models.EventSessionRevoked:          true,
+}
+
+// webhookService implements the WebhookService interface
+type webhookService struct {
+	repo   repository.WebhookRepository
+	audit  AuditService
+	config *config.WebhookConfig
+}
+
+// NewWebhookService creates a new WebhookService instance
+func NewWebhookService(repo repository.WebhookRepository, audit AuditService, cfg *config.WebhookConfig) WebhookService {
+	return &webhookService{
+		repo:   repo,
+		audit:  audit,
+		config: cfg,
+	}
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, orgID int, rawURL string, eventTypes []string) (*models.CreatedWebhookEndpoint, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && !(s.config.AllowHTTP && parsed.Scheme == "http")) {
+		return nil, apperrors.NewBadRequestError("url must be an absolute https URL", err)
+	}
+	// Hostnames are checked when each delivery is dialled
+	if addr, err := netip.ParseAddr(parsed.Hostname()); err == nil && !s.config.AllowPrivateNetworks && !publicAddress(addr) {
+		return nil, apperrors.NewBadRequestError("url must not point to a private or loopback address", nil)
+	}
+
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	for _, eventType := range eventTypes {
+		if !webhookEventTypes[eventType] {
+			return nil, apperrors.NewBadRequestError(fmt.Sprintf("unknown event type %q", eventType), nil)
+		}
+	}
+
+	secret, err := auth.GenerateToken(webhookSecretBytes)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to generate webhook secret", err)
+	}
+	secret = webhookSecretPrefix + secret
+
+	endpoint, err := s.repo.CreateEndpoint(ctx, &models.WebhookEndpoint{
+		OrganizationID: orgID,
+		URL:            parsed.String(),
+		EventTypes:     eventTypes,
+	}, secret)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to create webhook endpoint", err)
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditWebhookCreated,
+		OrganizationID: &orgID,
+		TargetType:     "webhook_endpoint",
+		TargetID:       strconv.Itoa(endpoint.ID),
+		Details:        map[string]interface{}{"url": endpoint.URL, "event_types": endpoint.EventTypes},
+	})
+
+	return &models.CreatedWebhookEndpoint{WebhookEndpoint: *endpoint, Secret: secret}, nil
+}
+
+func (s *webhookService) GetEndpoints(ctx context.Context, orgID int) ([]models.WebhookEndpoint, error) {
+	endpoints, err := s.repo.GetEndpoints(ctx, orgID)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve webhook endpoints", err)
+	}
+	return endpoints, nil
+}
+
+func (s *webhookService) DeleteEndpoint(ctx context.Context, orgID, id int) error {
+	deleted, err := s.repo.DeleteEndpoint(ctx, orgID, id)
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to delete webhook endpoint", err)
+	}
+	if !deleted {
+		return apperrors.NewNotFoundError("webhook endpoint not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditWebhookDeleted,
+		OrganizationID: &orgID,
+		TargetType:     "webhook_endpoint",
+		TargetID:       strconv.Itoa(id),
+	})
+	return nil
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, orgID int, status string) ([]models.WebhookDelivery, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead:
+	default:
+		return nil, apperrors.NewBadRequestError("invalid status", nil)
+	}
+
+	deliveries, err := s.repo.GetDeliveries(ctx, orgID, status, webhookDeliveryPageMax)
+	if err != nil {
+		return nil, apperrors.NewInternalServerError("failed to retrieve webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+func (s *webhookService) RetryDelivery(ctx context.Context, orgID int, id int64) error {
+	retried, err := s.repo.RetryDelivery(ctx, orgID, id, time.Now().UTC())
+	if err != nil {
+		return apperrors.NewInternalServerError("failed to retry webhook delivery", err)
+	}
+	if !retried {
+		return apperrors.NewNotFoundError("dead webhook delivery not found")
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:         models.AuditWebhookRetried,
+		OrganizationID: &orgID,
+		TargetType:     "webhook_delivery",
+		TargetID:       strconv.FormatInt(id, 10),
+	})
+	return nil
+}
+
+// Ensure webhookService implements WebhookService interface
+var _ WebhookService = (*webhookService)(nil)
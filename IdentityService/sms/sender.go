@@ -0,0 +1,82 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"identity-service/config"
+)
+
+// Message is a text message to a phone number in E.164 format
+type Message struct {
+	To   string
+	Body string
+}
+
+// Sender delivers text messages. Providers plug in by implementing it.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender creates the Sender selected by the SMS configuration
+func NewSender(cfg *config.SMSConfig, log *slog.Logger) Sender {
+	if cfg.Driver == "file" {
+		return &fileSender{from: cfg.From, path: cfg.FilePath}
+	}
+	return &logSender{from: cfg.From, log: log}
+}
+
+// logSender writes messages to the log instead of delivering them.
+// It is intended for local development.
+type logSender struct {
+	from string
+	log  *slog.Logger
+}
+
+func (s *logSender) Send(ctx context.Context, msg Message) error {
+	s.log.InfoContext(ctx, "text message not delivered (log driver)",
+		slog.String("from", s.from),
+		slog.String("to", msg.To),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
+
+// fileSender appends messages to a file as JSON lines, so development
+// tools and end-to-end tests can read the codes back
+type fileSender struct {
+	from string
+	path string
+	mu   sync.Mutex
+}
+
+func (s *fileSender) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(map[string]string{
+		"sent_at": time.Now().UTC().Format(time.RFC3339),
+		"from":    s.from,
+		"to":      msg.To,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode text message: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open text message file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write text message: %w", err)
+	}
+	return nil
+}
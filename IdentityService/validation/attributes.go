@@ -0,0 +1,173 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"identity-service/models"
+)
+
+const (
+	maxAttributePatternLength = 500
+	maxAttributeStringLength  = 1000
+	maxAttributeEnumValues    = 100
+)
+
+// attributeNamePattern keeps names usable as JSON keys, query parameters
+// and token claims alike
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// ValidateAttributeDefinition validates an entry of a custom attribute
+// schema, including that every enum value is itself a valid value
+func (v *Validator) ValidateAttributeDefinition(def *models.AttributeDefinition) error {
+	var errors ValidationErrors
+	add := func(field, message string) {
+		errors = append(errors, ValidationError{Field: field, Message: message})
+	}
+
+	if !attributeNamePattern.MatchString(def.Name) {
+		add("name", "must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+	}
+
+	switch def.Type {
+	case models.AttributeTypeString, models.AttributeTypeNumber, models.AttributeTypeBoolean:
+	default:
+		add("type", "must be string, number or boolean")
+	}
+
+	if def.Pattern != "" {
+		if def.Type != models.AttributeTypeString {
+			add("pattern", "only applies to string attributes")
+		} else if len(def.Pattern) > maxAttributePatternLength {
+			add("pattern", fmt.Sprintf("must be at most %d characters", maxAttributePatternLength))
+		} else if _, err := regexp.Compile(def.Pattern); err != nil {
+			add("pattern", "is not a valid regular expression")
+		}
+	}
+
+	if len(def.Enum) > maxAttributeEnumValues {
+		add("enum", fmt.Sprintf("must have at most %d values", maxAttributeEnumValues))
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+
+	unrestricted := *def
+	unrestricted.Enum = nil
+	for i, value := range def.Enum {
+		if message := attributeValueError(&unrestricted, value); message != "" {
+			add("enum["+strconv.Itoa(i)+"]", message)
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+// ValidateAttributes validates a member's complete set of custom attributes
+// against the organization's schema. Unknown attributes are rejected and
+// null counts as missing.
+func (v *Validator) ValidateAttributes(defs []models.AttributeDefinition, attrs map[string]interface{}) error {
+	var errors ValidationErrors
+
+	known := make(map[string]bool, len(defs))
+	for i := range defs {
+		def := &defs[i]
+		known[def.Name] = true
+
+		value, ok := attrs[def.Name]
+		if !ok || value == nil {
+			if def.Required {
+				errors = append(errors, ValidationError{Field: "attributes." + def.Name, Message: "is required"})
+			}
+			continue
+		}
+		if message := attributeValueError(def, value); message != "" {
+			errors = append(errors, ValidationError{Field: "attributes." + def.Name, Message: message})
+		}
+	}
+
+	// Sorted so the response is stable
+	var unknown []string
+	for name := range attrs {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errors = append(errors, ValidationError{Field: "attributes." + name, Message: "is not defined"})
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+// ParseAttributeValue converts a value given as text, such as in a query
+// string, to the attribute's type
+func (v *Validator) ParseAttributeValue(def *models.AttributeDefinition, raw string) (interface{}, error) {
+	switch def.Type {
+	case models.AttributeTypeNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, ValidationError{Field: def.Name, Message: "must be a number"}
+		}
+		return n, nil
+	case models.AttributeTypeBoolean:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, ValidationError{Field: def.Name, Message: "must be true or false"}
+		}
+		return b, nil
+	default:
+		return raw, nil
+	}
+}
+
+// attributeValueError describes why value does not fit the definition, or
+// returns "" when it does. Numbers arrive as float64 from encoding/json.
+func attributeValueError(def *models.AttributeDefinition, value interface{}) string {
+	switch def.Type {
+	case models.AttributeTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(s) > maxAttributeStringLength {
+			return fmt.Sprintf("must be at most %d characters", maxAttributeStringLength)
+		}
+		if def.Pattern != "" {
+			pattern, err := regexp.Compile(def.Pattern)
+			if err != nil || !pattern.MatchString(s) {
+				return "does not match the required format"
+			}
+		}
+	case models.AttributeTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return "must be a number"
+		}
+	case models.AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	}
+
+	if len(def.Enum) > 0 {
+		for _, allowed := range def.Enum {
+			if allowed == value {
+				return ""
+			}
+		}
+		return "is not one of the allowed values"
+	}
+
+	return ""
+}
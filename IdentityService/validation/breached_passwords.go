@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// breachPrefixLength is the number of SHA-1 hex digits that name a bucket
+const breachPrefixLength = 5
+
+// BreachedPasswords looks passwords up in a local copy of a breached
+// password corpus laid out like the Have I Been Pwned range API: one file
+// per five-character SHA-1 prefix (for example "21BD1" or "21BD1.txt"),
+// each line holding the remaining 35 hex digits and a count as
+// "SUFFIX:COUNT". Only the bucket for the password's prefix is read.
+type BreachedPasswords struct {
+	dir string
+}
+
+// NewBreachedPasswords creates a corpus reading buckets from dir
+func NewBreachedPasswords(dir string) *BreachedPasswords {
+	return &BreachedPasswords{dir: dir}
+}
+
+// Count returns how often password appears in the corpus. A missing
+// bucket counts as zero so partial corpora can be used.
+func (b *BreachedPasswords) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:breachPrefixLength], digest[breachPrefixLength:]
+
+	file, err := b.openBucket(prefix)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open breached password bucket %s: %w", prefix, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		// Padding entries from the range API have a count of zero
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("malformed entry in breached password bucket %s", prefix)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read breached password bucket %s: %w", prefix, err)
+	}
+
+	return 0, nil
+}
+
+func (b *BreachedPasswords) openBucket(prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(b.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(b.dir, prefix+".txt"))
+	}
+	return file, err
+}
@@ -0,0 +1,113 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minUserInputLength keeps short name fragments like "Al" from rejecting
+// unrelated passwords
+const minUserInputLength = 3
+
+// ValidatePassword checks a new password against the configured policy.
+// name and email belong to the account the password is for; passwords
+// containing them are rejected. Every violated rule is reported, so the
+// result is a ValidationErrors unless the breached password corpus fails.
+func (v *Validator) ValidatePassword(password, name, email string) error {
+	var errors ValidationErrors
+	add := func(message string) {
+		errors = append(errors, ValidationError{Field: "password", Message: message})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < v.config.PasswordMinLength {
+		add(fmt.Sprintf("must be at least %d characters", v.config.PasswordMinLength))
+	}
+	if length > v.config.PasswordMaxLength {
+		// Nothing else is worth checking, and scoring very long input is slow
+		add(fmt.Sprintf("must be at most %d characters", v.config.PasswordMaxLength))
+		return errors
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	if v.config.PasswordRequireUpper && !hasUpper {
+		add("must contain an uppercase letter")
+	}
+	if v.config.PasswordRequireLower && !hasLower {
+		add("must contain a lowercase letter")
+	}
+	if v.config.PasswordRequireDigit && !hasDigit {
+		add("must contain a digit")
+	}
+	if v.config.PasswordRequireSymbol && !hasSymbol {
+		add("must contain a symbol")
+	}
+
+	userInputs := passwordUserInputs(name, email)
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		if strings.Contains(lower, input) {
+			add("must not contain your name or email address")
+			break
+		}
+	}
+
+	if passwordStrength(password, userInputs) < v.config.PasswordMinStrength {
+		add("is too easy to guess")
+	}
+
+	if v.breached != nil && v.config.PasswordBreachThreshold > 0 {
+		count, err := v.breached.Count(password)
+		if err != nil {
+			return err
+		}
+		if count >= v.config.PasswordBreachThreshold {
+			add("has appeared in a data breach and cannot be used")
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+// passwordUserInputs splits the account's name and email into the
+// lowercase fragments a password must not contain
+func passwordUserInputs(name, email string) []string {
+	local, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	domain, _, _ = strings.Cut(domain, ".")
+
+	fields := []string{local, domain}
+	fields = append(fields, strings.FieldsFunc(strings.ToLower(name), isSeparator)...)
+	fields = append(fields, strings.FieldsFunc(local, isSeparator)...)
+
+	var inputs []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		if utf8.RuneCountInString(field) >= minUserInputLength && !seen[field] {
+			seen[field] = true
+			inputs = append(inputs, field)
+		}
+	}
+	return inputs
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
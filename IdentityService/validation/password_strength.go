@@ -0,0 +1,233 @@
+package validation
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength scores follow zxcvbn: 0 is trivially guessable, 4 is very strong.
+// Each threshold is the estimated number of guesses an offline attacker needs.
+var strengthThresholds = []float64{1e3, 1e6, 1e8, 1e10}
+
+// commonPasswords holds frequent passwords and password fragments, most
+// common first. A match costs its rank in guesses.
+var commonPasswords = []string{
+	"password", "123456", "qwerty", "letmein", "welcome", "admin", "iloveyou",
+	"monkey", "dragon", "football", "baseball", "master", "sunshine", "princess",
+	"shadow", "superman", "michael", "trustno1", "login", "starwars", "freedom",
+	"whatever", "hello", "charlie", "donald", "passw0rd", "secret", "access",
+	"batman", "flower", "hottie", "loveme", "zaq1zaq1", "ninja", "mustang",
+	"jordan", "harley", "ranger", "hunter", "buster", "soccer", "hockey",
+	"killer", "george", "andrew", "thomas", "jessica", "pepper", "daniel",
+	"summer", "winter", "spring", "autumn", "computer", "internet", "service",
+	"changeme", "default", "guest", "root", "test", "user", "love", "lovely",
+	"angel", "tigger", "cookie", "cheese", "orange", "banana", "apple", "purple",
+	"silver", "golden", "diamond", "matrix", "yankees", "cowboys", "eagles",
+	"chelsea", "arsenal", "liverpool", "london", "berlin", "paris", "america",
+	"canada", "monday", "friday", "january", "august", "october", "december",
+	"family", "friend", "forever", "happy", "lucky", "money", "power", "magic",
+	"queen", "king", "prince", "pokemon", "minecraft", "fortnite", "google",
+	"facebook", "company", "office", "qazwsx", "asdfgh", "zxcvbn", "abc123",
+	"letmein1", "welcome1", "password1", "p@ssword", "identity", "account",
+	"security", "private", "system", "server", "database", "mypass", "pass",
+}
+
+// leetSubstitutions undoes common character substitutions before
+// dictionary matching
+var leetSubstitutions = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i",
+)
+
+// keyboardRows are matched forwards and backwards as spatial patterns
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "qazwsxedc"}
+
+// passwordStrength estimates how hard password is to guess on the 0-4
+// scale. It finds the cheapest way to build the password from known
+// patterns (dictionary words, the user's own details, sequences, keyboard
+// runs, repeats and years) and brute-forced characters.
+func passwordStrength(password string, userInputs []string) int {
+	guesses := estimateGuesses(password, userInputs)
+	for score, threshold := range strengthThresholds {
+		if guesses < threshold {
+			return score
+		}
+	}
+	return len(strengthThresholds)
+}
+
+// patternMatch is a pattern covering length runes that costs guesses
+type patternMatch struct {
+	length  int
+	guesses float64
+}
+
+// estimateGuesses returns the estimated guesses to crack password
+func estimateGuesses(password string, userInputs []string) float64 {
+	runes := []rune(password)
+	lower := []rune(strings.ToLower(password))
+	// Every substitution is one rune for one rune, so indexes line up
+	unleet := []rune(leetSubstitutions.Replace(strings.ToLower(password)))
+	charset := charsetSize(runes)
+	bruteForce := math.Log10(charset)
+
+	// cheapest[i] is the lowest log10 guesses covering the first i runes
+	cheapest := make([]float64, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		cheapest[i] = math.Inf(1)
+	}
+	for i := 0; i < len(runes); i++ {
+		cheapest[i+1] = math.Min(cheapest[i+1], cheapest[i]+bruteForce)
+		for _, m := range matchesAt(lower, unleet, i, charset, userInputs) {
+			cheapest[i+m.length] = math.Min(cheapest[i+m.length], cheapest[i]+math.Log10(m.guesses))
+		}
+	}
+
+	return math.Pow(10, cheapest[len(runes)])
+}
+
+// matchesAt returns every pattern that starts at rune i
+func matchesAt(lower, unleet []rune, i int, charset float64, userInputs []string) []patternMatch {
+	var matches []patternMatch
+
+	for _, input := range userInputs {
+		if hasRunePrefix(lower[i:], input) || hasRunePrefix(unleet[i:], input) {
+			matches = append(matches, patternMatch{len([]rune(input)), 10})
+		}
+	}
+
+	for rank, word := range commonPasswords {
+		guesses := math.Max(float64(rank+1), 10)
+		if hasRunePrefix(lower[i:], word) {
+			matches = append(matches, patternMatch{len([]rune(word)), guesses})
+		} else if hasRunePrefix(unleet[i:], word) {
+			matches = append(matches, patternMatch{len([]rune(word)), guesses * 2})
+		}
+	}
+
+	// Any shorter run inside a sequence, keyboard run or repeat is one too
+	for length := sequenceLength(lower[i:]); length >= 3; length-- {
+		matches = append(matches, patternMatch{length, 26 * float64(length)})
+	}
+	for length := keyboardLength(lower[i:]); length >= 4; length-- {
+		matches = append(matches, patternMatch{length, 100 * float64(length)})
+	}
+	for length := repeatLength(lower[i:]); length >= 3; length-- {
+		matches = append(matches, patternMatch{length, charset * float64(length)})
+	}
+	if isYear(lower[i:]) {
+		matches = append(matches, patternMatch{4, 200})
+	}
+
+	return matches
+}
+
+// charsetSize is the brute-force alphabet implied by the character classes used
+func charsetSize(runes []rune) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	size := 0.0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	return math.Max(size, 10)
+}
+
+// sequenceLength measures a run like "abcd", "4321" or "aceg"
+func sequenceLength(runes []rune) int {
+	if len(runes) < 2 {
+		return 0
+	}
+	delta := runes[1] - runes[0]
+	if delta == 0 || delta > 2 || delta < -2 {
+		return 0
+	}
+	length := 2
+	for length < len(runes) && runes[length]-runes[length-1] == delta {
+		length++
+	}
+	return length
+}
+
+// keyboardLength measures a run of adjacent keys on one keyboard row
+func keyboardLength(runes []rune) int {
+	best := 0
+	for _, row := range keyboardRows {
+		for _, candidate := range []string{row, reverse(row)} {
+			line := []rune(candidate)
+			for start := range line {
+				length := 0
+				for length < len(runes) && start+length < len(line) && runes[length] == line[start+length] {
+					length++
+				}
+				best = max(best, length)
+			}
+		}
+	}
+	return best
+}
+
+// repeatLength measures a run of one repeated character or block, like "aaa" or "abab"
+func repeatLength(runes []rune) int {
+	best := 0
+	for block := 1; block <= len(runes)/2; block++ {
+		length := block
+		for length+block <= len(runes) && string(runes[length:length+block]) == string(runes[:block]) {
+			length += block
+		}
+		if length > block {
+			best = max(best, length)
+		}
+	}
+	return best
+}
+
+// isYear reports whether runes start with a year between 1900 and 2099
+func isYear(runes []rune) bool {
+	if len(runes) < 4 {
+		return false
+	}
+	prefix := string(runes[:2])
+	if prefix != "19" && prefix != "20" {
+		return false
+	}
+	return unicode.IsDigit(runes[2]) && unicode.IsDigit(runes[3])
+}
+
+func hasRunePrefix(runes []rune, prefix string) bool {
+	return prefix != "" && strings.HasPrefix(string(runes), prefix)
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
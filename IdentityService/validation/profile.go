@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	// Time zones must validate the same on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+const (
+	maxAvatarURLLength = 2048
+	maxLocaleLength    = 35
+	maxTimeZoneLength  = 64
+)
+
+// localePattern accepts BCP 47 language tags such as "en", "pt-BR" or
+// "zh-Hant-TW"
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidateProfile validates profile attributes. Empty values are allowed
+// and clear the attribute.
+func (v *Validator) ValidateProfile(displayName, avatarURL, locale, timeZone string) error {
+	var errors ValidationErrors
+
+	if len(displayName) > v.config.MaxNameLength {
+		errors = append(errors, ValidationError{
+			Field:   "display_name",
+			Message: fmt.Sprintf("must be at most %d characters", v.config.MaxNameLength),
+		})
+	}
+
+	if avatarURL != "" {
+		parsed, err := url.Parse(avatarURL)
+		switch {
+		case len(avatarURL) > maxAvatarURLLength:
+			errors = append(errors, ValidationError{
+				Field:   "avatar_url",
+				Message: fmt.Sprintf("must be at most %d characters", maxAvatarURLLength),
+			})
+		case err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http"):
+			errors = append(errors, ValidationError{Field: "avatar_url", Message: "must be an absolute http or https URL"})
+		}
+	}
+
+	if locale != "" && (len(locale) > maxLocaleLength || !localePattern.MatchString(locale)) {
+		errors = append(errors, ValidationError{Field: "locale", Message: "must be a language tag such as en or pt-BR"})
+	}
+
+	if timeZone != "" {
+		// LoadLocation also accepts "Local" and paths; only IANA names are stored
+		_, err := time.LoadLocation(timeZone)
+		if err != nil || len(timeZone) > maxTimeZoneLength || timeZone == "Local" || strings.HasPrefix(timeZone, "/") {
+			errors = append(errors, ValidationError{Field: "time_zone", Message: "must be an IANA time zone such as Europe/Berlin"})
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}
+
+// phonePattern accepts E.164 numbers such as +14155550123
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidatePhoneNumber validates a phone number for SMS delivery
+func (v *Validator) ValidatePhoneNumber(phone string) error {
+	if phone == "" {
+		return ValidationError{Field: "phone", Message: "is required"}
+	}
+	if !phonePattern.MatchString(phone) {
+		return ValidationError{Field: "phone", Message: "must be an E.164 number such as +14155550123"}
+	}
+	return nil
+}
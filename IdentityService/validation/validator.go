@@ -9,14 +9,19 @@ import (
 
 // Validator provides validation functionality
 type Validator struct {
-	config *config.ValidationConfig
+	config   *config.ValidationConfig
+	breached *BreachedPasswords
 }
 
 // NewValidator creates a new Validator instance
 func NewValidator(cfg *config.ValidationConfig) *Validator {
-	return &Validator{
+	v := &Validator{
 		config: cfg,
 	}
+	if cfg.BreachedPasswordsDir != "" {
+		v.breached = NewBreachedPasswords(cfg.BreachedPasswordsDir)
+	}
+	return v
 }
 
 // ValidationError represents a validation error
@@ -105,3 +110,44 @@ func (v *Validator) ValidateCreateUserRequest(name, email string) error {
 
 	return nil
 }
+
+// ValidateSlug validates an organization slug
+func (v *Validator) ValidateSlug(slug string) error {
+	if slug == "" {
+		return ValidationError{Field: "slug", Message: "is required"}
+	}
+
+	if len(slug) > 63 {
+		return ValidationError{Field: "slug", Message: "must be at most 63 characters"}
+	}
+
+	matched, err := regexp.MatchString(v.config.SlugRegex, slug)
+	if err != nil || !matched {
+		return ValidationError{Field: "slug", Message: "must contain only lowercase letters, digits and hyphens"}
+	}
+
+	return nil
+}
+
+// ValidateCreateOrganizationRequest validates a create organization request
+func (v *Validator) ValidateCreateOrganizationRequest(name, slug string) error {
+	var errors ValidationErrors
+
+	if err := v.ValidateName(name); err != nil {
+		if validationErr, ok := err.(ValidationError); ok {
+			errors = append(errors, validationErr)
+		}
+	}
+
+	if err := v.ValidateSlug(slug); err != nil {
+		if validationErr, ok := err.(ValidationError); ok {
+			errors = append(errors, validationErr)
+		}
+	}
+
+	if len(errors) > 0 {
+		return errors
+	}
+
+	return nil
+}